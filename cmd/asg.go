@@ -1,11 +1,8 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -217,13 +214,7 @@ func runASGScale(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("New:     Desired=%d, Min=%d, Max=%d\n", newDesired, newMin, newMax)
-	fmt.Print("\nProceed with scaling? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response != "y" && response != "yes" {
+	if !confirm("\nProceed with scaling? [y/N]: ") {
 		fmt.Println("Scaling cancelled")
 		return nil
 	}
@@ -271,13 +262,7 @@ func runASGRefresh(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Auto Scaling Group: %s\n", asgName)
 	fmt.Printf("Instance Count: %d\n", asg.InstanceCount)
 	fmt.Printf("Min Healthy Percentage: %d%%\n", refreshMinHealthy)
-	fmt.Print("\nThis will start a rolling refresh of all instances. Proceed? [y/N]: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response != "y" && response != "yes" {
+	if !confirm("\nThis will start a rolling refresh of all instances. Proceed? [y/N]: ") {
 		fmt.Println("Refresh cancelled")
 		return nil
 	}
@@ -1,9 +1,11 @@
 package aws
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -33,7 +35,12 @@ var ssmParamCmd = &cobra.Command{
 
 Examples:
   cml aws ssm param list /app/
-  cml aws ssm param get /app/db-password`,
+  cml aws ssm param get /app/db-password
+  cml aws ssm param tree /app/ --with-decryption
+  cml aws ssm param mv /app/staging/ /app/stage/
+  cml aws ssm param delete --prefix /app/legacy/
+  cml aws ssm param tag /app/db-password add team=payments
+  cml aws ssm param label /app/db-password add stable`,
 }
 
 var ssmParamListCmd = &cobra.Command{
@@ -327,6 +334,16 @@ func padStr(s string, width int) string {
 	return s + strings.Repeat(" ", width-len(s))
 }
 
+// confirm prints prompt and reports whether the user answered y or yes
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
 func stringPtr(s string) *string { return &s }
 func boolPtr(b bool) *bool       { return &b }
 
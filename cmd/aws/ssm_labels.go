@@ -0,0 +1,230 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+)
+
+var ssmParamTagCmd = &cobra.Command{
+	Use:   "tag <name> [list|add|remove] [key=value... | key...]",
+	Short: "Manage parameter tags",
+	Long: `List, add or remove tags on a parameter.
+
+Examples:
+  cml aws ssm param tag /app/db-password               # list tags
+  cml aws ssm param tag /app/db-password add team=payments env=prod
+  cml aws ssm param tag /app/db-password remove temp`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSSMParamTag,
+}
+
+var ssmParamLabelCmd = &cobra.Command{
+	Use:   "label <name> [list|add|remove] [label...]",
+	Short: "Manage parameter version labels",
+	Long: `List, add or remove version labels on a parameter.
+
+add labels the latest version, or --version. A label is on at most one
+version, so adding it moves it. remove finds the version each label is on.
+Labels can be read with 'cml aws ssm param get /app/db-password:<label>'.
+
+Examples:
+  cml aws ssm param label /app/db-password             # versions with labels
+  cml aws ssm param label /app/db-password add stable
+  cml aws ssm param label /app/db-password add known-good --version 3
+  cml aws ssm param label /app/db-password remove stable`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSSMParamLabel,
+}
+
+var ssmParamLabelVersion int64
+
+func init() {
+	ssmParamCmd.AddCommand(ssmParamTagCmd)
+	ssmParamCmd.AddCommand(ssmParamLabelCmd)
+
+	ssmParamLabelCmd.Flags().Int64Var(&ssmParamLabelVersion, "version", 0, "Version to label (default: latest)")
+}
+
+func runSSMParamTag(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	name, action := args[0], "list"
+	args = args[1:]
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	client, err := getSSMClient()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "list", "ls":
+		if len(args) > 0 {
+			return fmt.Errorf("list takes no arguments")
+		}
+		output, err := client.ListTagsForResource(ctx, &ssm.ListTagsForResourceInput{
+			ResourceId:   &name,
+			ResourceType: ssmTypes.ResourceTypeForTaggingParameter,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list tags: %w", err)
+		}
+		if len(output.TagList) == 0 {
+			fmt.Println("No tags")
+			return nil
+		}
+		tags := output.TagList
+		sort.Slice(tags, func(i, j int) bool { return deref(tags[i].Key) < deref(tags[j].Key) })
+		for _, t := range tags {
+			fmt.Printf("  %s=%s\n", ui.NameStyle.Render(deref(t.Key)), deref(t.Value))
+		}
+
+	case "add", "set":
+		if len(args) == 0 {
+			return fmt.Errorf("add needs at least one key=value")
+		}
+		tags := make([]ssmTypes.Tag, 0, len(args))
+		for _, kv := range args {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || k == "" {
+				return fmt.Errorf("invalid tag %q (use key=value)", kv)
+			}
+			tags = append(tags, ssmTypes.Tag{Key: stringPtr(k), Value: stringPtr(v)})
+		}
+		_, err := client.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+			ResourceId:   &name,
+			ResourceType: ssmTypes.ResourceTypeForTaggingParameter,
+			Tags:         tags,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add tags: %w", err)
+		}
+		fmt.Printf("%s Tagged %s: %s\n", ui.RunningStyle.Render("✓"), name, strings.Join(args, ", "))
+
+	case "remove", "rm":
+		if len(args) == 0 {
+			return fmt.Errorf("remove needs at least one key")
+		}
+		_, err := client.RemoveTagsFromResource(ctx, &ssm.RemoveTagsFromResourceInput{
+			ResourceId:   &name,
+			ResourceType: ssmTypes.ResourceTypeForTaggingParameter,
+			TagKeys:      args,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove tags: %w", err)
+		}
+		fmt.Printf("%s Removed tags from %s: %s\n", ui.RunningStyle.Render("✓"), name, strings.Join(args, ", "))
+
+	default:
+		return fmt.Errorf("unknown action %q (use list, add or remove)", action)
+	}
+	return nil
+}
+
+func runSSMParamLabel(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	name, action := args[0], "list"
+	args = args[1:]
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	client, err := getSSMClient()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "list", "ls":
+		if len(args) > 0 {
+			return fmt.Errorf("list takes no arguments")
+		}
+		return listParameterLabels(ctx, client, name)
+
+	case "add":
+		if len(args) == 0 {
+			return fmt.Errorf("add needs at least one label")
+		}
+		input := &ssm.LabelParameterVersionInput{Name: &name, Labels: args}
+		if ssmParamLabelVersion > 0 {
+			input.ParameterVersion = &ssmParamLabelVersion
+		}
+		output, err := client.LabelParameterVersion(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to label parameter: %w", err)
+		}
+		if len(output.InvalidLabels) > 0 {
+			return fmt.Errorf("invalid labels: %s", strings.Join(output.InvalidLabels, ", "))
+		}
+		fmt.Printf("%s Labeled %s version %d: %s\n", ui.RunningStyle.Render("✓"), name, output.ParameterVersion, strings.Join(args, ", "))
+
+	case "remove", "rm":
+		if len(args) == 0 {
+			return fmt.Errorf("remove needs at least one label")
+		}
+		// Unlabeling needs the version each label is on
+		byVersion := map[int64][]string{}
+		for _, label := range args {
+			selector := name + ":" + label
+			output, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: &selector})
+			if err != nil {
+				return fmt.Errorf("label %s not found on %s: %w", label, name, err)
+			}
+			byVersion[output.Parameter.Version] = append(byVersion[output.Parameter.Version], label)
+		}
+		for version, labels := range byVersion {
+			_, err := client.UnlabelParameterVersion(ctx, &ssm.UnlabelParameterVersionInput{
+				Name:             &name,
+				ParameterVersion: &version,
+				Labels:           labels,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove labels from version %d: %w", version, err)
+			}
+		}
+		fmt.Printf("%s Removed labels from %s: %s\n", ui.RunningStyle.Render("✓"), name, strings.Join(args, ", "))
+
+	default:
+		return fmt.Errorf("unknown action %q (use list, add or remove)", action)
+	}
+	return nil
+}
+
+// listParameterLabels prints the versions that carry labels, newest first
+func listParameterLabels(ctx context.Context, client *ssm.Client, name string) error {
+	paginator := ssm.NewGetParameterHistoryPaginator(client, &ssm.GetParameterHistoryInput{Name: &name})
+
+	var labeled []ssmTypes.ParameterHistory
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get parameter history: %w", err)
+		}
+		for _, h := range page.Parameters {
+			if len(h.Labels) > 0 {
+				labeled = append(labeled, h)
+			}
+		}
+	}
+
+	if len(labeled) == 0 {
+		fmt.Println("No labels")
+		return nil
+	}
+	sort.Slice(labeled, func(i, j int) bool { return labeled[i].Version > labeled[j].Version })
+	for _, h := range labeled {
+		fmt.Printf("  %s %s\n", ui.NameStyle.Render(fmt.Sprintf("%-6d", h.Version)), strings.Join(h.Labels, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,497 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+)
+
+var ssmParamTreeCmd = &cobra.Command{
+	Use:   "tree [path]",
+	Short: "Show the parameter hierarchy",
+	Long: `Show the parameters under a path as a tree, with their types.
+
+--with-decryption also shows the values, decrypting SecureStrings. Long and
+multi-line values are shortened.
+
+Examples:
+  cml aws ssm param tree
+  cml aws ssm param tree /app/
+  cml aws ssm param tree /app/prod --with-decryption`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSSMParamTree,
+}
+
+var ssmParamDeleteCmd = &cobra.Command{
+	Use:     "delete [name] [--prefix path]",
+	Aliases: []string{"rm"},
+	Short:   "Delete a parameter or a whole hierarchy",
+	Long: `Delete one parameter, or with --prefix every parameter under a path
+(recursively). The parameters are listed and confirmation is asked unless
+--yes is given.
+
+Examples:
+  cml aws ssm param delete /app/old-param
+  cml aws ssm param delete --prefix /app/legacy/
+  cml aws ssm param delete --prefix /app/tmp --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSSMParamDelete,
+}
+
+var ssmParamMvCmd = &cobra.Command{
+	Use:   "mv <source> <destination>",
+	Short: "Rename a parameter or a hierarchy",
+	Long: `Rename a parameter and every parameter under it: /app/old/db/host moved
+with 'mv /app/old /app/new' becomes /app/new/db/host.
+
+Values, types, KMS keys, descriptions, tiers and tags are copied, then the
+sources are deleted once every destination was written. Version history and
+labels stay with the deleted sources. Existing destinations are an error
+unless --overwrite is given.
+
+Examples:
+  cml aws ssm param mv /app/db-pass /app/db/password
+  cml aws ssm param mv /app/staging/ /app/stage/ --dry-run
+  cml aws ssm param mv /legacy/app /app --overwrite --yes`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSSMParamMv,
+}
+
+var (
+	ssmParamTreeDecrypt bool
+	ssmParamDelPrefix   string
+	ssmParamDelYes      bool
+	ssmParamMvOverwrite bool
+	ssmParamMvDryRun    bool
+	ssmParamMvYes       bool
+)
+
+func init() {
+	ssmParamCmd.AddCommand(ssmParamTreeCmd)
+	ssmParamCmd.AddCommand(ssmParamDeleteCmd)
+	ssmParamCmd.AddCommand(ssmParamMvCmd)
+
+	ssmParamTreeCmd.Flags().BoolVar(&ssmParamTreeDecrypt, "with-decryption", false, "Show values, decrypting SecureStrings")
+
+	ssmParamDeleteCmd.Flags().StringVar(&ssmParamDelPrefix, "prefix", "", "Delete every parameter under this path")
+	ssmParamDeleteCmd.Flags().BoolVarP(&ssmParamDelYes, "yes", "y", false, "Skip confirmation prompt")
+
+	ssmParamMvCmd.Flags().BoolVar(&ssmParamMvOverwrite, "overwrite", false, "Overwrite existing destination parameters")
+	ssmParamMvCmd.Flags().BoolVar(&ssmParamMvDryRun, "dry-run", false, "Show what would be moved without changing anything")
+	ssmParamMvCmd.Flags().BoolVarP(&ssmParamMvYes, "yes", "y", false, "Skip confirmation prompt")
+}
+
+// ssmBatchSize is the most names GetParameters and DeleteParameters accept
+const ssmBatchSize = 10
+
+// hierarchyPath normalizes a path for GetParametersByPath: leading slash,
+// no trailing slash except for the root
+func hierarchyPath(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("path %q must start with /", path)
+	}
+	if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+		return trimmed, nil
+	}
+	return "/", nil
+}
+
+// getParametersByPath returns every parameter under path, sorted by name
+func getParametersByPath(ctx context.Context, client *ssm.Client, path string, decrypt bool) ([]ssmTypes.Parameter, error) {
+	paginator := ssm.NewGetParametersByPathPaginator(client, &ssm.GetParametersByPathInput{
+		Path:           &path,
+		Recursive:      boolPtr(true),
+		WithDecryption: boolPtr(decrypt),
+	})
+
+	var params []ssmTypes.Parameter
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parameters under %s: %w", path, err)
+		}
+		params = append(params, page.Parameters...)
+	}
+
+	sort.Slice(params, func(i, j int) bool { return deref(params[i].Name) < deref(params[j].Name) })
+	return params, nil
+}
+
+// paramNode is one path segment in the parameter tree; a segment can be
+// both a parameter and the parent of others
+type paramNode struct {
+	param    *ssmTypes.Parameter
+	children map[string]*paramNode
+}
+
+func runSSMParamTree(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	client, err := getSSMClient()
+	if err != nil {
+		return err
+	}
+
+	path := "/"
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path, err = hierarchyPath(path); err != nil {
+		return err
+	}
+
+	params, err := getParametersByPath(ctx, client, path, ssmParamTreeDecrypt)
+	if err != nil {
+		return err
+	}
+	if len(params) == 0 {
+		fmt.Printf("No parameters found under %s\n", path)
+		return nil
+	}
+
+	root := &paramNode{children: map[string]*paramNode{}}
+	for i, p := range params {
+		node := root
+		rel := strings.TrimPrefix(strings.TrimPrefix(deref(p.Name), path), "/")
+		for _, part := range strings.Split(rel, "/") {
+			child := node.children[part]
+			if child == nil {
+				child = &paramNode{children: map[string]*paramNode{}}
+				node.children[part] = child
+			}
+			node = child
+		}
+		node.param = &params[i]
+	}
+
+	fmt.Println(ui.HeaderStyle.Render(strings.TrimSuffix(path, "/") + "/"))
+	printParamTree(root, "")
+	fmt.Printf("\n  %d parameters\n", len(params))
+	return nil
+}
+
+func printParamTree(node *paramNode, indent string) {
+	keys := make([]string, 0, len(node.children))
+	for k := range node.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		child := node.children[k]
+		connector, next := "├── ", "│   "
+		if i == len(keys)-1 {
+			connector, next = "└── ", "    "
+		}
+
+		line := indent + connector
+		if len(child.children) > 0 {
+			line += ui.HeaderStyle.Render(k + "/")
+		} else {
+			line += ui.NameStyle.Render(k)
+		}
+		if p := child.param; p != nil {
+			line += "  " + ui.TypeStyle.Render(string(p.Type))
+			if ssmParamTreeDecrypt {
+				line += "  " + ui.MutedStyle.Render(treeValue(deref(p.Value)))
+			}
+		}
+		fmt.Println(line)
+
+		printParamTree(child, indent+next)
+	}
+}
+
+// treeValue shortens a value to one line for the tree
+func treeValue(v string) string {
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	if r := []rune(v); len(r) > 60 {
+		v = string(r[:57]) + "..."
+	}
+	return v
+}
+
+func runSSMParamDelete(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if (len(args) == 1) == (ssmParamDelPrefix != "") {
+		return fmt.Errorf("give a parameter name or --prefix, not both")
+	}
+
+	client, err := getSSMClient()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	prompt := ""
+	if len(args) == 1 {
+		names = []string{args[0]}
+		prompt = fmt.Sprintf("Delete parameter %s? [y/N]: ", ui.NameStyle.Render(args[0]))
+	} else {
+		path, err := hierarchyPath(ssmParamDelPrefix)
+		if err != nil {
+			return err
+		}
+		params, err := getParametersByPath(ctx, client, path, false)
+		if err != nil {
+			return err
+		}
+		if len(params) == 0 {
+			fmt.Printf("No parameters found under %s\n", path)
+			return nil
+		}
+		for _, p := range params {
+			names = append(names, deref(p.Name))
+			fmt.Printf("  %s %s\n", ui.StoppedStyle.Render("-"), deref(p.Name))
+		}
+		prompt = fmt.Sprintf("\nDelete %d parameters under %s? [y/N]: ", len(names), ui.NameStyle.Render(path))
+	}
+
+	if !ssmParamDelYes && !confirm(prompt) {
+		fmt.Println("Delete cancelled")
+		return nil
+	}
+
+	deleted, err := deleteParameters(ctx, client, names)
+	if deleted > 0 {
+		fmt.Printf("%s Deleted %d parameters\n", ui.RunningStyle.Render("✓"), deleted)
+	}
+	return err
+}
+
+// deleteParameters deletes names in batches and returns how many were
+// deleted. Names that no longer exist are reported as an error.
+func deleteParameters(ctx context.Context, client *ssm.Client, names []string) (int, error) {
+	deleted := 0
+	var missing []string
+	for start := 0; start < len(names); start += ssmBatchSize {
+		batch := names[start:min(start+ssmBatchSize, len(names))]
+		output, err := client.DeleteParameters(ctx, &ssm.DeleteParametersInput{Names: batch})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete parameters: %w", err)
+		}
+		deleted += len(output.DeletedParameters)
+		missing = append(missing, output.InvalidParameters...)
+	}
+	if len(missing) > 0 {
+		return deleted, fmt.Errorf("parameters not found: %s", strings.Join(missing, ", "))
+	}
+	return deleted, nil
+}
+
+// paramMove is one parameter of an mv, with what PutParameter needs to
+// recreate it
+type paramMove struct {
+	from, to string
+	param    ssmTypes.Parameter
+	meta     ssmTypes.ParameterMetadata
+	tags     []ssmTypes.Tag
+}
+
+func runSSMParamMv(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	src, err := hierarchyPath(args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := hierarchyPath(args[1])
+	if err != nil {
+		return err
+	}
+	if src == "/" || dst == "/" {
+		return fmt.Errorf("cannot move to or from the root")
+	}
+	if dst == src || strings.HasPrefix(dst, src+"/") {
+		return fmt.Errorf("cannot move %s into itself", src)
+	}
+	// Moving a path up into its own parent would overwrite the sources
+	// (/app/x/x → /app/x) before they are deleted
+	if strings.HasPrefix(src, dst+"/") {
+		return fmt.Errorf("cannot move %s into its parent %s", src, dst)
+	}
+
+	client, err := getSSMClient()
+	if err != nil {
+		return err
+	}
+
+	// The parameter named src itself, if any, and everything under it
+	params, err := getParametersByPath(ctx, client, src, true)
+	if err != nil {
+		return err
+	}
+	output, err := client.GetParameter(ctx, &ssm.GetParameterInput{Name: &src, WithDecryption: boolPtr(true)})
+	var notFound *ssmTypes.ParameterNotFound
+	switch {
+	case err == nil:
+		params = append([]ssmTypes.Parameter{*output.Parameter}, params...)
+	case !errors.As(err, &notFound):
+		return fmt.Errorf("failed to get parameter: %w", err)
+	}
+	if len(params) == 0 {
+		return fmt.Errorf("no parameter %s or parameters under it", src)
+	}
+
+	meta, err := describeParametersByPrefix(ctx, client, src)
+	if err != nil {
+		return err
+	}
+
+	moves := make([]paramMove, 0, len(params))
+	for _, p := range params {
+		name := deref(p.Name)
+		moves = append(moves, paramMove{from: name, to: dst + strings.TrimPrefix(name, src), param: p, meta: meta[name]})
+	}
+	sourceNames := make(map[string]bool, len(moves))
+	for _, m := range moves {
+		sourceNames[m.from] = true
+	}
+	for _, m := range moves {
+		if sourceNames[m.to] {
+			return fmt.Errorf("cannot move %s to %s: it is also being moved", m.from, m.to)
+		}
+	}
+
+	existing, err := existingParameters(ctx, client, moves)
+	if err != nil {
+		return err
+	}
+	for _, m := range moves {
+		mark := ui.RunningStyle.Render("+")
+		if existing[m.to] {
+			mark = ui.PendingStyle.Render("~")
+		}
+		fmt.Printf("  %s %s → %s\n", mark, m.from, m.to)
+	}
+	if len(existing) > 0 && !ssmParamMvOverwrite {
+		return fmt.Errorf("%d destination parameters already exist (~); use --overwrite to replace them", len(existing))
+	}
+
+	if ssmParamMvDryRun {
+		fmt.Printf("\n%d parameters would be moved (dry run)\n", len(moves))
+		return nil
+	}
+
+	if !ssmParamMvYes && !confirm(fmt.Sprintf("\nMove %d parameters from %s to %s? [y/N]: ", len(moves), ui.NameStyle.Render(src), ui.NameStyle.Render(dst))) {
+		fmt.Println("Move cancelled")
+		return nil
+	}
+
+	// Write every destination before deleting any source, so a failure
+	// part way leaves copies rather than losing parameters
+	for i := range moves {
+		m := &moves[i]
+		tags, err := client.ListTagsForResource(ctx, &ssm.ListTagsForResourceInput{
+			ResourceId:   &m.from,
+			ResourceType: ssmTypes.ResourceTypeForTaggingParameter,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list tags of %s: %w", m.from, err)
+		}
+		m.tags = tags.TagList
+
+		if err := putMovedParameter(ctx, client, m); err != nil {
+			return err
+		}
+	}
+
+	sources := make([]string, len(moves))
+	for i, m := range moves {
+		sources[i] = m.from
+	}
+	if _, err := deleteParameters(ctx, client, sources); err != nil {
+		return fmt.Errorf("copied to %s but deleting the sources failed: %w", dst, err)
+	}
+
+	fmt.Printf("%s Moved %d parameters from %s to %s\n", ui.RunningStyle.Render("✓"), len(moves), src, dst)
+	return nil
+}
+
+func putMovedParameter(ctx context.Context, client *ssm.Client, m *paramMove) error {
+	input := &ssm.PutParameterInput{
+		Name:      &m.to,
+		Value:     m.param.Value,
+		Type:      m.param.Type,
+		DataType:  m.param.DataType,
+		Overwrite: boolPtr(ssmParamMvOverwrite),
+	}
+	if m.meta.Name != nil {
+		input.Description = m.meta.Description
+		input.AllowedPattern = m.meta.AllowedPattern
+		input.Tier = m.meta.Tier
+		if m.param.Type == ssmTypes.ParameterTypeSecureString {
+			input.KeyId = m.meta.KeyId
+		}
+	}
+	if _, err := client.PutParameter(ctx, input); err != nil {
+		return fmt.Errorf("failed to put %s: %w", m.to, err)
+	}
+
+	// PutParameter rejects tags together with Overwrite
+	if len(m.tags) > 0 {
+		_, err := client.AddTagsToResource(ctx, &ssm.AddTagsToResourceInput{
+			ResourceId:   &m.to,
+			ResourceType: ssmTypes.ResourceTypeForTaggingParameter,
+			Tags:         m.tags,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to tag %s: %w", m.to, err)
+		}
+	}
+	return nil
+}
+
+// describeParametersByPrefix returns the metadata of parameters whose names
+// begin with prefix, by name
+func describeParametersByPrefix(ctx context.Context, client *ssm.Client, prefix string) (map[string]ssmTypes.ParameterMetadata, error) {
+	paginator := ssm.NewDescribeParametersPaginator(client, &ssm.DescribeParametersInput{
+		ParameterFilters: []ssmTypes.ParameterStringFilter{
+			{
+				Key:    stringPtr("Name"),
+				Option: stringPtr("BeginsWith"),
+				Values: []string{prefix},
+			},
+		},
+	})
+
+	meta := map[string]ssmTypes.ParameterMetadata{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe parameters: %w", err)
+		}
+		for _, p := range page.Parameters {
+			meta[deref(p.Name)] = p
+		}
+	}
+	return meta, nil
+}
+
+// existingParameters returns which move destinations already exist
+func existingParameters(ctx context.Context, client *ssm.Client, moves []paramMove) (map[string]bool, error) {
+	existing := map[string]bool{}
+	for start := 0; start < len(moves); start += ssmBatchSize {
+		var names []string
+		for _, m := range moves[start:min(start+ssmBatchSize, len(moves))] {
+			names = append(names, m.to)
+		}
+		output, err := client.GetParameters(ctx, &ssm.GetParametersInput{Names: names})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check destination parameters: %w", err)
+		}
+		for _, p := range output.Parameters {
+			existing[deref(p.Name)] = true
+		}
+	}
+	return existing, nil
+}
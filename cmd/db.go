@@ -3,7 +3,10 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/mattn/go-runewidth"
 	"github.com/spf13/cobra"
@@ -49,7 +52,8 @@ var dbConnectCmd = &cobra.Command{
 	Long: `Open a tunnel to the database endpoint.
 
 For AWS RDS, --via must specify a bastion EC2 instance ID that has the SSM
-agent installed; the tunnel uses AWS-StartPortForwardingSessionToRemoteHost.
+agent installed; the tunnel uses AWS-StartPortForwardingSessionToRemoteHost
+over cml's built-in Session Manager client (no AWS CLI or plugin needed).
 
 Examples:
   cml db connect prod-pg --via i-0abc123def456
@@ -104,7 +108,11 @@ func getDBProvider(ctx context.Context) (provider.DBProvider, error) {
 			return nil, fmt.Errorf("no context set. Use 'cml use <context>' to set one")
 		}
 	}
+	return newDBProvider(ctx, ctxConfig, ctxName)
+}
 
+// newDBProvider builds the DB provider for a context
+func newDBProvider(ctx context.Context, ctxConfig *config.Context, ctxName string) (provider.DBProvider, error) {
 	switch ctxConfig.Provider {
 	case "aws":
 		client, err := aws.NewClient(ctx,
@@ -163,7 +171,9 @@ func runDBGet(cmd *cobra.Command, args []string) error {
 }
 
 func runDBConnect(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	// Cancel on Ctrl+C so the SSM sessions are terminated cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	dbProvider, err := getDBProvider(ctx)
 	if err != nil {
 		return err
@@ -173,7 +183,10 @@ func runDBConnect(cmd *cobra.Command, args []string) error {
 		Via:       dbConnectVia,
 		LocalPort: dbConnectLocal,
 	}
-	return dbProvider.Connect(ctx, args[0], opts)
+	if err := dbProvider.Connect(ctx, args[0], opts); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
 }
 
 func printDBTable(dbs []types.Database) {
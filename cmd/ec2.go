@@ -3,8 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/exec"
 
 	"github.com/spf13/cobra"
 
@@ -148,25 +146,11 @@ func runEC2SSH(cmd *cobra.Command, args []string) error {
 
 	// Start SSM session
 	fmt.Printf("Starting SSM session to %s (%s)...\n", selected.Name, selected.ID)
-	return startSSMSession(selected.ID, client.Profile(), client.Region())
+	return startSSMSession(client, selected.ID)
 }
 
-// startSSMSession starts an AWS SSM session using the AWS CLI
-func startSSMSession(instanceID, profile, region string) error {
-	args := []string{"ssm", "start-session", "--target", instanceID}
-
-	if profile != "" {
-		args = append(args, "--profile", profile)
-	}
-
-	if region != "" {
-		args = append(args, "--region", region)
-	}
-
-	ssmCmd := exec.Command("aws", args...)
-	ssmCmd.Stdin = os.Stdin
-	ssmCmd.Stdout = os.Stdout
-	ssmCmd.Stderr = os.Stderr
-
-	return ssmCmd.Run()
+// startSSMSession opens an interactive Session Manager shell using the
+// built-in data channel client.
+func startSSMSession(client *aws.Client, instanceID string) error {
+	return client.StartShell(context.Background(), instanceID)
 }
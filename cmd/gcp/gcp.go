@@ -0,0 +1,66 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	internalConfig "github.com/vietdv277/cumulus/internal/config"
+	gcpinternal "github.com/vietdv277/cumulus/internal/gcp"
+)
+
+// GCPCmd is the root command for GCP-specific operations
+var GCPCmd = &cobra.Command{
+	Use:   "gcp",
+	Short: "GCP-specific commands",
+	Long: `GCP-specific commands for features without cross-provider equivalents.
+
+Examples:
+  cml gcp projects list
+  cml gcp projects use my-project
+  cml gcp iap tunnel my-vm 22
+  cml gcp iam test-permissions projects/my-project`,
+}
+
+func init() {
+	GCPCmd.AddCommand(projectsCmd)
+	GCPCmd.AddCommand(iapCmd)
+	GCPCmd.AddCommand(iamCmd)
+}
+
+// getGCPClient returns a client for the current context, which must be a GCP context.
+func getGCPClient(ctx context.Context) (*gcpinternal.Client, *internalConfig.Context, string, error) {
+	ctxConfig, ctxName, err := internalConfig.GetCurrentContext()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	if ctxConfig == nil || ctxConfig.Provider != "gcp" {
+		return nil, nil, "", fmt.Errorf("current context is not GCP. Use 'cml use gcp:<context>'")
+	}
+
+	client, err := gcpinternal.NewClient(ctx,
+		gcpinternal.WithProject(ctxConfig.Project),
+		gcpinternal.WithRegion(ctxConfig.Region),
+		gcpinternal.WithBastion(ctxConfig.Bastion, ctxConfig.BastionZone),
+		gcpinternal.WithBastionProject(ctxConfig.BastionProject),
+		gcpinternal.WithBastionIAP(ctxConfig.BastionIAP),
+	)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to create GCP client: %w", err)
+	}
+
+	return client, ctxConfig, ctxName, nil
+}
+
+func padStr(s string, width int) string {
+	if len(s) >= width {
+		if width > 3 {
+			return s[:width-3] + "..."
+		}
+		return s[:width]
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
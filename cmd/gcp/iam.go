@@ -0,0 +1,87 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+)
+
+var iamCmd = &cobra.Command{
+	Use:   "iam",
+	Short: "GCP IAM commands",
+	Long: `GCP IAM commands for permission checks.
+
+Examples:
+  cml gcp iam test-permissions
+  cml gcp iam test-permissions gs://my-bucket`,
+}
+
+var iamTestPermissionsCmd = &cobra.Command{
+	Use:   "test-permissions [resource]",
+	Short: "Show which permissions the caller has on a resource",
+	Long: `Call testIamPermissions on a resource and show granted vs missing permissions.
+
+The resource defaults to the current context's project and may be:
+  my-project | projects/my-project     a project
+  folders/123 | organizations/456      a folder or organization
+  gs://my-bucket | buckets/my-bucket   a Cloud Storage bucket
+  [projects/P/]zones/Z/instances/NAME  a GCE instance
+
+Without --permission, a default set covering what cml uses is checked.
+
+Examples:
+  cml gcp iam test-permissions
+  cml gcp iam test-permissions other-project -P compute.instances.list
+  cml gcp iam test-permissions zones/asia-southeast1-b/instances/bastion
+  cml gcp iam test-permissions gs://my-bucket -P storage.objects.get -P storage.objects.create`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runIAMTestPermissions,
+}
+
+var iamPermissions []string
+
+func init() {
+	iamCmd.AddCommand(iamTestPermissionsCmd)
+
+	iamTestPermissionsCmd.Flags().StringArrayVarP(&iamPermissions, "permission", "P", nil, "Permission to test (repeatable)")
+}
+
+func runIAMTestPermissions(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	client, _, ctxName, err := getGCPClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	resource := ""
+	if len(args) > 0 {
+		resource = args[0]
+	}
+
+	result, err := client.TestPermissions(resource, iamPermissions)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println(ui.HeaderStyle.Render("GCP Permissions"))
+	fmt.Println(ui.MutedStyle.Render("───────────────────────────────"))
+	fmt.Printf("  Context:  %s\n", ui.GCPStyle.Render(ctxName))
+	fmt.Printf("  Resource: %s\n", result.Resource)
+	fmt.Println()
+
+	for _, p := range result.Granted {
+		fmt.Printf("  %s %s\n", ui.RunningStyle.Render("✓"), p)
+	}
+	for _, p := range result.Missing {
+		fmt.Printf("  %s %s\n", ui.StoppedStyle.Render("✗"), ui.MutedStyle.Render(p))
+	}
+
+	fmt.Println()
+	fmt.Printf("  %d granted, %d missing\n", len(result.Granted), len(result.Missing))
+	return nil
+}
@@ -0,0 +1,78 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	gcpinternal "github.com/vietdv277/cumulus/internal/gcp"
+)
+
+var iapCmd = &cobra.Command{
+	Use:   "iap",
+	Short: "Identity-Aware Proxy commands",
+	Long: `Identity-Aware Proxy (IAP) commands for reaching instances without
+external IPs or a bastion.
+
+Examples:
+  cml gcp iap tunnel my-vm 22
+  cml gcp iap tunnel my-vm 5432 15432`,
+}
+
+var iapTunnelCmd = &cobra.Command{
+	Use:   "tunnel <instance> <port> [local-port]",
+	Short: "Forward a local port to an instance port through IAP",
+	Long: `Forward a local port to a port on a GCE instance through IAP TCP forwarding.
+The tunnel is implemented natively (no gcloud required) and accepts any
+number of concurrent connections. The local port defaults to the remote
+port. Press Ctrl+C to close the tunnel.
+
+Requires the iap.tunnelInstances.accessViaIAP permission and a firewall rule
+allowing 35.235.240.0/20 to the port.
+
+Examples:
+  cml gcp iap tunnel my-vm 22
+  cml gcp iap tunnel my-vm 5432 15432`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: runIAPTunnel,
+}
+
+func init() {
+	iapCmd.AddCommand(iapTunnelCmd)
+}
+
+func runIAPTunnel(cmd *cobra.Command, args []string) error {
+	instance := args[0]
+
+	remotePort, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid port: %s", args[1])
+	}
+	localPort := remotePort
+	if len(args) > 2 {
+		localPort, err = strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("invalid local port: %s", args[2])
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, _, _, err := getGCPClient(ctx)
+	if err != nil {
+		return err
+	}
+	vmProvider := gcpinternal.NewVMProvider(client)
+
+	fmt.Fprintf(os.Stderr, "→ IAP tunnel localhost:%d → %s:%d\n", localPort, instance, remotePort)
+	if err := vmProvider.IAPTunnel(ctx, instance, remotePort, localPort); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
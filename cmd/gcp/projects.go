@@ -0,0 +1,265 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	internalConfig "github.com/vietdv277/cumulus/internal/config"
+	gcpinternal "github.com/vietdv277/cumulus/internal/gcp"
+	"github.com/vietdv277/cumulus/internal/ui"
+)
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "GCP project commands",
+	Long: `List accessible GCP projects and switch the project of the current context.
+
+Examples:
+  cml gcp projects list
+  cml gcp projects list --create-contexts --region asia-southeast1
+  cml gcp projects use my-project`,
+}
+
+var projectsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List accessible projects",
+	Long: `List the active projects visible to the current credentials via Resource Manager.
+The project of the current GCP context is marked with '*'.
+
+With --create-contexts, a 'gcp:<project-id>' context is added for every
+listed project that does not already have one. Existing contexts are left
+untouched.
+
+Examples:
+  cml gcp projects list
+  cml gcp projects list --filter prod
+  cml gcp projects list --create-contexts --region asia-southeast1`,
+	RunE: runProjectsList,
+}
+
+var projectsUseCmd = &cobra.Command{
+	Use:   "use <project>",
+	Short: "Switch the current context's project",
+	Long: `Set the project of the current GCP context. The project must be visible
+to the current credentials.
+
+Examples:
+  cml gcp projects use my-other-project`,
+	Args: cobra.ExactArgs(1),
+	RunE: runProjectsUse,
+}
+
+var (
+	projectsFilter         string
+	projectsCreateContexts bool
+	projectsRegion         string
+)
+
+func init() {
+	projectsCmd.AddCommand(projectsListCmd)
+	projectsCmd.AddCommand(projectsUseCmd)
+
+	projectsListCmd.Flags().StringVar(&projectsFilter, "filter", "", "Filter by project ID or name substring")
+	projectsListCmd.Flags().BoolVar(&projectsCreateContexts, "create-contexts", false, "Add a gcp:<project-id> context for each listed project")
+	projectsListCmd.Flags().StringVar(&projectsRegion, "region", "", "Region for created contexts (default: current context region)")
+}
+
+func runProjectsList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Listing projects only needs credentials, so a GCP context is optional
+	// and a missing or unreadable one is the same as none
+	ctxConfig, _, _ := internalConfig.GetCurrentContext()
+	var current, region string
+	if ctxConfig != nil && ctxConfig.Provider == "gcp" {
+		current, region = ctxConfig.Project, ctxConfig.Region
+	}
+	if projectsRegion != "" {
+		region = projectsRegion
+	}
+
+	client, err := gcpinternal.NewClient(ctx, gcpinternal.WithProject(current))
+	if err != nil {
+		return fmt.Errorf("failed to create GCP client: %w", err)
+	}
+
+	projects, err := client.ListProjects()
+	if err != nil {
+		return fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	if projectsFilter != "" {
+		filter := strings.ToLower(projectsFilter)
+		var filtered []gcpinternal.Project
+		for _, p := range projects {
+			if strings.Contains(strings.ToLower(p.ID), filter) || strings.Contains(strings.ToLower(p.Name), filter) {
+				filtered = append(filtered, p)
+			}
+		}
+		projects = filtered
+	}
+
+	if len(projects) == 0 {
+		fmt.Println("No projects found")
+		return nil
+	}
+
+	printProjectTable(projects, current)
+
+	if projectsCreateContexts {
+		return createProjectContexts(projects, region)
+	}
+	return nil
+}
+
+// createProjectContexts adds a gcp:<project-id> context for every project
+// without one and reports what was added.
+func createProjectContexts(projects []gcpinternal.Project, region string) error {
+	cfg, err := internalConfig.LoadCMLConfig()
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, p := range projects {
+		name := "gcp:" + p.ID
+		if _, ok := cfg.Contexts[name]; ok {
+			continue
+		}
+		cfg.Contexts[name] = &internalConfig.Context{
+			Provider: "gcp",
+			Project:  p.ID,
+			Region:   region,
+		}
+		added++
+	}
+
+	if added == 0 {
+		fmt.Println("\nAll projects already have contexts")
+		return nil
+	}
+
+	if err := internalConfig.SaveCMLConfig(cfg); err != nil {
+		return err
+	}
+	fmt.Printf("\nAdded %d contexts (gcp:<project-id>)\n", added)
+	return nil
+}
+
+func runProjectsUse(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	projectID := args[0]
+
+	client, _, ctxName, err := getGCPClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	project, err := client.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := internalConfig.LoadCMLConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Contexts[ctxName].Project = project.ID
+	if err := internalConfig.SaveCMLConfig(cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("Context %s now uses project %s\n", ctxName, project.ID)
+	if project.Name != "" && project.Name != project.ID {
+		fmt.Printf("  Name:   %s\n", project.Name)
+	}
+	fmt.Printf("  Number: %s\n", project.Number)
+	return nil
+}
+
+func printProjectTable(projects []gcpinternal.Project, current string) {
+	headers := []string{"", "Project ID", "Name", "Number", "Parent"}
+	widths := []int{1, 32, 30, 14, 24}
+
+	var sb strings.Builder
+
+	// Top border
+	sb.WriteString(ui.BorderStyle.Render(ui.TopLeft))
+	for i, w := range widths {
+		sb.WriteString(ui.BorderStyle.Render(strings.Repeat(ui.Horizontal, w+2)))
+		if i < len(widths)-1 {
+			sb.WriteString(ui.BorderStyle.Render(ui.TopT))
+		}
+	}
+	sb.WriteString(ui.BorderStyle.Render(ui.TopRight))
+	sb.WriteString("\n")
+
+	// Header row
+	sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+	for i, h := range headers {
+		cell := " " + padStr(h, widths[i]) + " "
+		sb.WriteString(ui.HeaderStyle.Render(cell))
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+	}
+	sb.WriteString("\n")
+
+	// Header separator
+	sb.WriteString(ui.BorderStyle.Render(ui.LeftT))
+	for i, w := range widths {
+		sb.WriteString(ui.BorderStyle.Render(strings.Repeat(ui.Horizontal, w+2)))
+		if i < len(widths)-1 {
+			sb.WriteString(ui.BorderStyle.Render(ui.Cross))
+		}
+	}
+	sb.WriteString(ui.BorderStyle.Render(ui.RightT))
+	sb.WriteString("\n")
+
+	// Data rows
+	for _, p := range projects {
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		marker := " "
+		if p.ID == current {
+			marker = "*"
+		}
+		cell := " " + marker + " "
+		sb.WriteString(ui.RunningStyle.Render(cell))
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		cell = " " + padStr(p.ID, widths[1]) + " "
+		sb.WriteString(ui.NameStyle.Render(cell))
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		cell = " " + padStr(p.Name, widths[2]) + " "
+		sb.WriteString(ui.MutedStyle.Render(cell))
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		cell = " " + padStr(p.Number, widths[3]) + " "
+		sb.WriteString(ui.IDStyle.Render(cell))
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		cell = " " + padStr(p.Parent, widths[4]) + " "
+		sb.WriteString(ui.MutedStyle.Render(cell))
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		sb.WriteString("\n")
+	}
+
+	// Bottom border
+	sb.WriteString(ui.BorderStyle.Render(ui.BottomLeft))
+	for i, w := range widths {
+		sb.WriteString(ui.BorderStyle.Render(strings.Repeat(ui.Horizontal, w+2)))
+		if i < len(widths)-1 {
+			sb.WriteString(ui.BorderStyle.Render(ui.BottomT))
+		}
+	}
+	sb.WriteString(ui.BorderStyle.Render(ui.BottomRight))
+	sb.WriteString("\n")
+
+	fmt.Print(sb.String())
+	fmt.Printf("  %d projects\n", len(projects))
+}
@@ -69,19 +69,24 @@ var k8sContextsCmd = &cobra.Command{
 
 var k8sConnectCmd = &cobra.Command{
 	Use:   "connect <cluster>",
-	Short: "Open an SSM tunnel to the context bastion and launch a subshell with HTTPS_PROXY set",
-	Long: `Open an SSM port-forwarding session to the AWS context's bastion instance and
-drop into an interactive subshell with HTTPS_PROXY pointing at the forwarded port.
-kubectl run inside the subshell reaches a private EKS API through the bastion.
-Exiting the subshell tears the tunnel down.
-
-Requires the active AWS context to have a 'bastion' field set (EC2 instance ID).
-The remote port defaults to 8888 and can be overridden via 'bastion_port' on the
-context or --local-port on the command.
+	Short: "Tunnel to the context bastion and launch a subshell with HTTPS_PROXY set",
+	Long: `Open a port-forwarding session to the context's bastion and drop into an
+interactive subshell with HTTPS_PROXY pointing at the forwarded port.
+kubectl run inside the subshell reaches a private EKS or GKE API through the
+bastion. Exiting the subshell tears the tunnel down.
+
+AWS contexts tunnel over SSM and need 'bastion' set to an EC2 instance ID.
+GCP contexts need 'bastion' set to a VM name; 'bastion_project' and
+'bastion_zone' are honoured. With 'bastion_iap' the proxy port is reached
+directly through IAP (no gcloud needed; the proxy must listen on the
+bastion's internal IP), otherwise via 'gcloud compute ssh -L'.
+The remote port defaults to 8888 and can be overridden via 'bastion_port' on
+the context. --local-port changes the local end.
 
 Examples:
   cml k8s connect prod-cluster
-  cml k8s connect prod-cluster --bastion i-013xxxxx --local-port 9999`,
+  cml k8s connect prod-cluster --bastion i-013xxxxx --local-port 9999
+  cml k8s connect gke-prod -c gcp-prod`,
 	Args: cobra.ExactArgs(1),
 	RunE: runK8sConnect,
 }
@@ -105,7 +110,7 @@ func init() {
 	k8sListCmd.Flags().StringVar(&k8sListName, "name", "", "Filter by name substring")
 	k8sListCmd.Flags().BoolVarP(&k8sListInteractive, "interactive", "i", false, "Interactive selection mode")
 
-	k8sConnectCmd.Flags().StringVar(&k8sConnectBastion, "bastion", "", "Override context bastion instance ID")
+	k8sConnectCmd.Flags().StringVar(&k8sConnectBastion, "bastion", "", "Override context bastion (AWS: instance ID; GCP: VM name)")
 	k8sConnectCmd.Flags().IntVar(&k8sConnectLocalPort, "local-port", 0, "Local port for HTTPS_PROXY (default: bastion_port or 8888)")
 
 	k8sCmd.PersistentFlags().StringVarP(&k8sContextFlag, "context", "c", "", "Use specific context")
@@ -305,17 +310,18 @@ func runK8sConnect(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	if ctxConfig.Provider != "aws" {
-		return fmt.Errorf("k8s connect is AWS-only for now (context %q is %s)", ctxName, ctxConfig.Provider)
-	}
 
 	bastion := k8sConnectBastion
 	if bastion == "" {
 		bastion = ctxConfig.Bastion
 	}
 	if bastion == "" {
+		hint := "--bastion i-xxxxxx [--bastion-port 8888]"
+		if ctxConfig.Provider == "gcp" {
+			hint = "--bastion my-bastion --bastion-zone us-central1-a [--bastion-iap] [--bastion-port 8888]"
+		}
 		return fmt.Errorf("no bastion configured for context %q — set one with:\n"+
-			"  cml use update %s --bastion i-xxxxxx [--bastion-port 8888]", ctxName, ctxName)
+			"  cml use update %s %s", ctxName, ctxName, hint)
 	}
 
 	remotePort := ctxConfig.BastionPort
@@ -330,38 +336,11 @@ func runK8sConnect(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	client, err := aws.NewClient(ctx,
-		aws.WithProfile(ctxConfig.Profile),
-		aws.WithRegion(ctxConfig.Region),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create AWS client: %w", err)
-	}
-	vmProvider := aws.NewVMProvider(client, ctxConfig.Profile, ctxConfig.Region)
-
-	fmt.Fprintf(os.Stderr, "→ Starting SSM tunnel to %s:%d (local :%d)\n", bastion, remotePort, localPort)
-	tunnelCmd, err := vmProvider.StartPortForward(ctx, bastion, remotePort, localPort)
+	stopTunnel, err := startK8sTunnel(ctx, ctxConfig, bastion, remotePort, localPort)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if tunnelCmd.Process != nil {
-			_ = tunnelCmd.Process.Signal(syscall.SIGTERM)
-			// Small grace period, then force kill.
-			done := make(chan struct{})
-			go func() { _ = tunnelCmd.Wait(); close(done) }()
-			select {
-			case <-done:
-			case <-time.After(2 * time.Second):
-				_ = tunnelCmd.Process.Kill()
-				<-done
-			}
-		}
-	}()
-
-	if err := waitForPort(ctx, localPort, 10*time.Second); err != nil {
-		return fmt.Errorf("tunnel did not become ready: %w", err)
-	}
+	defer stopTunnel()
 
 	proxy := fmt.Sprintf("http://localhost:%d", localPort)
 	fmt.Fprintf(os.Stderr, "→ Proxy ready at %s\n", proxy)
@@ -410,6 +389,90 @@ func runK8sConnect(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// startK8sTunnel starts the port forward to the bastion's proxy port for the
+// context's provider and returns once the local port accepts connections.
+// AWS uses a native SSM listener; GCP uses a native IAP listener when
+// bastion_iap is set and `gcloud compute ssh -L` otherwise. The returned func
+// tears the tunnel down.
+func startK8sTunnel(ctx context.Context, ctxConfig *config.Context, bastion string, remotePort, localPort int) (func(), error) {
+	var tunnelCmd *exec.Cmd
+
+	switch ctxConfig.Provider {
+	case "aws":
+		client, err := aws.NewClient(ctx,
+			aws.WithProfile(ctxConfig.Profile),
+			aws.WithRegion(ctxConfig.Region),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS client: %w", err)
+		}
+		vmProvider := aws.NewVMProvider(client, ctxConfig.Profile, ctxConfig.Region)
+
+		fmt.Fprintf(os.Stderr, "→ Starting SSM tunnel to %s:%d (local :%d)\n", bastion, remotePort, localPort)
+		ln, err := vmProvider.StartPortForward(ctx, bastion, remotePort, localPort)
+		if err != nil {
+			return nil, err
+		}
+		// The listener is bound already; each connection opens its own session.
+		return func() { _ = ln.Close() }, nil
+
+	case "gcp":
+		client, err := gcpinternal.NewClient(ctx,
+			gcpinternal.WithProject(ctxConfig.Project),
+			gcpinternal.WithRegion(ctxConfig.Region),
+			gcpinternal.WithBastion(bastion, ctxConfig.BastionZone),
+			gcpinternal.WithBastionProject(ctxConfig.BastionProject),
+			gcpinternal.WithBastionIAP(ctxConfig.BastionIAP),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCP client: %w", err)
+		}
+		vmProvider := gcpinternal.NewVMProvider(client)
+
+		if ctxConfig.BastionIAP {
+			fmt.Fprintf(os.Stderr, "→ Starting IAP tunnel to %s:%d (local :%d)\n", bastion, remotePort, localPort)
+			ln, err := vmProvider.ListenIAP(ctx, vmProvider.BastionIAPTarget(bastion, remotePort), localPort)
+			if err != nil {
+				return nil, err
+			}
+			// The listener is bound already; each connection dials IAP on demand.
+			return func() { _ = ln.Close() }, nil
+		}
+
+		fmt.Fprintf(os.Stderr, "→ Starting SSH tunnel to %s:%d (local :%d)\n", bastion, remotePort, localPort)
+		tunnelCmd, err = vmProvider.StartPortForward(ctx, bastion, remotePort, localPort)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", ctxConfig.Provider)
+	}
+
+	// Only the gcloud path gets here
+	stop := func() {
+		if tunnelCmd.Process != nil {
+			_ = tunnelCmd.Process.Signal(syscall.SIGTERM)
+			// Small grace period, then force kill.
+			done := make(chan struct{})
+			go func() { _ = tunnelCmd.Wait(); close(done) }()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				_ = tunnelCmd.Process.Kill()
+				<-done
+			}
+		}
+	}
+
+	// gcloud may need to push SSH keys on first use.
+	if err := waitForPort(ctx, localPort, 30*time.Second); err != nil {
+		stop()
+		return nil, fmt.Errorf("tunnel did not become ready: %w", err)
+	}
+	return stop, nil
+}
+
 // waitForPort polls 127.0.0.1:<port> until a TCP connection succeeds or the timeout expires.
 func waitForPort(ctx context.Context, port int, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/config"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render <template|->",
+	Short: "Render a Go template with secret, VM and database lookups",
+	Long: `Render a Go text/template against the current context (or --context).
+
+Templates can look up live values instead of hardcoding them:
+
+  {{ secret "/app/db/pass" }}             secret value (ssm:// and sm:// work too)
+  {{ secret "sm://app/db" | json "user" }} one field of a JSON secret
+  {{ vm "web-01" "private_ip" }}          VM field: id, name, state, private_ip,
+                                          public_ip, type, zone, or tag:<key>
+  {{ db "prod-main" "endpoint" }}         database field: id, name, engine,
+                                          version, endpoint, port, state, size
+
+Rendering is strict: a missing secret, VM, database, JSON key or field, an
+empty field (e.g. a VM without a public IP) or an undefined template key is
+an error, and nothing is written. Each reference is fetched once per run.
+
+Output goes to stdout, or to --output, which is created with mode 0600.
+
+Examples:
+  cml render config.tmpl > config.yaml
+  cml render -c aws:staging config.tmpl -o config.staging.yaml
+  echo 'DB_HOST={{ db "prod-main" "endpoint" }}' | cml render -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRender,
+}
+
+var (
+	renderOutput      string
+	renderContextFlag string
+)
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVarP(&renderOutput, "output", "o", "", "Write to file (mode 0600) instead of stdout")
+	renderCmd.Flags().StringVarP(&renderContextFlag, "context", "c", "", "Use specific context")
+}
+
+func runRender(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	var data []byte
+	var err error
+	name := args[0]
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+		name = "stdin"
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+
+	ctxConfig, ctxName, err := resolveSecretsContext(renderContextFlag)
+	if err != nil {
+		return err
+	}
+	r := &renderer{ctx: ctx, ctxConfig: ctxConfig, ctxName: ctxName}
+
+	tmpl, err := template.New(filepath.Base(name)).
+		Option("missingkey=error").
+		Funcs(r.funcs()).
+		Parse(string(data))
+	if err != nil {
+		return err
+	}
+
+	// Render fully before writing so a failed lookup leaves no partial output
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return err
+	}
+
+	if renderOutput == "" {
+		_, err = os.Stdout.Write(out.Bytes())
+		return err
+	}
+	if err := writePrivateFile(renderOutput, out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", renderOutput, err)
+	}
+	fmt.Fprintf(os.Stderr, "Rendered %s to %s\n", args[0], renderOutput)
+	return nil
+}
+
+// renderer holds the context's providers, created on first use, and caches
+// lookups so repeated references cost one API call
+type renderer struct {
+	ctx       context.Context
+	ctxConfig *config.Context
+	ctxName   string
+
+	secrets provider.SecretsProvider
+	vms     provider.VMProvider
+	dbs     provider.DBProvider
+
+	secretCache map[string]string
+	vmCache     map[string]*types.VM
+	dbCache     map[string]*types.Database
+}
+
+func (r *renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"secret": r.secret,
+		"json":   renderJSON,
+		"vm":     r.vm,
+		"db":     r.db,
+	}
+}
+
+// renderJSON takes the value last so it can be piped: secret "x" | json "user"
+func renderJSON(key, value string) (string, error) {
+	return secretField(value, key)
+}
+
+func (r *renderer) secret(name string) (string, error) {
+	if v, ok := r.secretCache[name]; ok {
+		return v, nil
+	}
+	if r.secrets == nil {
+		sp, err := newSecretsProvider(r.ctx, r.ctxConfig, r.ctxName)
+		if err != nil {
+			return "", err
+		}
+		r.secrets = sp
+		r.secretCache = make(map[string]string)
+	}
+
+	sv, err := r.secrets.Get(r.ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	r.secretCache[name] = sv.Value
+	return sv.Value, nil
+}
+
+func (r *renderer) vm(name, field string) (string, error) {
+	v, ok := r.vmCache[name]
+	if !ok {
+		if r.vms == nil {
+			vp, err := newVMProvider(r.ctx, r.ctxConfig, r.ctxName)
+			if err != nil {
+				return "", err
+			}
+			r.vms = vp
+			r.vmCache = make(map[string]*types.VM)
+		}
+
+		var err error
+		if v, err = r.vms.Get(r.ctx, name); err != nil {
+			return "", fmt.Errorf("vm %s: %w", name, err)
+		}
+		r.vmCache[name] = v
+	}
+
+	var value string
+	switch {
+	case field == "id":
+		value = v.ID
+	case field == "name":
+		value = v.Name
+	case field == "state":
+		value = string(v.State)
+	case field == "private_ip":
+		value = v.PrivateIP
+	case field == "public_ip":
+		value = v.PublicIP
+	case field == "type":
+		value = v.Type
+	case field == "zone":
+		value = v.Zone
+	case strings.HasPrefix(field, "tag:"):
+		value = v.GetTag(strings.TrimPrefix(field, "tag:"))
+	default:
+		return "", fmt.Errorf("unknown vm field %q", field)
+	}
+	if value == "" {
+		return "", fmt.Errorf("vm %s has no %s", name, field)
+	}
+	return value, nil
+}
+
+func (r *renderer) db(name, field string) (string, error) {
+	d, ok := r.dbCache[name]
+	if !ok {
+		if r.dbs == nil {
+			dp, err := newDBProvider(r.ctx, r.ctxConfig, r.ctxName)
+			if err != nil {
+				return "", err
+			}
+			r.dbs = dp
+			r.dbCache = make(map[string]*types.Database)
+		}
+
+		var err error
+		if d, err = r.dbs.Get(r.ctx, name); err != nil {
+			return "", fmt.Errorf("db %s: %w", name, err)
+		}
+		r.dbCache[name] = d
+	}
+
+	var value string
+	switch field {
+	case "id":
+		value = d.ID
+	case "name":
+		value = d.Name
+	case "engine":
+		value = d.Engine
+	case "version":
+		value = d.Version
+	case "endpoint":
+		value = d.Endpoint
+	case "port":
+		if d.Port != 0 {
+			value = strconv.Itoa(d.Port)
+		}
+	case "state":
+		value = d.State
+	case "size":
+		value = d.Size
+	default:
+		return "", fmt.Errorf("unknown db field %q", field)
+	}
+	if value == "" {
+		return "", fmt.Errorf("db %s has no %s", name, field)
+	}
+	return value, nil
+}
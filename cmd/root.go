@@ -1,13 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	awscmd "github.com/vietdv277/cumulus/cmd/aws"
+	gcpcmd "github.com/vietdv277/cumulus/cmd/gcp"
 	"github.com/vietdv277/cumulus/internal/config"
 )
 
@@ -64,6 +67,7 @@ func init() {
 
 	// Add provider-specific commands
 	rootCmd.AddCommand(awscmd.AWSCmd)
+	rootCmd.AddCommand(gcpcmd.GCPCmd)
 }
 
 func initConfig() {
@@ -103,3 +107,13 @@ func GetProfile() string {
 func GetRegion() string {
 	return region
 }
+
+// confirm prints prompt and reports whether the user answered y or yes
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/vietdv277/cumulus/internal/aws"
 	"github.com/vietdv277/cumulus/internal/config"
+	gcpinternal "github.com/vietdv277/cumulus/internal/gcp"
 	"github.com/vietdv277/cumulus/internal/ui"
 	"github.com/vietdv277/cumulus/pkg/provider"
 	"github.com/vietdv277/cumulus/pkg/types"
@@ -25,6 +28,12 @@ var secretsCmd = &cobra.Command{
 For AWS, this manages both SSM Parameter Store and Secrets Manager.
 For GCP, this manages Secret Manager.
 
+On AWS, a name can pick its backend explicitly with a scheme:
+ssm:///app/db-password (SSM) or sm://app/db (Secrets Manager). Names
+without a scheme use the context's secrets_backend ('cml use update
+--secrets-backend ssm|sm'); when that is unset, names starting with / are
+SSM parameters and others are Secrets Manager secrets.
+
 Commands operate within the current context. Use 'cml use <context>' to switch.
 
 Examples:
@@ -32,7 +41,14 @@ Examples:
   cml secrets list /app/               # List secrets with prefix
   cml secrets get /app/db-password     # Get secret value
   cml secrets set /app/new-param val   # Create/update secret
-  cml secrets delete /app/old-param    # Delete secret`,
+  cml secrets edit app/db-config       # Edit in $EDITOR
+  cml secrets history app/db-config    # Version history
+  cml secrets delete /app/old-param    # Delete secret
+  cml secrets diff --from aws:dev --to aws:staging /app/
+  cml secrets copy --from aws:dev --to aws:staging /app/ --dry-run
+  cml secrets export /app/prod/ --format dotenv
+  cml secrets import staging.env --prefix /app/staging/
+  cml secrets exec --prefix /app/prod/ -- ./server`,
 }
 
 var secretsListCmd = &cobra.Command{
@@ -41,13 +57,14 @@ var secretsListCmd = &cobra.Command{
 	Short:   "List secrets",
 	Long: `List secrets in the current context.
 
-For AWS:
-- Secrets starting with / are from SSM Parameter Store
-- Other secrets are from Secrets Manager
+On AWS, both SSM Parameter Store and Secrets Manager are listed; the
+BACKEND column shows which one each secret is in. A prefix with a scheme
+(ssm:// or sm://), --ssm-only or --sm-only lists one backend.
 
 Examples:
   cml secrets list                     # List all secrets
-  cml secrets list /app/               # List SSM parameters with prefix
+  cml secrets list /app/               # List secrets with prefix
+  cml secrets list ssm:///app/         # List SSM parameters with prefix
   cml secrets list --ssm-only          # List only SSM parameters
   cml secrets list --sm-only           # List only Secrets Manager secrets`,
 	RunE: runSecretsList,
@@ -58,30 +75,49 @@ var secretsGetCmd = &cobra.Command{
 	Short: "Get secret value",
 	Long: `Get the value of a secret.
 
-For AWS:
-- Secrets starting with / are retrieved from SSM Parameter Store
-- Other secrets are retrieved from Secrets Manager
+For AWS, the backend comes from the name's scheme (ssm:// or sm://), else
+the context's secrets_backend, else a leading / means SSM Parameter Store.
+
+For JSON object values, --key prints one field (strings unquoted) and --keys
+lists the field names, one per line, for use in scripts.
 
 Examples:
   cml secrets get /app/db-password
-  cml secrets get my-api-key`,
+  cml secrets get my-api-key
+  cml secrets get app/db --key password
+  cml secrets get app/db --keys`,
 	Args: cobra.ExactArgs(1),
 	RunE: runSecretsGet,
 }
 
 var secretsSetCmd = &cobra.Command{
-	Use:   "set <name> <value>",
+	Use:   "set <name> [value]",
 	Short: "Create or update a secret",
 	Long: `Create or update a secret.
 
-For AWS:
-- Names starting with / are stored in SSM Parameter Store
-- Other names are stored in Secrets Manager
+The value can be given as an argument, read from a file with --from-file, or
+read from stdin with --stdin (a single trailing newline is dropped). Prefer
+--from-file or --stdin: arguments end up in shell history and ps output.
+
+For AWS, the backend comes from the name's scheme (ssm:// or sm://), else
+the context's secrets_backend, else a leading / means SSM Parameter Store.
+New SSM parameters are SecureStrings encrypted with the context's
+secrets_kms_key (default: the AWS managed key); existing ones keep their
+type and key unless --type or --kms-key is given.
+
+With --key, the secret must be a JSON object and only that field is set;
+the other fields are kept. The field is stored as a string unless it
+currently holds a number, boolean, array or object and the new value is valid
+JSON.
 
 Examples:
   cml secrets set /app/db-password "mysecret"
-  cml secrets set my-api-key "abc123"`,
-	Args: cobra.ExactArgs(2),
+  cml secrets set my-api-key --from-file key.txt
+  cml secrets set ssm:///app/log-level debug --type String
+  cml secrets set sm://app/api-key --stdin
+  pbpaste | cml secrets set app/db-config --stdin
+  cml secrets set app/db --key password --stdin`,
+	Args: cobra.RangeArgs(1, 2),
 	RunE: runSecretsSet,
 }
 
@@ -101,6 +137,12 @@ var (
 	secretsContextFlag string
 	secretsSSMOnly     bool
 	secretsSMOnly      bool
+	secretsFromFile    string
+	secretsStdin       bool
+	secretsKey         string
+	secretsKeys        bool
+	secretsSSMType     string
+	secretsKMSKey      string
 )
 
 func init() {
@@ -114,36 +156,61 @@ func init() {
 	secretsListCmd.Flags().BoolVar(&secretsSSMOnly, "ssm-only", false, "List only SSM Parameter Store secrets")
 	secretsListCmd.Flags().BoolVar(&secretsSMOnly, "sm-only", false, "List only Secrets Manager secrets")
 
+	// Get flags
+	secretsGetCmd.Flags().StringVar(&secretsKey, "key", "", "Print one field of a JSON secret")
+	secretsGetCmd.Flags().BoolVar(&secretsKeys, "keys", false, "List the fields of a JSON secret")
+
+	// Set flags
+	secretsSetCmd.Flags().StringVar(&secretsKey, "key", "", "Set one field of a JSON secret")
+
+	// SSM write options, on every command that writes secrets
+	for _, c := range []*cobra.Command{secretsSetCmd, secretsEditCmd, secretsImportCmd, secretsCopyCmd} {
+		c.Flags().StringVar(&secretsSSMType, "type", "", "AWS SSM: parameter type (String, StringList, SecureString)")
+		c.Flags().StringVar(&secretsKMSKey, "kms-key", "", "AWS SSM: KMS key for SecureString parameters (default: context secrets_kms_key)")
+	}
+	secretsSetCmd.Flags().StringVar(&secretsFromFile, "from-file", "", "Read the value from a file")
+	secretsSetCmd.Flags().BoolVar(&secretsStdin, "stdin", false, "Read the value from stdin")
+
 	// Global context override
 	secretsCmd.PersistentFlags().StringVarP(&secretsContextFlag, "context", "c", "", "Use specific context")
 }
 
 // getSecretsProvider returns the secrets provider for the current or specified context
 func getSecretsProvider(ctx context.Context) (provider.SecretsProvider, error) {
-	var ctxConfig *config.Context
-	var ctxName string
-	var err error
-
-	if secretsContextFlag != "" {
-		cfg, loadErr := config.LoadCMLConfig()
-		if loadErr != nil {
-			return nil, loadErr
-		}
-		ctxConfig = cfg.Contexts[secretsContextFlag]
-		if ctxConfig == nil {
-			return nil, fmt.Errorf("context %q not found", secretsContextFlag)
-		}
-		ctxName = secretsContextFlag
-	} else {
-		ctxConfig, ctxName, err = config.GetCurrentContext()
+	ctxConfig, ctxName, err := resolveSecretsContext(secretsContextFlag)
+	if err != nil {
+		return nil, err
+	}
+	return newSecretsProvider(ctx, ctxConfig, ctxName)
+}
+
+// resolveSecretsContext returns the named context, or the current context
+// when name is empty
+func resolveSecretsContext(name string) (*config.Context, string, error) {
+	if name != "" {
+		cfg, err := config.LoadCMLConfig()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		ctxConfig := cfg.Contexts[name]
 		if ctxConfig == nil {
-			return nil, fmt.Errorf("no context set. Use 'cml use <context>' to set one")
+			return nil, "", fmt.Errorf("context %q not found", name)
 		}
+		return ctxConfig, name, nil
 	}
 
+	ctxConfig, ctxName, err := config.GetCurrentContext()
+	if err != nil {
+		return nil, "", err
+	}
+	if ctxConfig == nil {
+		return nil, "", fmt.Errorf("no context set. Use 'cml use <context>' to set one")
+	}
+	return ctxConfig, ctxName, nil
+}
+
+// newSecretsProvider builds the secrets provider for a context
+func newSecretsProvider(ctx context.Context, ctxConfig *config.Context, ctxName string) (provider.SecretsProvider, error) {
 	switch ctxConfig.Provider {
 	case "aws":
 		client, err := aws.NewClient(ctx,
@@ -158,16 +225,63 @@ func getSecretsProvider(ctx context.Context) (provider.SecretsProvider, error) {
 		ssmClient := ssm.NewFromConfig(client.Config())
 		smClient := secretsmanager.NewFromConfig(client.Config())
 
-		return aws.NewSecretsProvider(client, ssmClient, smClient, ctxConfig.Profile, ctxConfig.Region), nil
+		opts, err := awsSecretsOptions(ctxConfig, ctxName)
+		if err != nil {
+			return nil, err
+		}
+		return aws.NewSecretsProvider(client, ssmClient, smClient, ctxConfig.Profile, ctxConfig.Region, opts...), nil
 
 	case "gcp":
-		return nil, fmt.Errorf("GCP Secrets provider not yet implemented")
+		gcpClient, err := gcpinternal.NewClient(ctx,
+			gcpinternal.WithProject(ctxConfig.Project),
+			gcpinternal.WithRegion(ctxConfig.Region),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCP client: %w", err)
+		}
+		return gcpinternal.NewSecretsProvider(gcpClient), nil
 
 	default:
 		return nil, fmt.Errorf("unknown provider: %s (context: %s)", ctxConfig.Provider, ctxName)
 	}
 }
 
+// awsSecretsOptions builds the AWS secrets options from the context's
+// secrets_backend and secrets_kms_key and the --type/--kms-key flags
+func awsSecretsOptions(ctxConfig *config.Context, ctxName string) ([]aws.SecretsOption, error) {
+	var opts []aws.SecretsOption
+
+	switch ctxConfig.SecretsBackend {
+	case "":
+	case aws.SecretsBackendSSM, aws.SecretsBackendSM:
+		opts = append(opts, aws.WithSecretsBackend(ctxConfig.SecretsBackend))
+	default:
+		return nil, fmt.Errorf("invalid secrets_backend %q in context %s (use ssm or sm)", ctxConfig.SecretsBackend, ctxName)
+	}
+
+	if secretsSSMType != "" {
+		var paramType string
+		for _, t := range []string{"String", "StringList", "SecureString"} {
+			if strings.EqualFold(secretsSSMType, t) {
+				paramType = t
+			}
+		}
+		if paramType == "" {
+			return nil, fmt.Errorf("invalid --type %q (use String, StringList or SecureString)", secretsSSMType)
+		}
+		opts = append(opts, aws.WithSSMParameterType(paramType))
+	}
+
+	kmsKey := ctxConfig.SecretsKMSKey
+	if secretsKMSKey != "" {
+		kmsKey = secretsKMSKey
+	}
+	if kmsKey != "" {
+		opts = append(opts, aws.WithKMSKey(kmsKey))
+	}
+	return opts, nil
+}
+
 func runSecretsList(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
@@ -181,6 +295,14 @@ func runSecretsList(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
 		filter.Prefix = args[0]
 	}
+	switch {
+	case secretsSSMOnly && secretsSMOnly:
+		return fmt.Errorf("--ssm-only and --sm-only are mutually exclusive")
+	case secretsSSMOnly:
+		filter.Backend = aws.SecretsBackendSSM
+	case secretsSMOnly:
+		filter.Backend = aws.SecretsBackendSM
+	}
 
 	// List secrets
 	secrets, err := secretsProvider.List(ctx, filter)
@@ -212,6 +334,25 @@ func runSecretsGet(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if secretsKeys {
+		fields, err := parseJSONFields(secretValue.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		for _, f := range fields {
+			fmt.Println(f.Key)
+		}
+		return nil
+	}
+	if secretsKey != "" {
+		field, err := secretField(secretValue.Value, secretsKey)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		fmt.Println(field)
+		return nil
+	}
+
 	// Print secret details
 	printSecretDetails(secretValue)
 
@@ -221,22 +362,73 @@ func runSecretsGet(cmd *cobra.Command, args []string) error {
 func runSecretsSet(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	value, err := readSecretValueInput(args[1:])
+	if err != nil {
+		return err
+	}
+
 	secretsProvider, err := getSecretsProvider(ctx)
 	if err != nil {
 		return err
 	}
 
 	name := args[0]
-	value := args[1]
+
+	// Read-modify-write a single field of a JSON secret
+	if secretsKey != "" {
+		current, err := secretsProvider.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		if value, err = setSecretField(current.Value, secretsKey, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
 
 	if err := secretsProvider.Set(ctx, name, value); err != nil {
 		return err
 	}
 
+	if secretsKey != "" {
+		fmt.Printf("Secret set: %s (key %s)\n", name, secretsKey)
+		return nil
+	}
 	fmt.Printf("Secret set: %s\n", name)
 	return nil
 }
 
+// readSecretValueInput returns the value from exactly one of a positional
+// argument, --from-file or --stdin
+func readSecretValueInput(args []string) (string, error) {
+	sources := len(args)
+	if secretsFromFile != "" {
+		sources++
+	}
+	if secretsStdin {
+		sources++
+	}
+	if sources != 1 {
+		return "", fmt.Errorf("give the value as exactly one of an argument, --from-file or --stdin")
+	}
+
+	switch {
+	case secretsFromFile != "":
+		data, err := os.ReadFile(secretsFromFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", secretsFromFile, err)
+		}
+		return string(data), nil
+	case secretsStdin:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	}
+	return args[0], nil
+}
+
 func runSecretsDelete(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
@@ -257,8 +449,8 @@ func runSecretsDelete(cmd *cobra.Command, args []string) error {
 
 // printSecretsTable prints secrets in a table format
 func printSecretsTable(secrets []types.Secret) {
-	headers := []string{"Name", "ARN/Type", "Updated"}
-	widths := []int{45, 45, 18}
+	headers := []string{"Name", "Backend", "ARN", "Updated"}
+	widths := []int{45, 13, 45, 18}
 
 	var sb strings.Builder
 
@@ -302,14 +494,13 @@ func printSecretsTable(secrets []types.Secret) {
 		sb.WriteString(ui.NameStyle.Render(cell))
 		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
 
-		// ARN/Type
-		arnOrType := secret.ARN
-		if arnOrType == secret.Name {
-			arnOrType = "SSM Parameter"
-		} else if strings.Contains(arnOrType, "secretsmanager") {
-			arnOrType = "Secrets Manager"
-		}
-		cell = " " + padRightSecrets(arnOrType, widths[1]) + " "
+		// Backend
+		cell = " " + padRightSecrets(secret.Backend, widths[1]) + " "
+		sb.WriteString(cell)
+		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
+
+		// ARN
+		cell = " " + padRightSecrets(secret.ARN, widths[2]) + " "
 		sb.WriteString(ui.MutedStyle.Render(cell))
 		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
 
@@ -318,7 +509,7 @@ func printSecretsTable(secrets []types.Secret) {
 		if !secret.UpdatedAt.IsZero() {
 			updated = secret.UpdatedAt.Format("2006-01-02 15:04")
 		}
-		cell = " " + padRightSecrets(updated, widths[2]) + " "
+		cell = " " + padRightSecrets(updated, widths[3]) + " "
 		sb.WriteString(ui.MutedStyle.Render(cell))
 		sb.WriteString(ui.BorderStyle.Render(ui.Vertical))
 
@@ -358,6 +549,9 @@ func printSecretDetails(sv *types.SecretValue) {
 		fmt.Printf("  Created:  %s\n", sv.CreatedAt.Format("2006-01-02 15:04:05"))
 	}
 	fmt.Printf("  Provider: %s\n", formatProviderName(sv.Provider))
+	if sv.Backend != "" {
+		fmt.Printf("  Backend:  %s\n", sv.Backend)
+	}
 	fmt.Println()
 	fmt.Println(ui.HeaderStyle.Render("Value"))
 	fmt.Println(ui.MutedStyle.Render("───────────────────────────────"))
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/config"
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var secretsAuditCmd = &cobra.Command{
+	Use:   "audit [prefix]",
+	Short: "Report stale, plaintext-looking and unrotated secrets",
+	Long: `Audit secret hygiene in one or more contexts.
+
+Checks:
+  stale        not updated in --stale-days days (GCP: since the newest version)
+  plaintext    AWS SSM String/StringList parameter whose name or value looks
+               like a credential (password, token, api key, AWS access key,
+               private key, JWT, URL with a password, ...); it should be a
+               SecureString or a Secrets Manager secret
+  no-rotation  AWS Secrets Manager secret without automatic rotation
+
+Values of SSM String parameters are read to look for credentials; use
+--no-values to check names only. Values are never printed.
+
+By default the current context (or --context) is audited; --contexts and
+--all-contexts audit several, and a context that fails is reported without
+stopping the others.
+
+Examples:
+  cml secrets audit
+  cml secrets audit /app/ --stale-days 180
+  cml secrets audit --contexts aws:dev,aws:prod -o json > audit.json
+  cml secrets audit --all-contexts`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSecretsAudit,
+}
+
+var (
+	secretsAuditStaleDays   int
+	secretsAuditContexts    []string
+	secretsAuditAllContexts bool
+	secretsAuditNoValues    bool
+	secretsAuditOutput      string
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsAuditCmd)
+
+	secretsAuditCmd.Flags().IntVar(&secretsAuditStaleDays, "stale-days", 90, "Flag secrets not updated in this many days (0 disables)")
+	secretsAuditCmd.Flags().StringSliceVar(&secretsAuditContexts, "contexts", nil, "Contexts to audit (default: current context or --context)")
+	secretsAuditCmd.Flags().BoolVar(&secretsAuditAllContexts, "all-contexts", false, "Audit every configured context")
+	secretsAuditCmd.Flags().BoolVar(&secretsAuditNoValues, "no-values", false, "Check SSM String parameter names only, without reading values")
+	secretsAuditCmd.Flags().StringVarP(&secretsAuditOutput, "output", "o", "table", "Output format: table, json")
+}
+
+// Audit check names
+const (
+	auditStale      = "stale"
+	auditPlaintext  = "plaintext"
+	auditNoRotation = "no-rotation"
+)
+
+// secretFinding is one audit result
+type secretFinding struct {
+	Context   string    `json:"context"`
+	Name      string    `json:"name"`
+	Backend   string    `json:"backend"`
+	Check     string    `json:"check"`
+	Detail    string    `json:"detail"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	// credentialName matches secret names that suggest a credential
+	credentialName = regexp.MustCompile(`(?i)(passw(or)?d|pwd|secret|token|api[_-]?key|private[_-]?key|access[_-]?key|credential)`)
+
+	// credentialValues match well-known credential formats, most specific first
+	credentialValues = []struct {
+		re   *regexp.Regexp
+		desc string
+	}{
+		{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`), "a private key"},
+		{regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`), "an AWS access key"},
+		{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), "a GitHub token"},
+		{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "a Slack token"},
+		{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), "a JWT"},
+		{regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`), "a URL with a password"},
+		{regexp.MustCompile(`(?i)(password|passwd|pwd|secret|token|api[_-]?key)\s*[=:]\s*\S`), "an embedded password"},
+	}
+)
+
+func runSecretsAudit(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if secretsAuditOutput != "table" && secretsAuditOutput != "json" {
+		return fmt.Errorf("invalid output format %q (use table or json)", secretsAuditOutput)
+	}
+	if secretsAuditAllContexts && len(secretsAuditContexts) > 0 {
+		return fmt.Errorf("--contexts and --all-contexts are mutually exclusive")
+	}
+
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	names := secretsAuditContexts
+	if secretsAuditAllContexts {
+		cfg, err := config.LoadCMLConfig()
+		if err != nil {
+			return err
+		}
+		for name := range cfg.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		_, name, err := resolveSecretsContext(secretsContextFlag)
+		if err != nil {
+			return err
+		}
+		names = []string{name}
+	}
+
+	findings := []secretFinding{}
+	audited, failed := 0, 0
+	for _, name := range names {
+		ctxConfig, ctxName, err := resolveSecretsContext(name)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", ui.StoppedStyle.Render("✗"), name, err)
+			continue
+		}
+
+		sp, err := newSecretsProvider(ctx, ctxConfig, ctxName)
+		if err == nil {
+			var secrets []types.Secret
+			if secrets, err = sp.List(ctx, &provider.SecretFilter{Prefix: prefix}); err == nil {
+				audited += len(secrets)
+				findings = append(findings, auditSecrets(ctx, sp, ctxName, secrets)...)
+			}
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", ui.StoppedStyle.Render("✗"), ctxName, err)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Context != findings[j].Context {
+			return findings[i].Context < findings[j].Context
+		}
+		return findings[i].Name < findings[j].Name
+	})
+
+	if secretsAuditOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return err
+		}
+	} else {
+		printSecretFindings(findings, audited, len(names)-failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d contexts could not be audited", failed, len(names))
+	}
+	return nil
+}
+
+// auditSecrets runs the checks on one context's secrets
+func auditSecrets(ctx context.Context, sp provider.SecretsProvider, ctxName string, secrets []types.Secret) []secretFinding {
+	var findings []secretFinding
+	now := time.Now()
+
+	for _, s := range secrets {
+		finding := func(check, detail string) {
+			findings = append(findings, secretFinding{
+				Context:   ctxName,
+				Name:      secretRef(s),
+				Backend:   s.Backend,
+				Check:     check,
+				Detail:    detail,
+				UpdatedAt: s.UpdatedAt,
+			})
+		}
+
+		if secretsAuditStaleDays > 0 {
+			updated := s.UpdatedAt
+			if updated.IsZero() {
+				updated = latestVersionTime(ctx, sp, s)
+			}
+			if days := int(now.Sub(updated).Hours() / 24); !updated.IsZero() && days >= secretsAuditStaleDays {
+				finding(auditStale, fmt.Sprintf("updated %d days ago", days))
+			}
+		}
+
+		switch {
+		case s.Backend == "ssm" && (s.Type == "String" || s.Type == "StringList"):
+			if detail := plaintextCredential(ctx, sp, s); detail != "" {
+				finding(auditPlaintext, s.Type+": "+detail)
+			}
+		case s.Backend == "sm" && !s.RotationEnabled:
+			finding(auditNoRotation, "automatic rotation not enabled")
+		}
+	}
+	return findings
+}
+
+// latestVersionTime returns when the newest version of a secret was written,
+// for backends that don't list an update time (GCP), or zero when unknown
+func latestVersionTime(ctx context.Context, sp provider.SecretsProvider, s types.Secret) time.Time {
+	versioner, ok := sp.(provider.SecretVersioner)
+	if !ok {
+		return time.Time{}
+	}
+	versions, err := versioner.History(ctx, secretRef(s))
+	if err != nil || len(versions) == 0 {
+		return time.Time{}
+	}
+	return versions[0].CreatedAt
+}
+
+// plaintextCredential explains why an unencrypted parameter looks like a
+// credential, or returns "" when it doesn't
+func plaintextCredential(ctx context.Context, sp provider.SecretsProvider, s types.Secret) string {
+	if !secretsAuditNoValues {
+		// An unreadable value falls back to the name check
+		if sv, err := sp.Get(ctx, secretRef(s)); err == nil {
+			for _, c := range credentialValues {
+				if c.re.MatchString(sv.Value) {
+					return "value looks like " + c.desc
+				}
+			}
+		}
+	}
+	if m := credentialName.FindString(s.Name); m != "" {
+		return fmt.Sprintf("name contains %q", strings.ToLower(m))
+	}
+	return ""
+}
+
+func printSecretFindings(findings []secretFinding, audited, contexts int) {
+	if len(findings) == 0 {
+		fmt.Printf("%s No findings (%d secrets in %d contexts)\n", ui.RunningStyle.Render("✓"), audited, contexts)
+		return
+	}
+
+	counts := map[string]int{}
+	rows := make([][]string, 0, len(findings))
+	for _, f := range findings {
+		counts[f.Check]++
+		rows = append(rows, []string{f.Context, f.Name, f.Check, f.Detail})
+	}
+	renderSimpleTable([]string{"Context", "Name", "Check", "Detail"}, []int{16, 45, 11, 50}, rows)
+
+	fmt.Printf("  %s %d findings: %d %s, %d %s, %d %s (%d secrets in %d contexts)\n",
+		ui.PendingStyle.Render("!"), len(findings),
+		counts[auditStale], auditStale, counts[auditPlaintext], auditPlaintext, counts[auditNoRotation], auditNoRotation,
+		audited, contexts)
+}
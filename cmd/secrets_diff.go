@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/aws"
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var secretsDiffCmd = &cobra.Command{
+	Use:   "diff <prefix>",
+	Short: "Compare secrets under a prefix between two contexts",
+	Long: `Compare the secrets under prefix in one context with those in another.
+
+Secrets are matched by their name relative to the prefix. Keys only in the
+source are shown as added (+), keys only in the destination as removed (-),
+and keys whose values differ as changed (~). Values are hidden unless
+--show-values is given.
+
+--from defaults to the current context. Use --to-prefix when the secrets live
+under a different prefix in the destination. Secret Manager (GCP) names cannot
+contain '/', so when the destination is GCP, '/' in relative names becomes '_'.
+
+Examples:
+  cml secrets diff --from aws:dev --to aws:staging /app/
+  cml secrets diff --to aws:prod /app/ --show-values
+  cml secrets diff --from aws:dev --to gcp:dev /app/ --to-prefix app_`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsDiff,
+}
+
+var secretsCopyCmd = &cobra.Command{
+	Use:     "copy <prefix>",
+	Aliases: []string{"cp", "promote"},
+	Short:   "Copy secrets under a prefix from one context to another",
+	Long: `Copy the secrets under prefix from one context to another, e.g. to promote
+configuration from dev to staging.
+
+Added and changed keys (as shown by 'cml secrets diff') are written to the
+destination; keys only in the destination are left alone. With
+--skip-existing, only added keys are written. The plan is shown and
+confirmed before anything is written.
+
+Examples:
+  cml secrets copy --from aws:dev --to aws:staging /app/ --dry-run
+  cml secrets copy --from aws:dev --to aws:staging /app/ --skip-existing
+  cml secrets copy --from aws:dev --to gcp:dev /app/ --to-prefix app_ -y`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsCopy,
+}
+
+var (
+	secretsFromContext  string
+	secretsToContext    string
+	secretsToPrefix     string
+	secretsShowValues   bool
+	secretsSkipExisting bool
+	secretsCopyDryRun   bool
+	secretsCopyYes      bool
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsDiffCmd)
+	secretsCmd.AddCommand(secretsCopyCmd)
+
+	for _, c := range []*cobra.Command{secretsDiffCmd, secretsCopyCmd} {
+		c.Flags().StringVar(&secretsFromContext, "from", "", "Source context (default: current context)")
+		c.Flags().StringVar(&secretsToContext, "to", "", "Destination context")
+		c.Flags().StringVar(&secretsToPrefix, "to-prefix", "", "Prefix in the destination (default: same as source)")
+		_ = c.MarkFlagRequired("to")
+	}
+
+	secretsDiffCmd.Flags().BoolVar(&secretsShowValues, "show-values", false, "Show secret values of changed keys")
+
+	secretsCopyCmd.Flags().BoolVar(&secretsSkipExisting, "skip-existing", false, "Only copy keys missing from the destination")
+	secretsCopyCmd.Flags().BoolVar(&secretsCopyDryRun, "dry-run", false, "Show what would be copied without writing")
+	secretsCopyCmd.Flags().BoolVarP(&secretsCopyYes, "yes", "y", false, "Skip confirmation prompt")
+}
+
+// secretChangeKind classifies a key in a secrets diff
+type secretChangeKind int
+
+const (
+	secretUnchanged secretChangeKind = iota
+	secretAdded
+	secretRemoved
+	secretChanged
+)
+
+// secretChange is one key in a secrets diff
+type secretChange struct {
+	Key      string // name relative to the prefix
+	Kind     secretChangeKind
+	SrcName  string // full name in the source (empty when removed)
+	DstName  string // full name in the destination
+	SrcValue string
+	DstValue string
+}
+
+// secretsSide is one end of a diff or copy
+type secretsSide struct {
+	ctxName  string
+	provider string // aws, gcp
+	prefix   string
+	secrets  provider.SecretsProvider
+}
+
+func runSecretsDiff(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	src, dst, err := openSecretsSides(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	changes, err := diffSecrets(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s → %s %s\n\n",
+		ui.NameStyle.Render(src.ctxName), src.prefix,
+		ui.NameStyle.Render(dst.ctxName), dst.prefix)
+
+	counts := map[secretChangeKind]int{}
+	for _, c := range changes {
+		counts[c.Kind]++
+		switch c.Kind {
+		case secretAdded:
+			fmt.Printf("  %s %s\n", ui.RunningStyle.Render("+"), c.Key)
+		case secretRemoved:
+			fmt.Printf("  %s %s\n", ui.StoppedStyle.Render("-"), c.Key)
+		case secretChanged:
+			fmt.Printf("  %s %s\n", ui.PendingStyle.Render("~"), c.Key)
+			if secretsShowValues {
+				fmt.Printf("      %s %s\n", ui.StoppedStyle.Render("-"), ui.MutedStyle.Render(c.DstValue))
+				fmt.Printf("      %s %s\n", ui.RunningStyle.Render("+"), ui.MutedStyle.Render(c.SrcValue))
+			}
+		}
+	}
+	if counts[secretAdded]+counts[secretRemoved]+counts[secretChanged] == 0 {
+		fmt.Println("  No differences")
+	}
+
+	fmt.Printf("\n%d added, %d removed, %d changed, %d unchanged\n",
+		counts[secretAdded], counts[secretRemoved], counts[secretChanged], counts[secretUnchanged])
+	return nil
+}
+
+func runSecretsCopy(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	src, dst, err := openSecretsSides(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	changes, err := diffSecrets(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+
+	var plan []secretChange
+	for _, c := range changes {
+		if c.Kind == secretAdded || (c.Kind == secretChanged && !secretsSkipExisting) {
+			plan = append(plan, c)
+		}
+	}
+	if len(plan) == 0 {
+		fmt.Println("Nothing to copy")
+		return nil
+	}
+
+	fmt.Printf("Copy from %s to %s:\n\n", ui.NameStyle.Render(src.ctxName), ui.NameStyle.Render(dst.ctxName))
+	for _, c := range plan {
+		mark, verb := ui.RunningStyle.Render("+"), "create"
+		if c.Kind == secretChanged {
+			mark, verb = ui.PendingStyle.Render("~"), "update"
+		}
+		fmt.Printf("  %s %s %s\n", mark, c.DstName, ui.MutedStyle.Render("("+verb+")"))
+	}
+
+	if secretsCopyDryRun {
+		fmt.Printf("\nDry run: %d secret(s) would be written\n", len(plan))
+		return nil
+	}
+
+	if !secretsCopyYes && !confirm(fmt.Sprintf("\nWrite %d secret(s) to %s? [y/N]: ", len(plan), dst.ctxName)) {
+		fmt.Println("Copy cancelled")
+		return nil
+	}
+
+	failed := 0
+	for _, c := range plan {
+		if err := dst.secrets.Set(ctx, c.DstName, c.SrcValue); err != nil {
+			fmt.Printf("%s %s: %v\n", ui.StoppedStyle.Render("✗"), c.DstName, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s\n", ui.RunningStyle.Render("✓"), c.DstName)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secret(s) failed to copy", failed, len(plan))
+	}
+	return nil
+}
+
+// openSecretsSides resolves --from/--to into secrets providers
+func openSecretsSides(ctx context.Context, prefix string) (*secretsSide, *secretsSide, error) {
+	open := func(name, prefix string) (*secretsSide, error) {
+		ctxConfig, ctxName, err := resolveSecretsContext(name)
+		if err != nil {
+			return nil, err
+		}
+		sp, err := newSecretsProvider(ctx, ctxConfig, ctxName)
+		if err != nil {
+			return nil, err
+		}
+		return &secretsSide{ctxName: ctxName, provider: ctxConfig.Provider, prefix: prefix, secrets: sp}, nil
+	}
+
+	src, err := open(secretsFromContext, prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	toPrefix := secretsToPrefix
+	if toPrefix == "" {
+		toPrefix = prefix
+	}
+	dst, err := open(secretsToContext, toPrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	if src.ctxName == dst.ctxName && src.prefix == dst.prefix {
+		return nil, nil, fmt.Errorf("source and destination are both %s %s", src.ctxName, src.prefix)
+	}
+	return src, dst, nil
+}
+
+// secretDestName maps a name relative to the source prefix to its full name
+// in the destination
+func secretDestName(dst *secretsSide, key string) string {
+	if dst.provider == "gcp" {
+		key = strings.ReplaceAll(strings.TrimPrefix(key, "/"), "/", "_")
+	}
+	return dst.prefix + key
+}
+
+// diffSecrets fetches the values under both prefixes and compares them,
+// sorted by key
+func diffSecrets(ctx context.Context, src, dst *secretsSide) ([]secretChange, error) {
+	srcValues, err := fetchSecretValues(ctx, src.secrets, src.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src.ctxName, err)
+	}
+	dstValues, err := fetchSecretValues(ctx, dst.secrets, dst.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dst.ctxName, err)
+	}
+
+	var changes []secretChange
+	seen := make(map[string]bool)
+	for name, value := range srcValues {
+		key := strings.TrimPrefix(name, src.prefix)
+		c := secretChange{Key: key, SrcName: name, DstName: secretDestName(dst, key), SrcValue: value}
+		seen[c.DstName] = true
+
+		dstValue, ok := dstValues[c.DstName]
+		switch {
+		case !ok:
+			c.Kind = secretAdded
+		case dstValue != value:
+			c.Kind, c.DstValue = secretChanged, dstValue
+		default:
+			c.Kind, c.DstValue = secretUnchanged, dstValue
+		}
+		changes = append(changes, c)
+	}
+	for name, value := range dstValues {
+		if !seen[name] {
+			changes = append(changes, secretChange{
+				Key:      strings.TrimPrefix(name, dst.prefix),
+				Kind:     secretRemoved,
+				DstName:  name,
+				DstValue: value,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// fetchSecretValues returns name → value for every secret under prefix.
+// When prefix has an ssm:// or sm:// scheme, the names carry it too.
+func fetchSecretValues(ctx context.Context, sp provider.SecretsProvider, prefix string) (map[string]string, error) {
+	secrets, err := sp.List(ctx, &provider.SecretFilter{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	_, bare := aws.ParseSecretName(prefix)
+	scheme := strings.TrimSuffix(prefix, bare)
+
+	values := make(map[string]string, len(secrets))
+	for _, s := range secrets {
+		// Secrets Manager's name filter is not strictly a prefix match
+		if !strings.HasPrefix(s.Name, bare) {
+			continue
+		}
+		sv, err := sp.Get(ctx, secretRef(s))
+		if err != nil {
+			return nil, err
+		}
+		values[scheme+s.Name] = sv.Value
+	}
+	return values, nil
+}
+
+// secretRef returns the name to read a listed secret by, with an ssm:// or
+// sm:// scheme on AWS so the read goes to the backend it was listed from
+func secretRef(s types.Secret) string {
+	if s.Provider == "aws" && s.Backend != "" {
+		return s.Backend + "://" + s.Name
+	}
+	return s.Name
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+)
+
+var secretsEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edit a secret in $EDITOR",
+	Long: `Edit a secret's value in $VISUAL or $EDITOR (default vi).
+
+The value is written to a temporary file readable only by you (mode 0600),
+which is removed as soon as the editor exits. The changes are shown as a diff
+and written back after confirmation. JSON values get a .json file for syntax
+highlighting, and a warning is shown if the edited value no longer parses.
+
+Examples:
+  cml secrets edit app/db-config
+  EDITOR="code --wait" cml secrets edit /app/feature-flags`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsEdit,
+}
+
+var secretsEditYes bool
+
+func init() {
+	secretsCmd.AddCommand(secretsEditCmd)
+
+	secretsEditCmd.Flags().BoolVarP(&secretsEditYes, "yes", "y", false, "Skip confirmation prompt")
+}
+
+func runSecretsEdit(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	name := args[0]
+
+	secretsProvider, err := getSecretsProvider(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := secretsProvider.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	edited, err := editInEditor(current.Value)
+	if err != nil {
+		return err
+	}
+	if edited == current.Value {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	fmt.Printf("%s\n\n", ui.NameStyle.Render(name))
+	for _, line := range lineDiff(current.Value, edited) {
+		switch line[0] {
+		case '+':
+			fmt.Println(ui.RunningStyle.Render(line))
+		case '-':
+			fmt.Println(ui.StoppedStyle.Render(line))
+		default:
+			fmt.Println(ui.MutedStyle.Render(line))
+		}
+	}
+	if json.Valid([]byte(current.Value)) && !json.Valid([]byte(edited)) {
+		fmt.Printf("\n%s the value was JSON but no longer parses\n", ui.PendingStyle.Render("!"))
+	}
+
+	if !secretsEditYes && !confirm(fmt.Sprintf("\nSave %s? [y/N]: ", name)) {
+		fmt.Println("Edit cancelled")
+		return nil
+	}
+
+	if err := secretsProvider.Set(ctx, name, edited); err != nil {
+		return err
+	}
+	fmt.Printf("Secret set: %s\n", name)
+	return nil
+}
+
+// editInEditor opens value in the user's editor via a 0600 temp file and
+// returns the result. Editors add a final newline; it is dropped again when
+// the original value had none.
+func editInEditor(value string) (string, error) {
+	// A blank $VISUAL or $EDITOR counts as unset
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	pattern := "cml-secret-*"
+	if json.Valid([]byte(value)) {
+		pattern += ".json"
+	}
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := f.Chmod(0600); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if _, err := f.WriteString(value); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	c := exec.Command(parts[0], append(parts[1:], f.Name())...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("editor %s: %w", parts[0], err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	edited := string(data)
+	if !strings.HasSuffix(value, "\n") {
+		edited = strings.TrimSuffix(strings.TrimSuffix(edited, "\n"), "\r")
+	}
+	return edited, nil
+}
+
+// lineDiff returns the lines of a and b prefixed with "- ", "+ " or "  ",
+// from a longest common subsequence of lines.
+func lineDiff(a, b string) []string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] is the LCS length of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, "  "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		out = append(out, "+ "+y[j])
+	}
+	return out
+}
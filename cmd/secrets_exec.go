@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var secretsExecCmd = &cobra.Command{
+	Use:   "exec [--prefix <prefix>] [--map KEY=name] -- <command> [args...]",
+	Short: "Run a command with secrets in its environment",
+	Long: `Run a command with secrets injected as environment variables.
+
+Secrets under each --prefix become env-style keys relative to the prefix
+(/app/prod/db/password with --prefix /app/prod/ is DB_PASSWORD, as in
+'cml secrets export'). --map sets a variable from a single secret and wins
+over prefixes. Secrets override variables already in the environment.
+
+Values are read through the current context (or --context) and passed to the
+child in memory only: nothing is written to disk, and nothing appears on the
+command line. The command's exit code is returned.
+
+Examples:
+  cml secrets exec --prefix /app/prod/ -- ./server
+  cml secrets exec --map DB_PASS=/app/db/pass -- psql -h db -U app
+  cml secrets exec -c aws:staging --prefix /app/staging/ --prefix /shared/ -- make migrate`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSecretsExec,
+}
+
+var (
+	secretsExecPrefixes []string
+	secretsExecMaps     []string
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsExecCmd)
+
+	secretsExecCmd.Flags().StringArrayVar(&secretsExecPrefixes, "prefix", nil, "Inject all secrets under prefix (repeatable)")
+	secretsExecCmd.Flags().StringArrayVar(&secretsExecMaps, "map", nil, "Inject one secret as KEY=name (repeatable)")
+}
+
+func runSecretsExec(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if len(secretsExecPrefixes) == 0 && len(secretsExecMaps) == 0 {
+		return fmt.Errorf("nothing to inject; use --prefix or --map")
+	}
+	mapped := make(map[string]string, len(secretsExecMaps))
+	for _, m := range secretsExecMaps {
+		key, name, ok := strings.Cut(m, "=")
+		if !ok || key == "" || name == "" {
+			return fmt.Errorf("invalid --map %q (want KEY=name)", m)
+		}
+		mapped[key] = name
+	}
+
+	secretsProvider, err := getSecretsProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	env := make(map[string]string)
+	source := make(map[string]string) // env key → secret name, for collisions
+	for _, prefix := range secretsExecPrefixes {
+		values, err := fetchSecretValues(ctx, secretsProvider, prefix)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("no secrets found under %s", prefix)
+		}
+		for name, value := range values {
+			key := envKey(strings.TrimPrefix(name, prefix))
+			if key == "" {
+				continue
+			}
+			if other, ok := source[key]; ok {
+				return fmt.Errorf("%s and %s both map to %s; use --map to pick one", other, name, key)
+			}
+			env[key], source[key] = value, name
+		}
+	}
+	for key, name := range mapped {
+		sv, err := secretsProvider.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		env[key] = sv.Value
+	}
+
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	childEnv := os.Environ()
+	for _, k := range keys {
+		childEnv = append(childEnv, k+"="+env[k])
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	child := exec.Command(path, args[1:]...)
+	child.Env = childEnv
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	// Forward signals so the child can shut down cleanly. Signals are caught
+	// before the child starts and held until it has a process to receive them.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	go func() {
+		for sig := range sigCh {
+			_ = child.Process.Signal(sig)
+		}
+	}()
+
+	if err := child.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code := exitErr.ExitCode()
+			if code < 0 {
+				code = 1 // killed by a signal
+			}
+			os.Exit(code)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
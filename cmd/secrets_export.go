@@ -0,0 +1,618 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+)
+
+var secretsExportCmd = &cobra.Command{
+	Use:   "export <prefix>",
+	Short: "Export secrets under a prefix to a file",
+	Long: `Export the secrets under prefix as dotenv, JSON, YAML or a Kubernetes Secret.
+
+Names are taken relative to the prefix. For dotenv and k8s-secret they become
+env-style keys: '/', '-' and '.' turn into '_' and letters are upper-cased, so
+/app/prod/db/password exported with prefix /app/prod/ is DB_PASSWORD. JSON
+and YAML keep the hierarchy as nested objects:
+
+  {"db": {"password": "..."}}
+
+Output goes to stdout, or to --output, which is created with mode 0600.
+
+Examples:
+  cml secrets export /app/prod/ > prod.env
+  cml secrets export /app/prod/ --format json -o prod.json
+  cml secrets export /app/prod/ --format k8s-secret --name app-env --namespace prod | kubectl apply -f -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsExport,
+}
+
+var secretsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import secrets from a file under a prefix",
+	Long: `Create secrets under --prefix from a dotenv, JSON, YAML or Kubernetes Secret file.
+
+dotenv and k8s-secret keys are used as-is (DB_PASSWORD → <prefix>DB_PASSWORD).
+Nested JSON and YAML objects become path segments ({"db": {"password": ...}} →
+<prefix>db/password); on GCP, where names cannot contain '/', segments are
+joined with '_'. The format is taken from the file extension (.env, .json,
+.yaml, .yml) unless --format is given; a YAML file of kind Secret is read as
+k8s-secret. Use - to read stdin, which requires --yes or --dry-run.
+
+Secrets that already exist with a different value are conflicts, handled by
+--on-conflict:
+  fail       list the conflicts and write nothing (default)
+  skip       leave existing secrets unchanged
+  overwrite  replace existing values
+
+Examples:
+  cml secrets import staging.env --prefix /app/staging/ --dry-run
+  cml secrets import config.json --prefix /app/staging/ --on-conflict skip
+  kubectl get secret app-env -o yaml | cml secrets import - --prefix /app/prod/ --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsImport,
+}
+
+var (
+	secretsExportFormat    string
+	secretsExportOutput    string
+	secretsExportName      string
+	secretsExportNamespace string
+
+	secretsImportPrefix     string
+	secretsImportFormat     string
+	secretsImportOnConflict string
+	secretsImportDryRun     bool
+	secretsImportYes        bool
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsExportCmd)
+	secretsCmd.AddCommand(secretsImportCmd)
+
+	secretsExportCmd.Flags().StringVarP(&secretsExportFormat, "format", "f", "dotenv", "Output format: dotenv, json, yaml, k8s-secret")
+	secretsExportCmd.Flags().StringVarP(&secretsExportOutput, "output", "o", "", "Write to file instead of stdout")
+	secretsExportCmd.Flags().StringVar(&secretsExportName, "name", "", "k8s-secret: Secret name (default: last prefix segment)")
+	secretsExportCmd.Flags().StringVar(&secretsExportNamespace, "namespace", "", "k8s-secret: Secret namespace")
+
+	secretsImportCmd.Flags().StringVar(&secretsImportPrefix, "prefix", "", "Prefix to create the secrets under")
+	secretsImportCmd.Flags().StringVarP(&secretsImportFormat, "format", "f", "", "Input format: dotenv, json, yaml, k8s-secret (default: from extension)")
+	secretsImportCmd.Flags().StringVar(&secretsImportOnConflict, "on-conflict", "fail", "On existing secrets with a different value: fail, skip, overwrite")
+	secretsImportCmd.Flags().BoolVar(&secretsImportDryRun, "dry-run", false, "Show what would be written without writing")
+	secretsImportCmd.Flags().BoolVarP(&secretsImportYes, "yes", "y", false, "Skip confirmation prompt")
+	_ = secretsImportCmd.MarkFlagRequired("prefix")
+}
+
+// k8sSecret is the subset of a Kubernetes Secret manifest cml reads and writes
+type k8sSecret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sSecretMeta     `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+type k8sSecretMeta struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+func runSecretsExport(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	prefix := args[0]
+
+	switch secretsExportFormat {
+	case "dotenv", "json", "yaml", "k8s-secret":
+	default:
+		return fmt.Errorf("invalid format %q (use dotenv, json, yaml or k8s-secret)", secretsExportFormat)
+	}
+
+	secretsProvider, err := getSecretsProvider(ctx)
+	if err != nil {
+		return err
+	}
+	values, err := fetchSecretValues(ctx, secretsProvider, prefix)
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("no secrets found under %s", prefix)
+	}
+
+	rel := make(map[string]string, len(values))
+	for name, value := range values {
+		rel[strings.TrimPrefix(name, prefix)] = value
+	}
+
+	var out []byte
+	switch secretsExportFormat {
+	case "dotenv":
+		env, err := envKeys(rel)
+		if err != nil {
+			return err
+		}
+		out = formatDotenv(env)
+	case "json", "yaml":
+		tree, err := nestSecrets(rel)
+		if err != nil {
+			return err
+		}
+		if secretsExportFormat == "json" {
+			out, err = json.MarshalIndent(tree, "", "  ")
+			out = append(out, '\n')
+		} else {
+			out, err = yaml.Marshal(tree)
+		}
+		if err != nil {
+			return err
+		}
+	case "k8s-secret":
+		env, err := envKeys(rel)
+		if err != nil {
+			return err
+		}
+		name := secretsExportName
+		if name == "" {
+			name = k8sSecretName(prefix)
+		}
+		manifest := k8sSecret{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   k8sSecretMeta{Name: name, Namespace: secretsExportNamespace},
+			Type:       "Opaque",
+			Data:       make(map[string]string, len(env)),
+		}
+		for k, v := range env {
+			manifest.Data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		if out, err = yaml.Marshal(manifest); err != nil {
+			return err
+		}
+	}
+
+	if secretsExportOutput == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := writePrivateFile(secretsExportOutput, out); err != nil {
+		return fmt.Errorf("failed to write %s: %w", secretsExportOutput, err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d secret(s) to %s\n", len(values), secretsExportOutput)
+	return nil
+}
+
+// writePrivateFile writes data to path with mode 0600. An existing file is
+// narrowed to 0600 before the data goes in; os.WriteFile would keep its mode.
+func writePrivateFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0600); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runSecretsImport(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	switch secretsImportOnConflict {
+	case "fail", "skip", "overwrite":
+	default:
+		return fmt.Errorf("invalid --on-conflict %q (use fail, skip or overwrite)", secretsImportOnConflict)
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		// stdin is consumed by the file, so the confirmation prompt can't read it
+		if !secretsImportYes && !secretsImportDryRun {
+			return fmt.Errorf("reading from stdin requires --yes (or --dry-run)")
+		}
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", args[0], err)
+	}
+
+	format := secretsImportFormat
+	if format == "" {
+		format = detectSecretsFormat(args[0], data)
+	}
+	entries, err := parseSecretsFile(format, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s as %s: %w", args[0], format, err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no secrets found in %s", args[0])
+	}
+
+	ctxConfig, ctxName, err := resolveSecretsContext(secretsContextFlag)
+	if err != nil {
+		return err
+	}
+	secretsProvider, err := newSecretsProvider(ctx, ctxConfig, ctxName)
+	if err != nil {
+		return err
+	}
+	existing, err := fetchSecretValues(ctx, secretsProvider, secretsImportPrefix)
+	if err != nil {
+		return err
+	}
+
+	dst := &secretsSide{ctxName: ctxName, provider: ctxConfig.Provider, prefix: secretsImportPrefix}
+	var plan, conflicts []secretChange
+	unchanged := 0
+	for key, value := range entries {
+		c := secretChange{Key: key, DstName: secretDestName(dst, key), SrcValue: value}
+		current, ok := existing[c.DstName]
+		switch {
+		case !ok:
+			c.Kind = secretAdded
+			plan = append(plan, c)
+		case current == value:
+			unchanged++
+		default:
+			c.Kind, c.DstValue = secretChanged, current
+			conflicts = append(conflicts, c)
+			if secretsImportOnConflict == "overwrite" {
+				plan = append(plan, c)
+			}
+		}
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].DstName < plan[j].DstName })
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].DstName < conflicts[j].DstName })
+
+	if len(conflicts) > 0 && secretsImportOnConflict == "fail" {
+		fmt.Printf("%d secret(s) already exist in %s with a different value:\n\n", len(conflicts), ctxName)
+		for _, c := range conflicts {
+			fmt.Printf("  %s %s\n", ui.PendingStyle.Render("~"), c.DstName)
+		}
+		fmt.Println()
+		return fmt.Errorf("conflicting secrets; use --on-conflict skip or overwrite")
+	}
+
+	if len(plan) == 0 {
+		fmt.Printf("Nothing to import (%d unchanged, %d skipped)\n", unchanged, len(conflicts))
+		return nil
+	}
+
+	fmt.Printf("Import into %s:\n\n", ui.NameStyle.Render(ctxName))
+	for _, c := range plan {
+		mark, verb := ui.RunningStyle.Render("+"), "create"
+		if c.Kind == secretChanged {
+			mark, verb = ui.PendingStyle.Render("~"), "overwrite"
+		}
+		fmt.Printf("  %s %s %s\n", mark, c.DstName, ui.MutedStyle.Render("("+verb+")"))
+	}
+	if secretsImportOnConflict == "skip" && len(conflicts) > 0 {
+		fmt.Printf("\n%s\n", ui.MutedStyle.Render(fmt.Sprintf("Skipping %d existing secret(s) with a different value", len(conflicts))))
+	}
+
+	if secretsImportDryRun {
+		fmt.Printf("\nDry run: %d secret(s) would be written\n", len(plan))
+		return nil
+	}
+
+	if !secretsImportYes && !confirm(fmt.Sprintf("\nWrite %d secret(s)? [y/N]: ", len(plan))) {
+		fmt.Println("Import cancelled")
+		return nil
+	}
+
+	failed := 0
+	for _, c := range plan {
+		if err := secretsProvider.Set(ctx, c.DstName, c.SrcValue); err != nil {
+			fmt.Printf("%s %s: %v\n", ui.StoppedStyle.Render("✗"), c.DstName, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s\n", ui.RunningStyle.Render("✓"), c.DstName)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d secret(s) failed to import", failed, len(plan))
+	}
+	return nil
+}
+
+var envKeyInvalid = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// envKey turns a relative secret name into an env-style key
+// (db/password → DB_PASSWORD)
+func envKey(name string) string {
+	key := strings.ToUpper(envKeyInvalid.ReplaceAllString(strings.Trim(name, "/"), "_"))
+	if key != "" && key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
+
+// envKeys maps relative names to env-style keys, failing when two names
+// collapse to the same key
+func envKeys(rel map[string]string) (map[string]string, error) {
+	env := make(map[string]string, len(rel))
+	from := make(map[string]string, len(rel))
+	for name, value := range rel {
+		key := envKey(name)
+		if key == "" {
+			return nil, fmt.Errorf("secret %q has no name relative to the prefix; export a parent prefix", name)
+		}
+		if other, ok := from[key]; ok {
+			return nil, fmt.Errorf("%q and %q both map to %s", other, name, key)
+		}
+		env[key], from[key] = value, name
+	}
+	return env, nil
+}
+
+// formatDotenv renders env as sorted KEY=value lines, quoting values that
+// need it
+func formatDotenv(env map[string]string) []byte {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s=%s\n", k, quoteDotenv(env[k]))
+	}
+	return buf.Bytes()
+}
+
+func quoteDotenv(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\r\"'\\#$`=") {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`, "`", "\\`")
+	return `"` + r.Replace(v) + `"`
+}
+
+// parseDotenv reads KEY=value lines. Blank lines, # comments and a leading
+// "export " are ignored; single-quoted values are literal and double-quoted
+// values support \n, \r, \t, \\, \" and \$ escapes.
+func parseDotenv(data []byte) (map[string]string, error) {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, `'`):
+			end := strings.LastIndex(value, `'`)
+			if end == 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			value = value[1:end]
+		case strings.HasPrefix(value, `"`):
+			var sb strings.Builder
+			closed := false
+			for i := 1; i < len(value); i++ {
+				c := value[i]
+				if c == '"' {
+					closed = true
+					break
+				}
+				if c == '\\' && i+1 < len(value) {
+					i++
+					switch value[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 'r':
+						sb.WriteByte('\r')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(value[i])
+					}
+					continue
+				}
+				sb.WriteByte(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			value = sb.String()
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// nestSecrets turns relative names into nested objects by path segment
+func nestSecrets(rel map[string]string) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	names := make([]string, 0, len(rel))
+	for name := range rel {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		parts := strings.Split(strings.Trim(name, "/"), "/")
+		node := tree
+		for i, part := range parts {
+			if i == len(parts)-1 {
+				if _, ok := node[part]; ok {
+					return nil, fmt.Errorf("%q is both a secret and a prefix of other secrets; use --format dotenv", name)
+				}
+				node[part] = rel[name]
+				break
+			}
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				if _, isLeaf := node[part]; isLeaf {
+					return nil, fmt.Errorf("%q is both a secret and a prefix of other secrets; use --format dotenv", path.Join(parts[:i+1]...))
+				}
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return tree, nil
+}
+
+// flattenSecrets is the inverse of nestSecrets. Non-string leaves are
+// stored as their JSON encoding.
+func flattenSecrets(prefix string, v interface{}, out map[string]string) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if err := flattenSecrets(path.Join(prefix, k), child, out); err != nil {
+				return err
+			}
+		}
+	case string:
+		out[prefix] = val
+	case nil:
+		out[prefix] = ""
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		out[prefix] = string(b)
+	}
+	return nil
+}
+
+// detectSecretsFormat picks an import format from the file name, falling
+// back to the content for stdin
+func detectSecretsFormat(name string, data []byte) string {
+	isK8s := func() bool {
+		var probe struct {
+			Kind string `yaml:"kind"`
+		}
+		return yaml.Unmarshal(data, &probe) == nil && probe.Kind == "Secret"
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		if isK8s() {
+			return "k8s-secret"
+		}
+		return "yaml"
+	case ".env":
+		return "dotenv"
+	}
+	if strings.HasPrefix(filepath.Base(name), ".env") {
+		return "dotenv"
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return "json"
+	case isK8s():
+		return "k8s-secret"
+	}
+	return "dotenv"
+}
+
+// parseSecretsFile returns relative name → value
+func parseSecretsFile(format string, data []byte) (map[string]string, error) {
+	switch format {
+	case "dotenv":
+		return parseDotenv(data)
+
+	case "json", "yaml":
+		var tree map[string]interface{}
+		var err error
+		if format == "json" {
+			err = json.Unmarshal(data, &tree)
+		} else {
+			err = yaml.Unmarshal(data, &tree)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]string)
+		if err := flattenSecrets("", tree, out); err != nil {
+			return nil, err
+		}
+		return out, nil
+
+	case "k8s-secret":
+		var manifest k8sSecret
+		if err := yaml.Unmarshal(data, &manifest); err != nil {
+			return nil, err
+		}
+		if manifest.Kind != "Secret" {
+			return nil, fmt.Errorf("kind is %q, not Secret", manifest.Kind)
+		}
+		out := make(map[string]string, len(manifest.Data)+len(manifest.StringData))
+		for k, v := range manifest.Data {
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("data.%s: %w", k, err)
+			}
+			out[k] = string(decoded)
+		}
+		for k, v := range manifest.StringData {
+			out[k] = v
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown format (use dotenv, json, yaml or k8s-secret)")
+}
+
+var k8sNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// k8sSecretName derives a DNS-1123 Secret name from the last prefix segment
+func k8sSecretName(prefix string) string {
+	segments := strings.FieldsFunc(prefix, func(r rune) bool { return r == '/' })
+	name := "secrets"
+	if len(segments) > 0 {
+		name = segments[len(segments)-1]
+	}
+	name = strings.Trim(k8sNameInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		return "secrets"
+	}
+	return name
+}
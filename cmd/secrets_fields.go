@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonField is one top-level field of a JSON object secret
+type jsonField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// parseJSONFields decodes a JSON object, keeping its fields in order
+func parseJSONFields(value string) ([]jsonField, error) {
+	dec := json.NewDecoder(strings.NewReader(value))
+	tok, err := dec.Token()
+	if err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("value is not a JSON object")
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("value is not a JSON object: %w", err)
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("value is not a JSON object: %w", err)
+		}
+		fields = append(fields, jsonField{Key: tok.(string), Value: raw})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("value is not a JSON object: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("value is not a JSON object: trailing data")
+	}
+	return fields, nil
+}
+
+// secretField returns one field of a JSON object secret: strings unquoted,
+// anything else as JSON
+func secretField(value, key string) (string, error) {
+	fields, err := parseJSONFields(value)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range fields {
+		if f.Key != key {
+			continue
+		}
+		var s string
+		if json.Unmarshal(f.Value, &s) == nil {
+			return s, nil
+		}
+		return string(f.Value), nil
+	}
+	return "", fmt.Errorf("key %q not found", key)
+}
+
+// setSecretField sets one field of a JSON object secret, keeping the other
+// fields, their order and the indentation. The new value is stored as a
+// JSON string, unless the field currently holds a non-string and the new
+// value is valid JSON, so numbers and booleans keep their type.
+func setSecretField(value, key, fieldValue string) (string, error) {
+	fields, err := parseJSONFields(value)
+	if err != nil {
+		return "", err
+	}
+
+	raw, _ := json.Marshal(fieldValue)
+	found := false
+	for i, f := range fields {
+		if f.Key != key {
+			continue
+		}
+		if len(f.Value) > 0 && f.Value[0] != '"' && json.Valid([]byte(fieldValue)) {
+			raw = []byte(fieldValue)
+		}
+		fields[i].Value = raw
+		found = true
+	}
+	if !found {
+		fields = append(fields, jsonField{Key: key, Value: raw})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(f.Key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(f.Value)
+	}
+	buf.WriteByte('}')
+
+	var out bytes.Buffer
+	if strings.Contains(strings.TrimSpace(value), "\n") {
+		err = json.Indent(&out, buf.Bytes(), "", jsonIndent(value))
+	} else {
+		err = json.Compact(&out, buf.Bytes())
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(value, "\n") {
+		out.WriteByte('\n')
+	}
+	return out.String(), nil
+}
+
+// jsonIndent returns the indentation of an indented JSON value: the leading
+// whitespace of the first key line, or two spaces when there is none
+func jsonIndent(value string) string {
+	lines := strings.Split(strings.TrimSpace(value), "\n")
+	for _, line := range lines[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" {
+			if indent := line[:len(line)-len(trimmed)]; indent != "" {
+				return indent
+			}
+			break
+		}
+	}
+	return "  "
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var secretsHistoryCmd = &cobra.Command{
+	Use:   "history <name>",
+	Short: "Show a secret's version history",
+	Long: `Show the versions of a secret, newest first, without their values.
+
+  SSM              version numbers, labels and who wrote each version
+  Secrets Manager  version IDs and stages (AWSCURRENT, AWSPREVIOUS, ...)
+  GCP              version numbers and state (enabled, disabled, destroyed)
+
+The current version, the one 'cml secrets get' returns, is marked with *.
+
+Examples:
+  cml secrets history /app/db-password
+  cml secrets history app/db -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsHistory,
+}
+
+var secretsRollbackCmd = &cobra.Command{
+	Use:   "rollback <name> --to <version>",
+	Short: "Make an earlier version of a secret current again",
+	Long: `Make an earlier version of a secret current again.
+
+--to is a version from 'cml secrets history': an SSM version number or label,
+a Secrets Manager version ID or stage (e.g. AWSPREVIOUS), or a GCP version
+number. Secrets Manager moves the AWSCURRENT stage to that version; SSM and
+GCP write its value as a new version, so the history is kept.
+
+Examples:
+  cml secrets rollback app/db --to AWSPREVIOUS
+  cml secrets rollback /app/db-password --to 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsRollback,
+}
+
+var secretsRotateCmd = &cobra.Command{
+	Use:   "rotate <name>",
+	Short: "Rotate a Secrets Manager secret now",
+	Long: `Start an immediate rotation of an AWS Secrets Manager secret using its
+configured rotation function, and report the rotation status.
+
+Rotation runs asynchronously: the new version is staged as AWSPENDING until
+the function finishes. Use --status to only show the status.
+
+Examples:
+  cml secrets rotate app/db
+  cml secrets rotate app/db --status`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretsRotate,
+}
+
+var (
+	secretsHistoryOutput string
+	secretsRollbackTo    string
+	secretsRollbackYes   bool
+	secretsRotateStatus  bool
+)
+
+func init() {
+	secretsCmd.AddCommand(secretsHistoryCmd)
+	secretsCmd.AddCommand(secretsRollbackCmd)
+	secretsCmd.AddCommand(secretsRotateCmd)
+
+	secretsHistoryCmd.Flags().StringVarP(&secretsHistoryOutput, "output", "o", "table", "Output format: table, json")
+
+	secretsRollbackCmd.Flags().StringVar(&secretsRollbackTo, "to", "", "Version, label or stage to roll back to")
+	secretsRollbackCmd.Flags().BoolVarP(&secretsRollbackYes, "yes", "y", false, "Skip confirmation prompt")
+	_ = secretsRollbackCmd.MarkFlagRequired("to")
+
+	secretsRotateCmd.Flags().BoolVar(&secretsRotateStatus, "status", false, "Only show the rotation status")
+}
+
+func runSecretsHistory(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if secretsHistoryOutput != "table" && secretsHistoryOutput != "json" {
+		return fmt.Errorf("invalid output format %q (use table or json)", secretsHistoryOutput)
+	}
+
+	secretsProvider, err := getSecretsProvider(ctx)
+	if err != nil {
+		return err
+	}
+	versioner, ok := secretsProvider.(provider.SecretVersioner)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	versions, err := versioner.History(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if secretsHistoryOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(versions)
+	}
+
+	if len(versions) == 0 {
+		fmt.Println("No versions found")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(versions))
+	for _, v := range versions {
+		mark := " "
+		if v.Current {
+			mark = "*"
+		}
+		created := ""
+		if !v.CreatedAt.IsZero() {
+			created = v.CreatedAt.Local().Format("2006-01-02 15:04")
+		}
+		detail := v.State
+		if v.CreatedBy != "" {
+			detail = v.CreatedBy
+		}
+		rows = append(rows, []string{mark + " " + v.Version, created, strings.Join(v.Labels, ","), detail})
+	}
+	renderSimpleTable([]string{"Version", "Created", "Labels/Stages", "By/State"}, []int{38, 16, 28, 40}, rows)
+	fmt.Printf("  %d versions (* current)\n", len(versions))
+	return nil
+}
+
+func runSecretsRollback(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	name := args[0]
+
+	secretsProvider, err := getSecretsProvider(ctx)
+	if err != nil {
+		return err
+	}
+	versioner, ok := secretsProvider.(provider.SecretVersioner)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	// Resolve both ends first so typos fail before the prompt
+	current, err := secretsProvider.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	target, err := versioner.GetVersion(ctx, name, secretsRollbackTo)
+	if err != nil {
+		return err
+	}
+	if target.Version == current.Version {
+		return fmt.Errorf("version %s is already current", secretsRollbackTo)
+	}
+	if target.Value == current.Value {
+		fmt.Printf("%s version %s has the same value as the current version\n", ui.PendingStyle.Render("!"), secretsRollbackTo)
+	}
+
+	if !secretsRollbackYes && !confirm(fmt.Sprintf("Roll back %s from version %s to %s? [y/N]: ",
+		ui.NameStyle.Render(name), current.Version, target.Version)) {
+		fmt.Println("Rollback cancelled")
+		return nil
+	}
+
+	if err := versioner.Rollback(ctx, name, secretsRollbackTo); err != nil {
+		return err
+	}
+	fmt.Printf("%s %s rolled back to version %s\n", ui.RunningStyle.Render("✓"), name, target.Version)
+	return nil
+}
+
+func runSecretsRotate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	name := args[0]
+
+	secretsProvider, err := getSecretsProvider(ctx)
+	if err != nil {
+		return err
+	}
+	rotator, ok := secretsProvider.(provider.SecretRotator)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	var status *types.SecretRotation
+	if secretsRotateStatus {
+		status, err = rotator.RotationStatus(ctx, name)
+	} else {
+		status, err = rotator.Rotate(ctx, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !secretsRotateStatus {
+		fmt.Printf("%s Rotation started for %s\n\n", ui.RunningStyle.Render("✓"), ui.NameStyle.Render(name))
+	}
+	printSecretRotation(status)
+	return nil
+}
+
+func printSecretRotation(s *types.SecretRotation) {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ui.MutedStyle.Render("-")
+		}
+		return t.Local().Format("2006-01-02 15:04")
+	}
+
+	enabled := ui.StoppedStyle.Render("disabled")
+	if s.Enabled {
+		enabled = ui.RunningStyle.Render("enabled")
+	}
+	fmt.Printf("  Rotation:      %s\n", enabled)
+	if s.LambdaARN != "" {
+		fmt.Printf("  Function:      %s\n", ui.MutedStyle.Render(s.LambdaARN))
+	}
+	fmt.Printf("  Last rotated:  %s\n", formatTime(s.LastRotated))
+	fmt.Printf("  Next rotation: %s\n", formatTime(s.NextRotation))
+	if s.PendingVersion != "" {
+		fmt.Printf("  Pending:       %s %s\n", s.PendingVersion, ui.PendingStyle.Render("(AWSPENDING, rotation in progress)"))
+	}
+}
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/config"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var sshConfigCmd = &cobra.Command{
+	Use:   "ssh-config",
+	Short: "Generate an OpenSSH config for the VMs in a context",
+	Long: `Generate OpenSSH Host blocks for every running VM in one or more contexts,
+so plain ssh, scp, rsync, VS Code Remote and Ansible can reach private VMs.
+
+Hosts are named after the VM's Name tag (GCP: instance name). VMs sharing a
+name get the instance ID appended. With several contexts, each alias is
+prefixed with the context name (':' replaced by '-').
+
+The hop is handled by 'cml vm proxy' as the ProxyCommand:
+  AWS  an SSM port-forwarding session to port 22 (no bastion or public IP)
+  GCP  an IAP tunnel to port 22, or ProxyJump through the context bastion
+       (itself reached over IAP) when one is configured. GCP hosts use
+       gcloud's key and known_hosts files, so run 'gcloud compute ssh' once
+       first or pass --identity-file.
+
+Only running VMs are included; rerun to pick up fleet changes. With
+--install the config is written to ~/.ssh/cml_config and an Include line is
+added to ~/.ssh/config once.
+
+Examples:
+  cml ssh-config                          # print for the current context
+  cml ssh-config -c aws:prod,gcp:prod --install
+  cml ssh-config -o ~/.ssh/cml_prod --user ec2-user
+  ssh web-01 && rsync -a ./dist/ web-01:/srv/app/`,
+	Args: cobra.NoArgs,
+	RunE: runSSHConfig,
+}
+
+var vmProxyCmd = &cobra.Command{
+	Use:    "proxy <name-or-id> <port>",
+	Short:  "Connect stdin/stdout to a port on a VM (ssh ProxyCommand)",
+	Hidden: true,
+	Args:   cobra.ExactArgs(2),
+	RunE:   runVMProxy,
+}
+
+var (
+	sshConfigContexts []string
+	sshConfigOutput   string
+	sshConfigInstall  bool
+	sshConfigUser     string
+	sshConfigIdentity string
+)
+
+// sshConfigIncludeName is the file written by --install, relative to ~/.ssh.
+const sshConfigIncludeName = "cml_config"
+
+func init() {
+	rootCmd.AddCommand(sshConfigCmd)
+	vmCmd.AddCommand(vmProxyCmd)
+
+	sshConfigCmd.Flags().StringSliceVarP(&sshConfigContexts, "context", "c", nil, "Contexts to include (default: current context)")
+	sshConfigCmd.Flags().StringVarP(&sshConfigOutput, "output", "o", "", "Write to file instead of stdout")
+	sshConfigCmd.Flags().BoolVar(&sshConfigInstall, "install", false, "Write ~/.ssh/cml_config and Include it from ~/.ssh/config")
+	sshConfigCmd.Flags().StringVarP(&sshConfigUser, "user", "u", "", "SSH user for every host")
+	sshConfigCmd.Flags().StringVarP(&sshConfigIdentity, "identity-file", "i", "", "SSH identity file for every host")
+}
+
+func runSSHConfig(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	cfg, err := config.LoadCMLConfig()
+	if err != nil {
+		return err
+	}
+
+	names := sshConfigContexts
+	if len(names) == 0 {
+		if cfg.CurrentContext == "" {
+			return fmt.Errorf("no context set. Use 'cml use <context>' or --context")
+		}
+		names = []string{cfg.CurrentContext}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "cml"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by cml ssh-config. Regenerate instead of editing.\n")
+
+	hosts := 0
+	for _, name := range names {
+		ctxConfig := cfg.Contexts[name]
+		if ctxConfig == nil {
+			return fmt.Errorf("context %q not found", name)
+		}
+
+		vmProvider, err := newVMProvider(ctx, ctxConfig, name)
+		if err != nil {
+			return err
+		}
+		vms, err := vmProvider.List(ctx, &provider.VMFilter{State: "running"})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		prefix := ""
+		if len(names) > 1 {
+			prefix = sshAliasSlug(name) + "."
+		}
+		hosts += writeSSHHosts(&sb, exe, name, ctxConfig, prefix, vms)
+	}
+
+	out := sshConfigOutput
+	if out == "" && sshConfigInstall {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		out = filepath.Join(home, ".ssh", sshConfigIncludeName)
+	}
+
+	if out == "" || out == "-" {
+		fmt.Print(sb.String())
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, []byte(sb.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d hosts to %s\n", hosts, out)
+
+	if sshConfigInstall {
+		added, err := ensureSSHInclude(out)
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Fprintf(os.Stderr, "Added 'Include %s' to ~/.ssh/config\n", out)
+		}
+	}
+	return nil
+}
+
+// writeSSHHosts writes the Host blocks for one context and returns how many
+// VM hosts were written.
+func writeSSHHosts(sb *strings.Builder, exe, ctxName string, ctxConfig *config.Context, prefix string, vms []types.VM) int {
+	proxy := fmt.Sprintf("%s vm proxy --context %s %%h %%p", sshQuoteArg(exe), sshQuoteArg(ctxName))
+	isGCP := ctxConfig.Provider == "gcp"
+
+	fmt.Fprintf(sb, "\n# Context %s\n", ctxName)
+
+	common := func() {
+		if sshConfigUser != "" {
+			fmt.Fprintf(sb, "  User %s\n", sshConfigUser)
+		}
+		switch {
+		case sshConfigIdentity != "":
+			fmt.Fprintf(sb, "  IdentityFile %s\n", sshConfigIdentity)
+		case isGCP:
+			sb.WriteString("  IdentityFile ~/.ssh/google_compute_engine\n")
+		}
+		if isGCP {
+			sb.WriteString("  UserKnownHostsFile ~/.ssh/google_compute_known_hosts\n")
+		}
+	}
+
+	// GCP VMs behind a bastion are reached with ProxyJump via its private IP
+	jump := ""
+	if isGCP && ctxConfig.Bastion != "" {
+		jump = "cml-bastion-" + sshAliasSlug(ctxName)
+		fmt.Fprintf(sb, "Host %s\n", jump)
+		fmt.Fprintf(sb, "  HostName %s\n", ctxConfig.Bastion)
+		fmt.Fprintf(sb, "  ProxyCommand %s\n", proxy)
+		common()
+	}
+
+	counts := make(map[string]int)
+	for _, vm := range vms {
+		counts[vm.Name]++
+	}
+
+	for _, vm := range vms {
+		alias := vm.ID
+		if vm.Name != "" {
+			alias = vm.Name
+			if counts[vm.Name] > 1 {
+				alias += "-" + vm.ID
+			}
+		}
+
+		fmt.Fprintf(sb, "Host %s\n", prefix+sshAliasSlug(alias))
+		switch {
+		case !isGCP:
+			fmt.Fprintf(sb, "  HostName %s\n", vm.ID)
+			fmt.Fprintf(sb, "  ProxyCommand %s\n", proxy)
+		case jump != "":
+			fmt.Fprintf(sb, "  HostName %s\n", vm.PrivateIP)
+			fmt.Fprintf(sb, "  ProxyJump %s\n", jump)
+			fmt.Fprintf(sb, "  HostKeyAlias compute.%s\n", vm.ID)
+		default:
+			fmt.Fprintf(sb, "  HostName %s\n", vm.Name)
+			fmt.Fprintf(sb, "  ProxyCommand %s\n", proxy)
+			fmt.Fprintf(sb, "  HostKeyAlias compute.%s\n", vm.ID)
+		}
+		common()
+	}
+	return len(vms)
+}
+
+// sshAliasSlug makes s usable as a Host alias that scp and rsync won't
+// mistake for host:path.
+func sshAliasSlug(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', ' ', '\t', '/', '*', '?', '!':
+			return '-'
+		}
+		return r
+	}, s)
+}
+
+// sshQuoteArg double-quotes s for a ProxyCommand line when needed.
+func sshQuoteArg(s string) string {
+	if strings.ContainsAny(s, " \t\"'") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// ensureSSHInclude adds an Include line for path to the top of ~/.ssh/config
+// unless one is already there. Include must precede Host blocks to apply to
+// every host, so it is prepended.
+func ensureSSHInclude(path string) (bool, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, err
+	}
+	sshConfig := filepath.Join(home, ".ssh", "config")
+
+	data, err := os.ReadFile(sshConfig)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && strings.EqualFold(fields[0], "Include") {
+			for _, f := range fields[1:] {
+				if f == path || f == sshConfigIncludeName || f == "~/.ssh/"+sshConfigIncludeName {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	include := fmt.Sprintf("# Added by cml ssh-config\nInclude %s\n\n", path)
+	if err := os.WriteFile(sshConfig, append([]byte(include), data...), 0o600); err != nil {
+		return false, fmt.Errorf("failed to update %s: %w", sshConfig, err)
+	}
+	return true, nil
+}
+
+func runVMProxy(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	port, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid port: %s", args[1])
+	}
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	dialer, ok := vmProvider.(provider.VMDialer)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	conn, err := dialer.Dial(ctx, args[0], port)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	// ssh closing our stdin ends the session; the remote closing ends stdout
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		_ = conn.Close()
+	}()
+	_, _ = io.Copy(os.Stdout, conn)
+	return nil
+}
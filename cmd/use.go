@@ -52,6 +52,8 @@ Examples:
   cml use update gcp:prod --bastion bastion --bastion-project nexa-infra-np \
       --bastion-zone asia-southeast1-b --bastion-iap
   cml use update aws:prod --region us-west-2
+  cml use update aws:prod --transfer-bucket my-cml-transfers
+  cml use update aws:prod --secrets-backend sm --secrets-kms-key alias/app
   cml use update gcp:prod --bastion ""    # remove bastion`,
 	Args: cobra.ExactArgs(1),
 	RunE: runUseUpdate,
@@ -79,6 +81,9 @@ var (
 	useAddBastionProj string
 	useAddBastionZone string
 	useAddBastionIAP  bool
+	useAddTransferBkt string
+	useAddSecretsBE   string
+	useAddSecretsKMS  string
 
 	// Flags for use update
 	useUpdateProfile     string
@@ -89,6 +94,9 @@ var (
 	useUpdateBastionProj string
 	useUpdateBastionZone string
 	useUpdateBastionIAP  bool
+	useUpdateTransferBkt string
+	useUpdateSecretsBE   string
+	useUpdateSecretsKMS  string
 )
 
 func init() {
@@ -102,20 +110,26 @@ func init() {
 	useUpdateCmd.Flags().StringVar(&useUpdateProject, "project", "", "GCP project ID")
 	useUpdateCmd.Flags().StringVar(&useUpdateRegion, "region", "", "Region or zone")
 	useUpdateCmd.Flags().StringVar(&useUpdateBastion, "bastion", "", "Bastion (AWS: EC2 instance ID; GCP: VM name). Set to \"\" to remove")
-	useUpdateCmd.Flags().IntVar(&useUpdateBastionPort, "bastion-port", 0, "Remote port on bastion (AWS k8s connect; default 8888)")
+	useUpdateCmd.Flags().IntVar(&useUpdateBastionPort, "bastion-port", 0, "Remote port on bastion (k8s connect proxy; default 8888)")
 	useUpdateCmd.Flags().StringVar(&useUpdateBastionProj, "bastion-project", "", "GCP project hosting the bastion")
 	useUpdateCmd.Flags().StringVar(&useUpdateBastionZone, "bastion-zone", "", "Zone of the bastion instance")
 	useUpdateCmd.Flags().BoolVar(&useUpdateBastionIAP, "bastion-iap", false, "Use --tunnel-through-iap for bastion access")
+	useUpdateCmd.Flags().StringVar(&useUpdateTransferBkt, "transfer-bucket", "", "AWS: S3 bucket for staging vm cp transfers. Set to \"\" to remove")
+	useUpdateCmd.Flags().StringVar(&useUpdateSecretsBE, "secrets-backend", "", "AWS: backend for secret names without ssm:// or sm:// (ssm, sm). Set to \"\" to guess by leading /")
+	useUpdateCmd.Flags().StringVar(&useUpdateSecretsKMS, "secrets-kms-key", "", "AWS: KMS key for SSM SecureString writes. Set to \"\" for the AWS managed key")
 
 	// Flags for use add
 	useAddCmd.Flags().StringVar(&useAddProfile, "profile", "", "AWS profile name")
 	useAddCmd.Flags().StringVar(&useAddProject, "project", "", "GCP project ID")
 	useAddCmd.Flags().StringVar(&useAddRegion, "region", "", "Region or zone")
 	useAddCmd.Flags().StringVar(&useAddBastion, "bastion", "", "Bastion (AWS: EC2 instance ID; GCP: VM name)")
-	useAddCmd.Flags().IntVar(&useAddBastionPort, "bastion-port", 0, "Remote port on bastion (AWS k8s connect; default 8888)")
+	useAddCmd.Flags().IntVar(&useAddBastionPort, "bastion-port", 0, "Remote port on bastion (k8s connect proxy; default 8888)")
 	useAddCmd.Flags().StringVar(&useAddBastionProj, "bastion-project", "", "GCP project hosting the bastion (defaults to --project)")
 	useAddCmd.Flags().StringVar(&useAddBastionZone, "bastion-zone", "", "Zone of the bastion instance (defaults to --region)")
 	useAddCmd.Flags().BoolVar(&useAddBastionIAP, "bastion-iap", false, "Use --tunnel-through-iap for bastion access")
+	useAddCmd.Flags().StringVar(&useAddTransferBkt, "transfer-bucket", "", "AWS: S3 bucket for staging vm cp transfers")
+	useAddCmd.Flags().StringVar(&useAddSecretsBE, "secrets-backend", "", "AWS: backend for secret names without ssm:// or sm:// (ssm, sm)")
+	useAddCmd.Flags().StringVar(&useAddSecretsKMS, "secrets-kms-key", "", "AWS: KMS key for SSM SecureString writes")
 }
 
 func runUse(cmd *cobra.Command, args []string) error {
@@ -208,6 +222,12 @@ func runUseAdd(cmd *cobra.Command, args []string) error {
 			ctx.Bastion = useAddBastion
 			ctx.BastionPort = useAddBastionPort
 		}
+		ctx.TransferBucket = useAddTransferBkt
+		if err := validateSecretsBackend(useAddSecretsBE); err != nil {
+			return err
+		}
+		ctx.SecretsBackend = useAddSecretsBE
+		ctx.SecretsKMSKey = useAddSecretsKMS
 	case "gcp":
 		if useAddProject == "" {
 			return fmt.Errorf("--project is required for GCP contexts")
@@ -273,6 +293,18 @@ func runUseUpdate(cmd *cobra.Command, args []string) error {
 	if changed("bastion-iap") {
 		ctx.BastionIAP = useUpdateBastionIAP
 	}
+	if changed("transfer-bucket") {
+		ctx.TransferBucket = useUpdateTransferBkt
+	}
+	if changed("secrets-backend") {
+		if err := validateSecretsBackend(useUpdateSecretsBE); err != nil {
+			return err
+		}
+		ctx.SecretsBackend = useUpdateSecretsBE
+	}
+	if changed("secrets-kms-key") {
+		ctx.SecretsKMSKey = useUpdateSecretsKMS
+	}
 
 	if err := config.SaveCMLConfig(cfg); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
@@ -295,9 +327,28 @@ func runUseUpdate(cmd *cobra.Command, args []string) error {
 			fmt.Printf("  Bastion Project: %s\n", ctx.BastionProject)
 		}
 	}
+	if ctx.TransferBucket != "" {
+		fmt.Printf("  Transfer Bucket: %s\n", ctx.TransferBucket)
+	}
+	if ctx.SecretsBackend != "" {
+		fmt.Printf("  Secrets Backend: %s\n", ctx.SecretsBackend)
+	}
+	if ctx.SecretsKMSKey != "" {
+		fmt.Printf("  Secrets KMS Key: %s\n", ctx.SecretsKMSKey)
+	}
 	return nil
 }
 
+// validateSecretsBackend checks a secrets_backend value; empty means guess
+// by leading /
+func validateSecretsBackend(backend string) error {
+	switch backend {
+	case "", "ssm", "sm":
+		return nil
+	}
+	return fmt.Errorf("invalid secrets backend %q (use ssm or sm)", backend)
+}
+
 func runUseDelete(cmd *cobra.Command, args []string) error {
 	contextName := args[0]
 
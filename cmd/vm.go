@@ -3,7 +3,12 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/mattn/go-runewidth"
 	"github.com/spf13/cobra"
@@ -13,6 +18,7 @@ import (
 	gcpinternal "github.com/vietdv277/cumulus/internal/gcp"
 	"github.com/vietdv277/cumulus/internal/ui"
 	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/query"
 	"github.com/vietdv277/cumulus/pkg/types"
 )
 
@@ -30,8 +36,14 @@ Examples:
   cml vm get web-01              # Get VM details
   cml vm connect web-01          # SSH/SSM to VM
   cml vm tunnel web-01 3306      # Port forward
+  cml vm exec -t env=prod -- uptime  # Run a command on many VMs
   cml vm start web-01            # Start a VM
-  cml vm stop web-01             # Stop a VM`,
+  cml vm stop web-01             # Stop a VM
+  cml vm tag web-01 add team=payments  # Tag a VM
+  cml vm snapshot web-01         # AMI / machine image
+  cml vm reach web-01 db-01:5432 # Explain if traffic is allowed
+  cml vm create --template web --name web-07
+  cml vm terminate web-07        # Terminate a VM (asks first)`,
 }
 
 var vmListCmd = &cobra.Command{
@@ -45,7 +57,23 @@ Examples:
   cml vm list -s stopped         # List stopped VMs
   cml vm list -s all             # List all VMs
   cml vm list --name web         # Filter by name
-  cml vm list -t env=prod        # Filter by tag`,
+  cml vm list -t env=prod        # Filter by tag
+  cml vm list -w 'tags.team=payments && type=~"m5.*"'
+  cml vm list -s all -w 'private_ip in 10.1.0.0/16 && launched_at < 30d'
+
+Where expressions:
+  Fields     id, name, state, type, zone, provider, asg, private_ip,
+             public_ip, launched_at, age, tags.<key>
+  Compare    = != (exact)   =~ !~ (anchored regex)
+             in (a, b, c)   not in (...)   ip in 10.0.0.0/8
+             launched_at < > <= >= 2024-01-31 | RFC 3339 | 30d (= 30 days ago)
+             age < > <= >= 12h | 30d | 2w
+  Combine    && || !  (or: and, or, not)  and parentheses
+  Quote values containing spaces or operators: name = "web (blue)"
+
+Equality conditions joined by && are sent to the provider API where
+possible; the full expression is always applied to the results. A state
+condition in --where lifts the default running-only filter.`,
 	RunE: runVMList,
 }
 
@@ -76,17 +104,25 @@ Examples:
 }
 
 var vmTunnelCmd = &cobra.Command{
-	Use:   "tunnel <name-or-id> <remote-port> [local-port]",
+	Use:   "tunnel <name-or-id> [remote-host] <remote-port> [local-port]",
 	Short: "Create a tunnel to a VM",
-	Long: `Create a port forwarding tunnel to a VM.
+	Long: `Create port forwarding tunnels through a VM.
 
-If local-port is not specified, it defaults to remote-port.
+With a remote-host, the VM relays the connection to that host (for example a
+database endpoint only reachable from the VM's network). If local-port is not
+specified, it defaults to remote-port. Add more forwards with -L, as
+local:host:remote (or local:remote for a port on the VM itself).
+
+If a requested local port is already in use, a free port is picked instead
+and printed.
 
 Examples:
-  cml vm tunnel web-01 3306           # Forward 3306:3306
-  cml vm tunnel web-01 3306 13306     # Forward 13306:3306
-  cml vm tunnel db-01 5432            # PostgreSQL tunnel`,
-	Args: cobra.RangeArgs(2, 3),
+  cml vm tunnel web-01 3306                          # Forward 3306:3306
+  cml vm tunnel web-01 3306 13306                    # Forward 13306:3306
+  cml vm tunnel bastion mydb.xyz.rds.amazonaws.com 5432
+  cml vm tunnel bastion mydb.internal 5432 15432
+  cml vm tunnel bastion -L 5432:db.internal:5432 -L 6379:cache.internal:6379`,
+	Args: cobra.RangeArgs(1, 4),
 	RunE: runVMTunnel,
 }
 
@@ -128,6 +164,8 @@ var (
 	vmListName        string
 	vmListTags        []string
 	vmListInteractive bool
+	vmListWhere       string
+	vmTunnelForwards  []string
 	vmContextFlag     string
 )
 
@@ -146,40 +184,48 @@ func init() {
 	vmListCmd.Flags().StringVar(&vmListName, "name", "", "Filter by name pattern")
 	vmListCmd.Flags().StringArrayVarP(&vmListTags, "tag", "t", nil, "Filter by tag (key=value)")
 	vmListCmd.Flags().BoolVarP(&vmListInteractive, "interactive", "i", false, "Interactive selection mode")
+	vmListCmd.Flags().StringVarP(&vmListWhere, "where", "w", "", "Filter by expression (see 'cml vm list --help')")
+	vmTunnelCmd.Flags().StringArrayVarP(&vmTunnelForwards, "forward", "L", nil, "Additional forward local:host:remote (repeatable)")
 
 	// Global context override
 	vmCmd.PersistentFlags().StringVarP(&vmContextFlag, "context", "c", "", "Use specific context")
 }
 
-// getVMProvider returns the VM provider for the current or specified context
-func getVMProvider(ctx context.Context) (provider.VMProvider, error) {
-	// Get context (from flag or current)
-	var ctxConfig *config.Context
-	var ctxName string
-	var err error
-
+// resolveVMContext returns the --context context, or the current one
+func resolveVMContext() (*config.Context, string, error) {
 	if vmContextFlag != "" {
-		// Use specified context
-		cfg, loadErr := config.LoadCMLConfig()
-		if loadErr != nil {
-			return nil, loadErr
-		}
-		ctxConfig = cfg.Contexts[vmContextFlag]
-		if ctxConfig == nil {
-			return nil, fmt.Errorf("context %q not found", vmContextFlag)
-		}
-		ctxName = vmContextFlag
-	} else {
-		// Use current context
-		ctxConfig, ctxName, err = config.GetCurrentContext()
+		cfg, err := config.LoadCMLConfig()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
+		ctxConfig := cfg.Contexts[vmContextFlag]
 		if ctxConfig == nil {
-			return nil, fmt.Errorf("no context set. Use 'cml use <context>' to set one")
+			return nil, "", fmt.Errorf("context %q not found", vmContextFlag)
 		}
+		return ctxConfig, vmContextFlag, nil
+	}
+
+	ctxConfig, ctxName, err := config.GetCurrentContext()
+	if err != nil {
+		return nil, "", err
+	}
+	if ctxConfig == nil {
+		return nil, "", fmt.Errorf("no context set. Use 'cml use <context>' to set one")
+	}
+	return ctxConfig, ctxName, nil
+}
+
+// getVMProvider returns the VM provider for the current or specified context
+func getVMProvider(ctx context.Context) (provider.VMProvider, error) {
+	ctxConfig, ctxName, err := resolveVMContext()
+	if err != nil {
+		return nil, err
 	}
+	return newVMProvider(ctx, ctxConfig, ctxName)
+}
 
+// newVMProvider builds the VM provider for a context
+func newVMProvider(ctx context.Context, ctxConfig *config.Context, ctxName string) (provider.VMProvider, error) {
 	// Create provider based on context
 	switch ctxConfig.Provider {
 	case "aws":
@@ -226,12 +272,24 @@ func runVMList(cmd *cobra.Command, args []string) error {
 	// Build filter
 	filter := &provider.VMFilter{}
 
-	if vmListState != "" && vmListState != "all" {
+	if vmListState != "" {
 		filter.State = vmListState
-	} else if vmListState == "" {
+	} else {
 		filter.State = "running"
 	}
 
+	if vmListWhere != "" {
+		q, err := query.Parse(vmListWhere)
+		if err != nil {
+			return err
+		}
+		filter.Where = q
+		// A state condition in --where replaces the running-only default
+		if vmListState == "" && q.References("state") {
+			filter.State = "all"
+		}
+	}
+
 	if vmListName != "" {
 		filter.Name = vmListName
 	}
@@ -252,6 +310,9 @@ func runVMList(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if filter.Where != nil {
+		vms = filter.Where.Filter(vms)
+	}
 
 	if len(vms) == 0 {
 		fmt.Println("No VMs found")
@@ -319,40 +380,153 @@ func runVMConnect(cmd *cobra.Command, args []string) error {
 }
 
 func runVMTunnel(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	opts, err := parseTunnelArgs(args[1:], vmTunnelForwards)
+	if err != nil {
+		return err
+	}
+
+	// Cancel on Ctrl+C so native tunnels close their sessions cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	vmProvider, err := getVMProvider(ctx)
 	if err != nil {
 		return err
 	}
 
-	// Parse ports
-	remotePort := 0
-	localPort := 0
+	// Swap busy local ports for free ones before any listener binds
+	used := make(map[int]bool)
+	if opts.RemotePort > 0 {
+		if opts.LocalPort, err = freeLocalPort(opts.LocalPort, used); err != nil {
+			return err
+		}
+	}
+	for i := range opts.Forwards {
+		if opts.Forwards[i].LocalPort, err = freeLocalPort(opts.Forwards[i].LocalPort, used); err != nil {
+			return err
+		}
+	}
 
-	_, err = fmt.Sscanf(args[1], "%d", &remotePort)
-	if err != nil {
-		return fmt.Errorf("invalid remote port: %s", args[1])
+	fmt.Printf("Creating tunnel to %s:\n", args[0])
+	for _, f := range opts.AllForwards() {
+		remote := "remote"
+		if f.RemoteHost != "" {
+			remote = f.RemoteHost
+		}
+		fmt.Printf("  localhost:%d -> %s:%d\n", f.LocalPort, remote, f.RemotePort)
 	}
+	fmt.Println("Press Ctrl+C to close the tunnel")
 
+	if err := vmProvider.Tunnel(ctx, args[0], opts); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// parseTunnelArgs parses "[remote-host] <remote-port> [local-port]" and -L
+// specs into tunnel options. A lone second argument that is not a port is a
+// remote host.
+func parseTunnelArgs(args []string, specs []string) (*provider.TunnelOptions, error) {
+	opts := &provider.TunnelOptions{}
+
+	if len(args) > 0 {
+		if _, err := strconv.Atoi(args[0]); err != nil {
+			opts.RemoteHost = args[0]
+			args = args[1:]
+			if len(args) == 0 {
+				return nil, fmt.Errorf("remote port required after remote host %s", opts.RemoteHost)
+			}
+		}
+	}
 	if len(args) > 2 {
-		_, err = fmt.Sscanf(args[2], "%d", &localPort)
+		return nil, fmt.Errorf("too many arguments")
+	}
+	if len(args) > 0 {
+		port, err := parsePort(args[0])
 		if err != nil {
-			return fmt.Errorf("invalid local port: %s", args[2])
+			return nil, fmt.Errorf("invalid remote port: %s", args[0])
 		}
-	} else {
-		localPort = remotePort
+		opts.RemotePort, opts.LocalPort = port, port
+	}
+	if len(args) > 1 {
+		port, err := parsePort(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid local port: %s", args[1])
+		}
+		opts.LocalPort = port
+	}
+
+	for _, spec := range specs {
+		f, err := parseForwardSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		opts.Forwards = append(opts.Forwards, f)
 	}
 
-	opts := &provider.TunnelOptions{
-		LocalPort:  localPort,
-		RemotePort: remotePort,
+	if opts.RemotePort == 0 && len(opts.Forwards) == 0 {
+		return nil, fmt.Errorf("specify a remote port or at least one -L forward")
 	}
+	return opts, nil
+}
 
-	fmt.Printf("Creating tunnel to %s: localhost:%d -> remote:%d\n", args[0], localPort, remotePort)
-	fmt.Println("Press Ctrl+C to close the tunnel")
+// parseForwardSpec parses an -L spec: local:host:remote or local:remote.
+func parseForwardSpec(spec string) (provider.ForwardSpec, error) {
+	parts := strings.Split(spec, ":")
+	var f provider.ForwardSpec
+	var err error
+
+	switch len(parts) {
+	case 2:
+		if f.LocalPort, err = parsePort(parts[0]); err == nil {
+			f.RemotePort, err = parsePort(parts[1])
+		}
+	case 3:
+		f.RemoteHost = parts[1]
+		if f.LocalPort, err = parsePort(parts[0]); err == nil {
+			f.RemotePort, err = parsePort(parts[2])
+		}
+		if f.RemoteHost == "" {
+			err = fmt.Errorf("empty host")
+		}
+	default:
+		err = fmt.Errorf("expected local:host:remote or local:remote")
+	}
+	if err != nil {
+		return f, fmt.Errorf("invalid forward %q: %w", spec, err)
+	}
+	return f, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// freeLocalPort returns port if it can be bound on 127.0.0.1 and is not in
+// used, otherwise a free port chosen by the OS. The result is added to used.
+func freeLocalPort(port int, used map[int]bool) (int, error) {
+	if !used[port] {
+		if ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			_ = ln.Close()
+			used[port] = true
+			return port, nil
+		}
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("find a free local port: %w", err)
+	}
+	free := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
 
-	return vmProvider.Tunnel(ctx, args[0], opts)
+	fmt.Fprintf(os.Stderr, "Local port %d is in use; using %d\n", port, free)
+	used[free] = true
+	return free, nil
 }
 
 func runVMStart(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+)
+
+var vmConsoleCmd = &cobra.Command{
+	Use:   "console <name-or-id>",
+	Short: "Show a VM's serial console output",
+	Long: `Print the serial console output of a VM, which works even when the VM
+fails to boot and SSM or ssh are unavailable.
+
+AWS uses GetConsoleOutput (the most recent 64 KB; EC2 refreshes it every few
+minutes). GCP reads serial port 1.
+
+With --screenshot, a screenshot of the VM's screen is saved instead
+(AWS: JPEG; GCP: PNG, requires the display device to be enabled).
+
+Examples:
+  cml vm console web-01
+  cml vm console web-01 --follow
+  cml vm console web-01 --screenshot
+  cml vm console web-01 --screenshot -o boot.jpg`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVMConsole,
+}
+
+var (
+	vmConsoleFollow     bool
+	vmConsoleScreenshot bool
+	vmConsoleOutput     string
+)
+
+func init() {
+	vmCmd.AddCommand(vmConsoleCmd)
+
+	vmConsoleCmd.Flags().BoolVarP(&vmConsoleFollow, "follow", "f", false, "Keep polling for new output")
+	vmConsoleCmd.Flags().BoolVar(&vmConsoleScreenshot, "screenshot", false, "Save a screenshot of the console")
+	vmConsoleCmd.Flags().StringVarP(&vmConsoleOutput, "output", "o", "", "Screenshot file (default: <name>-console.<jpg|png>)")
+}
+
+func runVMConsole(cmd *cobra.Command, args []string) error {
+	// Ctrl+C ends --follow cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	console, ok := vmProvider.(provider.VMConsole)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	vm, err := vmProvider.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	if !vmConsoleScreenshot {
+		return console.ConsoleOutput(ctx, vm, vmConsoleFollow, os.Stdout)
+	}
+
+	data, err := console.Screenshot(ctx, vm)
+	if err != nil {
+		return err
+	}
+
+	path := vmConsoleOutput
+	if path == "" {
+		ext := "jpg"
+		if http.DetectContentType(data) == "image/png" {
+			ext = "png"
+		}
+		path = fmt.Sprintf("%s-console.%s", vmLabel(vm), ext)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	fmt.Printf("Saved screenshot of %s to %s (%s)\n", vmLabel(vm), path, humanSize(int64(len(data))))
+	return nil
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+)
+
+var vmCpCmd = &cobra.Command{
+	Use:   "cp <src> <dst>",
+	Short: "Copy a file to or from a VM",
+	Long: `Copy a single file between the local machine and a VM. Exactly one of
+src and dst is remote, written as <name-or-id>:<path>. A remote path ending in
+'/' (or a local directory) keeps the source file name. A drive letter such as
+C:\tmp\f or C:/tmp/f is a local Windows path, not a VM named C.
+
+AWS works with SSM-only instances: the file is staged in an S3 bucket and the
+instance fetches or uploads it through a short-lived presigned URL with curl
+(or wget). Set the bucket on the context with
+'cml use update <context> --transfer-bucket <bucket>' or pass --bucket.
+Remote files are written as root. Staged objects are deleted afterwards.
+The presigned URL is part of the Run Command parameters, so anyone with
+ssm:ListCommands can see it in the command history until it expires, two
+minutes after the copy starts.
+
+GCP streams the file over gcloud SSH, through the context bastion when one
+is configured and through IAP for instances without an external IP.
+
+Examples:
+  cml vm cp ./app.conf web-01:/tmp/app.conf
+  cml vm cp ./app.conf web-01:/tmp/
+  cml vm cp web-01:/var/log/app.log .
+  cml vm cp i-0abc123def456:/tmp/heap.hprof ./heap.hprof --bucket my-transfers`,
+	Args: cobra.ExactArgs(2),
+	RunE: runVMCp,
+}
+
+var vmCpBucket string
+
+func init() {
+	vmCmd.AddCommand(vmCpCmd)
+
+	vmCpCmd.Flags().StringVar(&vmCpBucket, "bucket", "", "AWS: S3 bucket to stage the transfer (default: context transfer_bucket)")
+}
+
+func runVMCp(cmd *cobra.Command, args []string) error {
+	srcVM, srcPath := splitVMPath(args[0])
+	dstVM, dstPath := splitVMPath(args[1])
+
+	switch {
+	case srcVM != "" && dstVM != "":
+		return fmt.Errorf("copying between two VMs is not supported")
+	case srcVM == "" && dstVM == "":
+		return fmt.Errorf("one of src or dst must be remote (<name-or-id>:<path>)")
+	}
+
+	// Cancel on Ctrl+C so remote commands are cancelled too
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ctxConfig, _, err := resolveVMContext()
+	if err != nil {
+		return err
+	}
+	opts := &provider.CopyOptions{StagingBucket: ctxConfig.TransferBucket}
+	if vmCpBucket != "" {
+		opts.StagingBucket = vmCpBucket
+	}
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	copier, ok := vmProvider.(provider.VMCopier)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	if dstVM != "" {
+		info, err := os.Stat(srcPath)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", srcPath)
+		}
+		if dstPath == "" || strings.HasSuffix(dstPath, "/") {
+			dstPath += filepath.Base(srcPath)
+		}
+
+		vm, err := vmProvider.Get(ctx, dstVM)
+		if err != nil {
+			return err
+		}
+		if err := copier.CopyTo(ctx, vm, srcPath, dstPath, opts); err != nil {
+			return err
+		}
+		fmt.Printf("Copied %s (%s) → %s:%s\n", srcPath, humanSize(info.Size()), dstVM, dstPath)
+		return nil
+	}
+
+	if srcPath == "" || strings.HasSuffix(srcPath, "/") {
+		return fmt.Errorf("remote source must be a file path")
+	}
+	if info, err := os.Stat(dstPath); (err == nil && info.IsDir()) || strings.HasSuffix(dstPath, string(os.PathSeparator)) {
+		dstPath = filepath.Join(dstPath, path.Base(srcPath))
+	}
+
+	vm, err := vmProvider.Get(ctx, srcVM)
+	if err != nil {
+		return err
+	}
+	if err := copier.CopyFrom(ctx, vm, srcPath, dstPath, opts); err != nil {
+		return err
+	}
+
+	size := "?"
+	if info, err := os.Stat(dstPath); err == nil {
+		size = humanSize(info.Size())
+	}
+	fmt.Printf("Copied %s:%s → %s (%s)\n", srcVM, srcPath, dstPath, size)
+	return nil
+}
+
+// splitVMPath splits "<vm>:<path>" into its parts. Arguments without a colon,
+// whose part before the colon contains a path separator (./a:b), or that
+// start with a Windows drive (C:\tmp, C:/tmp) are local paths and return an
+// empty VM.
+func splitVMPath(arg string) (string, string) {
+	i := strings.Index(arg, ":")
+	if i <= 0 || strings.ContainsAny(arg[:i], `/\`) || isWindowsDrivePath(arg) {
+		return "", arg
+	}
+	return arg[:i], arg[i+1:]
+}
+
+// isWindowsDrivePath reports whether arg starts with a drive letter, a colon
+// and a separator
+func isWindowsDrivePath(arg string) bool {
+	if len(arg) < 3 || arg[1] != ':' || (arg[2] != '\\' && arg[2] != '/') {
+		return false
+	}
+	c := arg[0] | 0x20
+	return c >= 'a' && c <= 'z'
+}
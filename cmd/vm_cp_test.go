@@ -0,0 +1,29 @@
+package cmd
+
+import "testing"
+
+func TestSplitVMPath(t *testing.T) {
+	tests := []struct {
+		arg, vm, path string
+	}{
+		{"web-01:/tmp/app.conf", "web-01", "/tmp/app.conf"},
+		{"i-0abc123def456:/tmp/", "i-0abc123def456", "/tmp/"},
+		{"web-01:", "web-01", ""},
+		{"./app.conf", "", "./app.conf"},
+		{"app.conf", "", "app.conf"},
+		{"./a:b", "", "./a:b"},
+		{`dir\a:b`, "", `dir\a:b`},
+		{":/tmp/x", "", ":/tmp/x"},
+		{`C:\tmp\f`, "", `C:\tmp\f`},
+		{"C:/tmp/f", "", "C:/tmp/f"},
+		{`d:\f`, "", `d:\f`},
+		{"c:relative", "c", "relative"},
+		{"ab:/tmp/f", "ab", "/tmp/f"},
+	}
+	for _, tt := range tests {
+		vm, path := splitVMPath(tt.arg)
+		if vm != tt.vm || path != tt.path {
+			t.Errorf("splitVMPath(%q) = %q, %q; want %q, %q", tt.arg, vm, path, tt.vm, tt.path)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var vmCreateCmd = &cobra.Command{
+	Use:   "create --template <name>[:version]",
+	Short: "Create VMs from a template",
+	Long: `Create VMs from a launch template (AWS) or instance template (GCP).
+
+AWS: --template is a launch template name or lt- ID, optionally with a
+version (a number, $Latest or $Default; default $Default). Name and tags
+are added to the template's own tags.
+
+GCP: --template is a global instance template name or a full template URL.
+Instance templates are not versioned. VMs are created in --zone, or the
+context region when it is a zone. Without --name, VMs are named after the
+template with a random suffix.
+
+With --count greater than 1, names get a -1..n suffix.
+
+Examples:
+  cml vm create --template web --name web-07
+  cml vm create --template web:3 --name web --count 3
+  cml vm create --template lt-0abc123 -t owner=alice
+  cml vm create --template web-tmpl --zone us-central1-b`,
+	Args: cobra.NoArgs,
+	RunE: runVMCreate,
+}
+
+var vmTerminateCmd = &cobra.Command{
+	Use:     "terminate <name-or-id>...",
+	Aliases: []string{"delete", "rm"},
+	Short:   "Terminate VMs",
+	Long: `Permanently terminate (AWS) or delete (GCP) VMs.
+
+VMs with termination protection (AWS) or deletion protection (GCP) are
+refused. VMs managed by an Auto Scaling group or managed instance group are
+flagged, since the group will replace them. You are asked to confirm unless
+--yes is given.
+
+Examples:
+  cml vm terminate web-07
+  cml vm terminate web-1 web-2 web-3 --yes`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runVMTerminate,
+}
+
+var (
+	vmCreateTemplate string
+	vmCreateName     string
+	vmCreateCount    int
+	vmCreateZone     string
+	vmCreateTags     []string
+
+	vmTerminateYes bool
+)
+
+func init() {
+	vmCmd.AddCommand(vmCreateCmd)
+	vmCmd.AddCommand(vmTerminateCmd)
+
+	vmCreateCmd.Flags().StringVar(&vmCreateTemplate, "template", "", "Launch/instance template as name[:version] (required)")
+	vmCreateCmd.Flags().StringVar(&vmCreateName, "name", "", "VM name")
+	vmCreateCmd.Flags().IntVarP(&vmCreateCount, "count", "n", 1, "Number of VMs to create")
+	vmCreateCmd.Flags().StringVar(&vmCreateZone, "zone", "", "Zone to create in (GCP)")
+	vmCreateCmd.Flags().StringArrayVarP(&vmCreateTags, "tag", "t", nil, "Extra tag (key=value, GCP: label)")
+	_ = vmCreateCmd.MarkFlagRequired("template")
+
+	vmTerminateCmd.Flags().BoolVarP(&vmTerminateYes, "yes", "y", false, "Skip confirmation")
+}
+
+func runVMCreate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if vmCreateCount < 1 {
+		return fmt.Errorf("--count must be at least 1")
+	}
+
+	opts := &provider.VMCreateOptions{
+		Template: vmCreateTemplate,
+		Name:     vmCreateName,
+		Count:    vmCreateCount,
+		Zone:     vmCreateZone,
+	}
+	// Template URLs contain ':' only in the scheme, never a version
+	if i := strings.LastIndex(opts.Template, ":"); i > 0 && !strings.Contains(opts.Template[i:], "/") {
+		opts.Template, opts.Version = opts.Template[:i], opts.Template[i+1:]
+	}
+	tags, err := parseKeyValues(vmCreateTags)
+	if err != nil {
+		return err
+	}
+	opts.Tags = tags
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	creator, ok := vmProvider.(provider.VMCreator)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	vms, err := creator.Create(ctx, opts)
+	if len(vms) > 0 {
+		fmt.Printf("Created %d VM(s) from template %s:\n", len(vms), vmCreateTemplate)
+		printVMTable(vms)
+	}
+	return err
+}
+
+func runVMTerminate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	creator, ok := vmProvider.(provider.VMCreator)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	// Resolve and check everything before asking, so nothing is terminated
+	// when one of the VMs is protected
+	vms := make([]*types.VM, 0, len(args))
+	for _, nameOrID := range args {
+		vm, err := vmProvider.Get(ctx, nameOrID)
+		if err != nil {
+			return err
+		}
+		if err := creator.CheckTerminate(ctx, vm); err != nil {
+			return err
+		}
+		vms = append(vms, vm)
+	}
+
+	fmt.Println("The following VMs will be terminated:")
+	for _, vm := range vms {
+		fmt.Printf("  %s  %s  %s  %s\n", ui.NameStyle.Render(vmLabel(vm)), vm.ID, vm.State, vm.Zone)
+		if vm.ASG != "" {
+			fmt.Printf("    %s\n", ui.MutedStyle.Render(fmt.Sprintf("member of group %s; it will launch a replacement", vm.ASG)))
+		}
+	}
+
+	if !vmTerminateYes && !confirm(fmt.Sprintf("\nTerminate %d VM(s)? This cannot be undone. [y/N]: ", len(vms))) {
+		fmt.Println("Termination cancelled")
+		return nil
+	}
+
+	failed := 0
+	for _, vm := range vms {
+		if err := creator.Terminate(ctx, vm); err != nil {
+			fmt.Printf("%s %s: %v\n", ui.StoppedStyle.Render("✗"), vmLabel(vm), err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s Terminating %s\n", ui.RunningStyle.Render("✓"), vmLabel(vm))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d VMs failed to terminate", failed, len(vms))
+	}
+	return nil
+}
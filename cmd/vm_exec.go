@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/shell"
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var vmExecCmd = &cobra.Command{
+	Use:   "exec [name-or-id...] [--tag k=v] [--asg name] -- <command>",
+	Short: "Run a command on one or more VMs",
+	Long: `Run a non-interactive shell command on one or more VMs concurrently.
+
+Targets are VMs named on the command line, running VMs matching every --tag,
+and/or running members of an AWS Auto Scaling group (--asg). Everything after
+'--' is the command. Several arguments are quoted one by one, so they reach
+the VM as typed; a single argument is run as a shell script, for pipes and
+redirects ('df -h / | tail -1').
+
+AWS runs the command through SSM Run Command (AWS-RunShellScript); GCP runs it
+over gcloud SSH, through the context bastion when one is configured.
+
+Output lines are prefixed with the VM name. A summary of exit codes follows,
+and the command fails if any VM returned a non-zero exit code. Use -o json for
+a machine-readable result per VM instead.
+
+Examples:
+  cml vm exec web-01 -- uptime
+  cml vm exec web-01 web-02 -- 'df -h /'
+  cml vm exec -t env=prod -t role=web -- systemctl is-active nginx
+  cml vm exec --asg web-asg --concurrency 5 -- sudo systemctl restart app
+  cml vm exec -t env=staging -o json -- cat /etc/os-release`,
+	RunE: runVMExec,
+}
+
+var (
+	vmExecTags        []string
+	vmExecASG         string
+	vmExecConcurrency int
+	vmExecOutput      string
+)
+
+func init() {
+	vmCmd.AddCommand(vmExecCmd)
+
+	vmExecCmd.Flags().StringArrayVarP(&vmExecTags, "tag", "t", nil, "Target running VMs with tag (key=value)")
+	vmExecCmd.Flags().StringVar(&vmExecASG, "asg", "", "Target running VMs in an AWS Auto Scaling group")
+	vmExecCmd.Flags().IntVar(&vmExecConcurrency, "concurrency", 10, "Maximum VMs to run on at once")
+	vmExecCmd.Flags().StringVarP(&vmExecOutput, "output", "o", "text", "Output format (text, json)")
+}
+
+// vmExecResult is the outcome of running the command on one VM.
+type vmExecResult struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (r *vmExecResult) ok() bool {
+	return r.Error == "" && r.ExitCode == 0
+}
+
+// execCommand builds the remote shell command. A single argument is taken as
+// a script; several are quoted so each stays one argument on the VM.
+func execCommand(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+	return shell.Join(args)
+}
+
+func runVMExec(cmd *cobra.Command, args []string) error {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 || dash == len(args) {
+		return fmt.Errorf("missing command: use 'cml vm exec <target> -- <command>'")
+	}
+	names, command := args[:dash], execCommand(args[dash:])
+
+	if len(names) == 0 && len(vmExecTags) == 0 && vmExecASG == "" {
+		return fmt.Errorf("no targets: name VMs or use --tag/--asg")
+	}
+	if vmExecOutput != "text" && vmExecOutput != "json" {
+		return fmt.Errorf("invalid output format %q (use text or json)", vmExecOutput)
+	}
+	if vmExecConcurrency < 1 {
+		vmExecConcurrency = 1
+	}
+
+	// Cancel on Ctrl+C so in-flight commands are cancelled remotely
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	executor, ok := vmProvider.(provider.VMExecutor)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	vms, err := resolveExecTargets(ctx, vmProvider, names)
+	if err != nil {
+		return err
+	}
+	if len(vms) == 0 {
+		fmt.Println("No VMs found")
+		return nil
+	}
+
+	results := execOnVMs(ctx, executor, vms, command, vmExecOutput == "text")
+
+	failed := 0
+	for i := range results {
+		if !results[i].ok() {
+			failed++
+		}
+	}
+
+	if vmExecOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printExecSummary(results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d VMs", failed, len(results))
+	}
+	return nil
+}
+
+// resolveExecTargets combines named VMs with running VMs matching --tag and
+// --asg, dropping duplicates while keeping the first-seen order.
+func resolveExecTargets(ctx context.Context, vmProvider provider.VMProvider, names []string) ([]types.VM, error) {
+	var vms []types.VM
+	seen := make(map[string]bool)
+	add := func(vm types.VM) {
+		if !seen[vm.ID] {
+			seen[vm.ID] = true
+			vms = append(vms, vm)
+		}
+	}
+
+	for _, name := range names {
+		vm, err := vmProvider.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		add(*vm)
+	}
+
+	if len(vmExecTags) > 0 || vmExecASG != "" {
+		filter := &provider.VMFilter{State: "running"}
+		if len(vmExecTags) > 0 {
+			filter.Tags = make(map[string]string)
+			for _, t := range vmExecTags {
+				parts := strings.SplitN(t, "=", 2)
+				if len(parts) != 2 {
+					return nil, fmt.Errorf("invalid tag filter %q (expected key=value)", t)
+				}
+				filter.Tags[parts[0]] = parts[1]
+			}
+		}
+
+		matched, err := vmProvider.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range matched {
+			if vmExecASG != "" && vm.ASG != vmExecASG {
+				continue
+			}
+			add(vm)
+		}
+	}
+
+	return vms, nil
+}
+
+// execOnVMs runs command on every VM with at most --concurrency in flight.
+// In stream mode output is written to the terminal line by line, prefixed
+// with the VM name; otherwise it is only captured into the results.
+func execOnVMs(ctx context.Context, executor provider.VMExecutor, vms []types.VM, command string, stream bool) []vmExecResult {
+	results := make([]vmExecResult, len(vms))
+
+	width := 0
+	for _, vm := range vms {
+		if n := len(vmLabel(&vm)); n > width {
+			width = n
+		}
+	}
+
+	var outMu sync.Mutex
+	sem := make(chan struct{}, vmExecConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range vms {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			vm := &vms[i]
+			var stdoutBuf, stderrBuf bytes.Buffer
+			var stdout, stderr io.Writer = &stdoutBuf, &stderrBuf
+
+			var outLines, errLines *prefixWriter
+			if stream {
+				prefix := padRightVM(vmLabel(vm), width)
+				outLines = &prefixWriter{w: os.Stdout, mu: &outMu, prefix: ui.NameStyle.Render(prefix) + " │ "}
+				errLines = &prefixWriter{w: os.Stderr, mu: &outMu, prefix: ui.StoppedStyle.Render(prefix) + " │ "}
+				stdout = io.MultiWriter(stdout, outLines)
+				stderr = io.MultiWriter(stderr, errLines)
+			}
+
+			start := time.Now()
+			code, err := executor.Exec(ctx, vm, command, stdout, stderr)
+
+			if stream {
+				outLines.Flush()
+				errLines.Flush()
+			}
+
+			results[i] = vmExecResult{
+				ID:         vm.ID,
+				Name:       vm.Name,
+				ExitCode:   code,
+				Stdout:     stdoutBuf.String(),
+				Stderr:     stderrBuf.String(),
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// vmLabel is the name used for a VM in output: its name, or ID if unnamed.
+func vmLabel(vm *types.VM) string {
+	if vm.Name != "" {
+		return vm.Name
+	}
+	return vm.ID
+}
+
+// printExecSummary prints one line per VM with its exit code and duration.
+func printExecSummary(results []vmExecResult) {
+	fmt.Println()
+
+	names := make([]string, len(results))
+	width := 0
+	for i, r := range results {
+		names[i] = r.Name
+		if names[i] == "" {
+			names[i] = r.ID
+		}
+		if n := len(names[i]); n > width {
+			width = n
+		}
+	}
+
+	succeeded := 0
+	for i, r := range results {
+		name := names[i]
+		duration := time.Duration(r.DurationMs) * time.Millisecond
+
+		switch {
+		case r.Error != "":
+			fmt.Printf("  %s %s  %s\n", ui.StoppedStyle.Render("✗"), padRightVM(name, width),
+				ui.MutedStyle.Render(r.Error))
+		case r.ExitCode != 0:
+			fmt.Printf("  %s %s  exit %d  %s\n", ui.StoppedStyle.Render("✗"), padRightVM(name, width),
+				r.ExitCode, ui.MutedStyle.Render(duration.String()))
+		default:
+			succeeded++
+			fmt.Printf("  %s %s  exit 0  %s\n", ui.RunningStyle.Render("✓"), padRightVM(name, width),
+				ui.MutedStyle.Render(duration.String()))
+		}
+	}
+
+	fmt.Printf("\n  %d succeeded, %d failed\n", succeeded, len(results)-succeeded)
+}
+
+// prefixWriter writes complete lines to w with a prefix, holding back a
+// trailing partial line until more output or Flush. Writers sharing mu
+// never interleave within a line.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.writeLine(p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes any trailing partial line.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(p.buf)
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "%s%s\n", p.prefix, line)
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var vmReachCmd = &cobra.Command{
+	Use:   "reach <src> <dst>:<port>",
+	Short: "Explain whether a VM can reach a port",
+	Long: `Explain, without sending any traffic, whether src can reach port on dst.
+
+src and dst are VM names or IDs, or IP addresses. The firewall
+configuration of both ends is fetched and evaluated, and each check names
+the rule or route that allows or blocks the traffic:
+
+  AWS  security groups (egress and ingress), network ACLs in both
+       directions including the return path to ephemeral ports, and the
+       source subnet's route table
+  GCP  VPC firewall rules (egress and ingress, by priority, with target
+       and source tags and service accounts) and routes
+
+A public src IP is treated as the internet, and the destination VM's public
+IP is used. Things not evaluated (OS firewalls, GCP firewall policies, AWS
+prefix lists) are reported as info or warnings. Exits non-zero when a check
+blocks the traffic.
+
+Examples:
+  cml vm reach web-01 db-01:5432
+  cml vm reach bastion 10.20.3.15:443
+  cml vm reach 203.0.113.7 web-01:22
+  cml vm reach web-01 dns-01:53 --protocol udp -o json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runVMReach,
+}
+
+var (
+	vmReachProtocol string
+	vmReachOutput   string
+)
+
+func init() {
+	vmCmd.AddCommand(vmReachCmd)
+
+	vmReachCmd.Flags().StringVar(&vmReachProtocol, "protocol", "tcp", "Protocol: tcp or udp")
+	vmReachCmd.Flags().StringVarP(&vmReachOutput, "output", "o", "text", "Output format: text, json")
+}
+
+func runVMReach(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	protocol := strings.ToLower(vmReachProtocol)
+	if protocol != "tcp" && protocol != "udp" {
+		return fmt.Errorf("invalid protocol %q (use tcp or udp)", vmReachProtocol)
+	}
+	if vmReachOutput != "text" && vmReachOutput != "json" {
+		return fmt.Errorf("invalid output format %q (use text or json)", vmReachOutput)
+	}
+
+	i := strings.LastIndex(args[1], ":")
+	if i <= 0 {
+		return fmt.Errorf("destination must be <name-or-ip>:<port>, got %q", args[1])
+	}
+	port, err := parsePort(args[1][i+1:])
+	if err != nil {
+		return err
+	}
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+	reacher, ok := vmProvider.(provider.VMReachability)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	src, err := resolveReachEndpoint(ctx, vmProvider, args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := resolveReachEndpoint(ctx, vmProvider, args[1][:i])
+	if err != nil {
+		return err
+	}
+	// From the internet, the destination VM is reached on its public IP
+	if src.VM == nil && !net.ParseIP(src.IP).IsPrivate() && dst.VM != nil {
+		dst.IP = dst.VM.PublicIP
+	}
+
+	report, err := reacher.Reach(ctx, src, dst, protocol, port)
+	if err != nil {
+		return err
+	}
+
+	if vmReachOutput == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			*types.ReachReport
+			Allowed bool `json:"allowed"`
+		}{report, report.Allowed()}); err != nil {
+			return err
+		}
+	} else {
+		printReachReport(report)
+	}
+
+	if !report.Allowed() {
+		return fmt.Errorf("traffic is blocked")
+	}
+	return nil
+}
+
+// resolveReachEndpoint treats an IP address as-is and anything else as a
+// VM, using its private IP.
+func resolveReachEndpoint(ctx context.Context, vmProvider provider.VMProvider, arg string) (provider.ReachEndpoint, error) {
+	if ip := net.ParseIP(arg); ip != nil {
+		return provider.ReachEndpoint{IP: ip.String()}, nil
+	}
+	vm, err := vmProvider.Get(ctx, arg)
+	if err != nil {
+		return provider.ReachEndpoint{}, err
+	}
+	if vm.PrivateIP == "" {
+		return provider.ReachEndpoint{}, fmt.Errorf("%s has no private IP", vmLabel(vm))
+	}
+	return provider.ReachEndpoint{VM: vm, IP: vm.PrivateIP}, nil
+}
+
+func printReachReport(r *types.ReachReport) {
+	endpoint := func(name, ip string) string {
+		if name == ip || ip == "" {
+			return ui.NameStyle.Render(name)
+		}
+		return fmt.Sprintf("%s (%s)", ui.NameStyle.Render(name), ip)
+	}
+	fmt.Printf("%s → %s  %s/%d\n\n", endpoint(r.Source, r.SourceIP), endpoint(r.Destination, r.DestIP), r.Protocol, r.Port)
+
+	width := 0
+	for _, s := range r.Steps {
+		width = max(width, len(s.Check))
+	}
+
+	var blocked []string
+	for _, s := range r.Steps {
+		var mark string
+		switch s.Verdict {
+		case types.ReachAllow:
+			mark = ui.RunningStyle.Render("✓")
+		case types.ReachDeny:
+			mark = ui.StoppedStyle.Render("✗")
+			blocked = append(blocked, s.Check)
+		case types.ReachWarn:
+			mark = ui.PendingStyle.Render("!")
+		default:
+			mark = ui.MutedStyle.Render("·")
+		}
+		fmt.Printf("  %s %s  %s\n", mark, padRightVM(s.Check, width), s.Rule)
+		if s.Detail != "" {
+			fmt.Printf("    %s %s\n", strings.Repeat(" ", width), ui.MutedStyle.Render(s.Detail))
+		}
+	}
+
+	fmt.Println()
+	if len(blocked) > 0 {
+		fmt.Printf("%s Blocked by: %s\n", ui.StoppedStyle.Render("✗"), strings.Join(blocked, ", "))
+		return
+	}
+	fmt.Printf("%s Allowed by the evaluated rules\n", ui.RunningStyle.Render("✓"))
+}
@@ -0,0 +1,428 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/query"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var vmSnapshotCmd = &cobra.Command{
+	Use:   "snapshot <name-or-id>",
+	Short: "Image or snapshot a VM",
+	Long: `Create an image of a VM (AWS: AMI; GCP: machine image), or with
+--kind disk a snapshot of each attached disk (AWS: crash-consistent EBS
+snapshots; GCP: disk snapshots), and wait for it to complete.
+
+The default name is <vm>-<YYYYMMDD-HHMMSS>. Images and snapshots are tagged
+(GCP: labelled) cml-source=<vm id> so list and prune can find them.
+
+AWS images are taken without rebooting the instance; pass --reboot for a
+consistent file system.
+
+Examples:
+  cml vm snapshot web-01
+  cml vm snapshot web-01 --name web-01-pre-upgrade -t reason=upgrade
+  cml vm snapshot db-01 --kind disk --no-wait
+  cml vm snapshot list web-01
+  cml vm snapshot prune --older-than 30d --keep 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runVMSnapshot,
+}
+
+var vmSnapshotListCmd = &cobra.Command{
+	Use:     "list [vm-name-or-id]",
+	Aliases: []string{"ls"},
+	Short:   "List images and snapshots",
+	Long: `List images and disk snapshots owned by the account (AWS) or project (GCP),
+newest first. Snapshots backing an AMI are shown as part of the AMI.
+
+Examples:
+  cml vm snapshot list                 # everything
+  cml vm snapshot list web-01          # taken from web-01 by cml
+  cml vm snapshot list --managed --kind image
+  cml vm snapshot list -t reason=upgrade`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVMSnapshotList,
+}
+
+var vmSnapshotPruneCmd = &cobra.Command{
+	Use:   "prune [vm-name-or-id]",
+	Short: "Delete old images and snapshots",
+	Long: `Delete images and snapshots older than --older-than, optionally keeping
+the newest --keep per source VM, and limited to a VM or tags.
+
+Only images and snapshots created by cml (tagged cml-source) are considered
+unless --all is given. Deleting an AMI also deletes its backing snapshots.
+Matches are listed and you are asked to confirm unless --yes is given.
+
+Examples:
+  cml vm snapshot prune --older-than 30d
+  cml vm snapshot prune web-01 --keep 3
+  cml vm snapshot prune --older-than 2w -t reason=upgrade --dry-run
+  cml vm snapshot prune --older-than 90d --all --kind disk --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVMSnapshotPrune,
+}
+
+var (
+	vmSnapshotName   string
+	vmSnapshotKind   string
+	vmSnapshotReboot bool
+	vmSnapshotNoWait bool
+	vmSnapshotTags   []string
+
+	vmSnapshotFilterKind string
+	vmSnapshotManaged    bool
+	vmSnapshotAll        bool
+	vmSnapshotOlderThan  string
+	vmSnapshotKeep       int
+	vmSnapshotDryRun     bool
+	vmSnapshotYes        bool
+)
+
+const (
+	// snapshotPollInterval is how often snapshot progress is refreshed
+	snapshotPollInterval = 10 * time.Second
+
+	// snapshotPollRetries is how many lookups in a row may fail before
+	// waiting gives up
+	snapshotPollRetries = 5
+)
+
+func init() {
+	vmCmd.AddCommand(vmSnapshotCmd)
+	vmSnapshotCmd.AddCommand(vmSnapshotListCmd)
+	vmSnapshotCmd.AddCommand(vmSnapshotPruneCmd)
+
+	vmSnapshotCmd.Flags().StringVar(&vmSnapshotName, "name", "", "Image name, or snapshot name prefix (default: <vm>-<timestamp>)")
+	vmSnapshotCmd.Flags().StringVar(&vmSnapshotKind, "kind", "image", "What to create: image or disk")
+	vmSnapshotCmd.Flags().BoolVar(&vmSnapshotReboot, "reboot", false, "Reboot the instance for a consistent image (AWS)")
+	vmSnapshotCmd.Flags().BoolVar(&vmSnapshotNoWait, "no-wait", false, "Return without waiting for completion")
+	vmSnapshotCmd.Flags().StringArrayVarP(&vmSnapshotTags, "tag", "t", nil, "Extra tag (key=value, GCP: label)")
+
+	for _, c := range []*cobra.Command{vmSnapshotListCmd, vmSnapshotPruneCmd} {
+		c.Flags().StringVar(&vmSnapshotFilterKind, "kind", "", "Only images or disk snapshots (image, disk)")
+		c.Flags().StringArrayVarP(&vmSnapshotTags, "tag", "t", nil, "Filter by tag (key=value)")
+	}
+	vmSnapshotListCmd.Flags().BoolVar(&vmSnapshotManaged, "managed", false, "Only images and snapshots created by cml")
+
+	vmSnapshotPruneCmd.Flags().StringVar(&vmSnapshotOlderThan, "older-than", "", "Delete when older than this (e.g. 30d, 2w, 12h)")
+	vmSnapshotPruneCmd.Flags().IntVar(&vmSnapshotKeep, "keep", 0, "Always keep the newest N per source VM")
+	vmSnapshotPruneCmd.Flags().BoolVar(&vmSnapshotAll, "all", false, "Include images and snapshots not created by cml")
+	vmSnapshotPruneCmd.Flags().BoolVar(&vmSnapshotDryRun, "dry-run", false, "Only list what would be deleted")
+	vmSnapshotPruneCmd.Flags().BoolVarP(&vmSnapshotYes, "yes", "y", false, "Skip confirmation")
+}
+
+// getVMSnapshotter returns the context's VM provider as a VMSnapshotter
+func getVMSnapshotter(ctx context.Context) (provider.VMProvider, provider.VMSnapshotter, error) {
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshotter, ok := vmProvider.(provider.VMSnapshotter)
+	if !ok {
+		return nil, nil, provider.ErrNotSupported
+	}
+	return vmProvider, snapshotter, nil
+}
+
+// parseSnapshotKind validates --kind; empty is allowed when allowEmpty.
+func parseSnapshotKind(s string, allowEmpty bool) (types.SnapshotKind, error) {
+	switch types.SnapshotKind(s) {
+	case types.SnapshotKindImage, types.SnapshotKindDisk:
+		return types.SnapshotKind(s), nil
+	case "":
+		if allowEmpty {
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("invalid kind %q (use image or disk)", s)
+}
+
+// parseKeyValues parses repeated key=value flags
+func parseKeyValues(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid tag %q (want key=value)", p)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+func runVMSnapshot(cmd *cobra.Command, args []string) error {
+	// Ctrl+C stops waiting; the snapshot itself carries on
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	kind, err := parseSnapshotKind(vmSnapshotKind, false)
+	if err != nil {
+		return err
+	}
+	tags, err := parseKeyValues(vmSnapshotTags)
+	if err != nil {
+		return err
+	}
+
+	vmProvider, snapshotter, err := getVMSnapshotter(ctx)
+	if err != nil {
+		return err
+	}
+	vm, err := vmProvider.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	name := vmSnapshotName
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", vmLabel(vm), time.Now().Format("20060102-150405"))
+	}
+
+	snaps, err := snapshotter.CreateSnapshot(ctx, vm, &provider.SnapshotOptions{
+		Name:   name,
+		Kind:   kind,
+		Reboot: vmSnapshotReboot,
+		Tags:   tags,
+	})
+	for _, s := range snaps {
+		fmt.Printf("Creating %s %s (%s) of %s\n", s.Kind, ui.NameStyle.Render(s.Name), s.ID, vmLabel(vm))
+	}
+	if err != nil {
+		return err
+	}
+	if vmSnapshotNoWait || len(snaps) == 0 {
+		return nil
+	}
+	return waitForSnapshots(ctx, snapshotter, snaps)
+}
+
+// waitForSnapshots polls until every snapshot is available or failed,
+// printing a line whenever a snapshot's progress or state changes.
+func waitForSnapshots(ctx context.Context, snapshotter provider.VMSnapshotter, snaps []types.Snapshot) error {
+	start := time.Now()
+	last := make(map[string]string, len(snaps))
+	errs := make(map[string]int, len(snaps))
+	pending := snaps
+
+	for {
+		var still []types.Snapshot
+		for _, s := range pending {
+			cur, err := snapshotter.GetSnapshot(ctx, &s)
+			if err != nil {
+				// Freshly created resources can take a moment to become visible
+				if errs[s.ID]++; errs[s.ID] >= snapshotPollRetries && ctx.Err() == nil {
+					return err
+				}
+				still = append(still, s)
+				continue
+			}
+			errs[s.ID] = 0
+
+			status := string(cur.State)
+			if cur.Progress >= 0 && !cur.IsDone() {
+				status = fmt.Sprintf("%s %d%%", cur.State, cur.Progress)
+			}
+			if last[cur.ID] != status {
+				last[cur.ID] = status
+				elapsed := time.Since(start).Round(time.Second)
+				switch cur.State {
+				case types.SnapshotStateAvailable:
+					fmt.Printf("%s %s available after %s\n", ui.RunningStyle.Render("✓"), cur.ID, elapsed)
+				case types.SnapshotStateFailed:
+					fmt.Printf("%s %s failed after %s\n", ui.StoppedStyle.Render("✗"), cur.ID, elapsed)
+				default:
+					fmt.Printf("  %s %s %s\n", cur.ID, status, ui.MutedStyle.Render(elapsed.String()))
+				}
+			}
+
+			if !cur.IsDone() {
+				still = append(still, *cur)
+			} else if cur.State == types.SnapshotStateFailed {
+				return fmt.Errorf("%s %s failed", cur.Kind, cur.ID)
+			}
+		}
+		if len(still) == 0 {
+			return nil
+		}
+		pending = still
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopped waiting; creation continues in the background")
+			return nil
+		case <-time.After(snapshotPollInterval):
+		}
+	}
+}
+
+// snapshotFilterFromFlags builds a SnapshotFilter from args and flags
+func snapshotFilterFromFlags(ctx context.Context, vmProvider provider.VMProvider, args []string, managed bool) (*provider.SnapshotFilter, error) {
+	kind, err := parseSnapshotKind(vmSnapshotFilterKind, true)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := parseKeyValues(vmSnapshotTags)
+	if err != nil {
+		return nil, err
+	}
+	filter := &provider.SnapshotFilter{Kind: kind, Tags: tags, Managed: managed}
+	if len(args) > 0 {
+		vm, err := vmProvider.Get(ctx, args[0])
+		if err != nil {
+			return nil, err
+		}
+		filter.SourceVM = vm.ID
+	}
+	return filter, nil
+}
+
+func runVMSnapshotList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	vmProvider, snapshotter, err := getVMSnapshotter(ctx)
+	if err != nil {
+		return err
+	}
+	filter, err := snapshotFilterFromFlags(ctx, vmProvider, args, vmSnapshotManaged)
+	if err != nil {
+		return err
+	}
+
+	snaps, err := snapshotter.ListSnapshots(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		fmt.Println("No images or snapshots found")
+		return nil
+	}
+	printSnapshotTable(snaps)
+	return nil
+}
+
+func runVMSnapshotPrune(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if vmSnapshotOlderThan == "" && vmSnapshotKeep == 0 {
+		return fmt.Errorf("specify --older-than and/or --keep")
+	}
+	var maxAge time.Duration
+	if vmSnapshotOlderThan != "" {
+		d, err := query.ParseDuration(vmSnapshotOlderThan)
+		if err != nil {
+			return err
+		}
+		maxAge = d
+	}
+	if vmSnapshotKeep < 0 {
+		return fmt.Errorf("--keep must not be negative")
+	}
+
+	vmProvider, snapshotter, err := getVMSnapshotter(ctx)
+	if err != nil {
+		return err
+	}
+	filter, err := snapshotFilterFromFlags(ctx, vmProvider, args, !vmSnapshotAll)
+	if err != nil {
+		return err
+	}
+
+	snaps, err := snapshotter.ListSnapshots(ctx, filter)
+	if err != nil {
+		return err
+	}
+	doomed := selectSnapshotsToPrune(snaps, maxAge, vmSnapshotKeep, time.Now())
+	if len(doomed) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	printSnapshotTable(doomed)
+	if vmSnapshotDryRun {
+		fmt.Printf("Would delete %d image(s)/snapshot(s)\n", len(doomed))
+		return nil
+	}
+
+	if !vmSnapshotYes && !confirm(fmt.Sprintf("\nDelete %d image(s)/snapshot(s)? This cannot be undone. [y/N]: ", len(doomed))) {
+		fmt.Println("Prune cancelled")
+		return nil
+	}
+
+	failed := 0
+	for i := range doomed {
+		s := &doomed[i]
+		if err := snapshotter.DeleteSnapshot(ctx, s); err != nil {
+			fmt.Printf("%s %s: %v\n", ui.StoppedStyle.Render("✗"), s.ID, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s Deleted %s %s\n", ui.RunningStyle.Render("✓"), s.Kind, s.ID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d", failed, len(doomed))
+	}
+	return nil
+}
+
+// selectSnapshotsToPrune returns the snapshots to delete from snaps (newest
+// first): those older than maxAge (if set), skipping the newest keep per
+// source VM and kind. Snapshots still in progress are never pruned.
+func selectSnapshotsToPrune(snaps []types.Snapshot, maxAge time.Duration, keep int, now time.Time) []types.Snapshot {
+	seen := make(map[string]int)
+	var out []types.Snapshot
+	for _, s := range snaps {
+		if !s.IsDone() {
+			continue
+		}
+		group := string(s.Kind) + "/" + s.SourceVM
+		seen[group]++
+		if seen[group] <= keep {
+			continue
+		}
+		if maxAge > 0 && now.Sub(s.CreatedAt) < maxAge {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// printSnapshotTable prints images and snapshots in a table format
+func printSnapshotTable(snaps []types.Snapshot) {
+	headers := []string{"ID", "Name", "Kind", "State", "Size", "Source VM", "Created"}
+	widths := []int{24, 36, 5, 10, 7, 20, 16}
+	rows := make([][]string, 0, len(snaps))
+	for _, s := range snaps {
+		created := ""
+		if !s.CreatedAt.IsZero() {
+			created = s.CreatedAt.Local().Format("2006-01-02 15:04")
+		}
+		state := string(s.State)
+		if s.State == types.SnapshotStatePending && s.Progress >= 0 {
+			state = fmt.Sprintf("%d%%", s.Progress)
+		}
+		size := ""
+		if s.SizeGB > 0 {
+			size = fmt.Sprintf("%d GB", s.SizeGB)
+		}
+		rows = append(rows, []string{s.ID, s.Name, string(s.Kind), state, size, s.SourceVM, created})
+	}
+	renderSimpleTable(headers, widths, rows)
+	fmt.Printf("  %d images/snapshots\n", len(snaps))
+}
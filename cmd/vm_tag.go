@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vietdv277/cumulus/internal/ui"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/query"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+var vmTagCmd = &cobra.Command{
+	Use:   "tag [name-or-id] <add|remove|list> [key=value... | key...]",
+	Short: "Manage VM tags (GCP: labels)",
+	Long: `Add, remove or list tags on a VM (AWS: EC2 tags; GCP: instance labels).
+
+With --where instead of a VM name, the action applies to every VM matching
+the expression (all states unless --state is given; see 'cml vm list --help'
+for the syntax). Bulk changes list the matching VMs and ask for confirmation
+unless --yes is given.
+
+GCP label keys and values must be lowercase letters, digits, '-' or '_'.
+
+Examples:
+  cml vm tag web-01                          # list tags
+  cml vm tag web-01 add team=payments env=prod
+  cml vm tag web-01 remove temp owner
+  cml vm tag -w 'name=~"web-.*" && !tags.team=payments' add team=payments
+  cml vm tag -w 'tags.owner=alice' remove owner --yes
+  cml vm tag -w 'type=~"m5.*"' list`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runVMTag,
+}
+
+var (
+	vmTagWhere string
+	vmTagState string
+	vmTagYes   bool
+)
+
+func init() {
+	vmCmd.AddCommand(vmTagCmd)
+
+	vmTagCmd.Flags().StringVarP(&vmTagWhere, "where", "w", "", "Apply to every VM matching this expression")
+	vmTagCmd.Flags().StringVarP(&vmTagState, "state", "s", "all", "Limit --where to VMs in this state")
+	vmTagCmd.Flags().BoolVarP(&vmTagYes, "yes", "y", false, "Skip confirmation for bulk changes")
+}
+
+func runVMTag(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	bulk := vmTagWhere != ""
+	var name string
+	if !bulk {
+		if len(args) == 0 {
+			return fmt.Errorf("name a VM or use --where")
+		}
+		name, args = args[0], args[1:]
+	}
+
+	action := "list"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	var set map[string]string
+	switch action {
+	case "list", "ls":
+		if len(args) > 0 {
+			return fmt.Errorf("list takes no arguments")
+		}
+	case "add", "set":
+		if len(args) == 0 {
+			return fmt.Errorf("add needs at least one key=value")
+		}
+		set = make(map[string]string, len(args))
+		for _, a := range args {
+			k, v, ok := strings.Cut(a, "=")
+			if !ok || k == "" {
+				return fmt.Errorf("invalid tag %q (want key=value)", a)
+			}
+			set[k] = v
+		}
+	case "remove", "rm":
+		if len(args) == 0 {
+			return fmt.Errorf("remove needs at least one key")
+		}
+		for _, a := range args {
+			if strings.Contains(a, "=") {
+				return fmt.Errorf("remove takes keys, not key=value: %q", a)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown action %q (use add, remove or list)", action)
+	}
+
+	vmProvider, err := getVMProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	var vms []types.VM
+	if bulk {
+		q, err := query.Parse(vmTagWhere)
+		if err != nil {
+			return err
+		}
+		matched, err := vmProvider.List(ctx, &provider.VMFilter{State: vmTagState, Where: q})
+		if err != nil {
+			return err
+		}
+		vms = q.Filter(matched)
+	} else {
+		vm, err := vmProvider.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		vms = []types.VM{*vm}
+	}
+
+	if len(vms) == 0 {
+		fmt.Println("No VMs found")
+		return nil
+	}
+
+	if action == "list" || action == "ls" {
+		for i := range vms {
+			if bulk {
+				fmt.Println(ui.NameStyle.Render(vmLabel(&vms[i])))
+			}
+			printVMTags(vms[i].Tags, bulk)
+		}
+		return nil
+	}
+
+	tagger, ok := vmProvider.(provider.VMTagger)
+	if !ok {
+		return provider.ErrNotSupported
+	}
+
+	if bulk && !vmTagYes {
+		for i := range vms {
+			fmt.Printf("  %s  %s\n", ui.NameStyle.Render(vmLabel(&vms[i])), ui.MutedStyle.Render(vms[i].ID))
+		}
+		verb := "Add " + strings.Join(args, " ") + " to"
+		if set == nil {
+			verb = "Remove " + strings.Join(args, " ") + " from"
+		}
+		if !confirm(fmt.Sprintf("\n%s %d VM(s)? [y/N]: ", verb, len(vms))) {
+			fmt.Println("Tagging cancelled")
+			return nil
+		}
+	}
+
+	failed := 0
+	for i := range vms {
+		vm := &vms[i]
+		if set != nil {
+			err = tagger.SetTags(ctx, vm, set)
+		} else {
+			err = tagger.RemoveTags(ctx, vm, args)
+		}
+		if err != nil {
+			fmt.Printf("%s %s: %v\n", ui.StoppedStyle.Render("✗"), vmLabel(vm), err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s %s\n", ui.RunningStyle.Render("✓"), vmLabel(vm))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to update tags on %d of %d VMs", failed, len(vms))
+	}
+	return nil
+}
+
+// printVMTags prints tags as aligned key/value lines sorted by key.
+func printVMTags(tags map[string]string, indent bool) {
+	prefix := ""
+	if indent {
+		prefix = "  "
+	}
+	if len(tags) == 0 {
+		fmt.Println(prefix + ui.MutedStyle.Render("(no tags)"))
+		return
+	}
+
+	keys := make([]string, 0, len(tags))
+	width := 0
+	for k := range tags {
+		keys = append(keys, k)
+		if len(k) > width {
+			width = len(k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Printf("%s%s  %s\n", prefix, ui.MutedStyle.Render(padRightVM(k, width)), tags[k])
+	}
+}
@@ -7,13 +7,29 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/vietdv277/cumulus/internal/aws"
+	"github.com/vietdv277/cumulus/internal/config"
+	gcpinternal "github.com/vietdv277/cumulus/internal/gcp"
 	"github.com/vietdv277/cumulus/internal/ui"
+	pkgtypes "github.com/vietdv277/cumulus/pkg/types"
 )
 
+var vpcContextFlag string
+
+// vpcClient is the subset of the AWS and GCP clients used by the vpc commands.
+type vpcClient interface {
+	ListVPCs() ([]pkgtypes.VPC, error)
+	DescribeVPC(vpcID string) (*pkgtypes.VPC, error)
+	ListSubnets(vpcID string) ([]pkgtypes.Subnet, error)
+}
+
 var vpcCmd = &cobra.Command{
 	Use:   "vpc",
 	Short: "Manage VPCs",
-	Long:  `Perform various operations on VPCs such as listing and describing VPCs and their subnets.`,
+	Long: `Perform various operations on VPCs such as listing and describing VPCs and their subnets.
+
+Works against AWS VPCs and GCP VPC networks. GCP contexts are used when the
+current context (or --context) is a GCP context; networks shared from a
+shared-VPC host project are listed alongside the project's own networks.`,
 }
 
 var vpcLsCmd = &cobra.Command{
@@ -23,7 +39,8 @@ var vpcLsCmd = &cobra.Command{
 
 Examples:
   cml vpc ls              # List all VPCs
-  cml vpc ls -p prod      # List VPCs using production profile`,
+  cml vpc ls -p prod      # List VPCs using production profile
+  cml vpc ls -c gcp-prod  # List GCP networks, including shared VPCs`,
 	RunE: runVPCList,
 }
 
@@ -32,10 +49,12 @@ var vpcDescribeCmd = &cobra.Command{
 	Short: "Show detailed VPC information",
 	Long: `Show detailed information about a VPC including its subnets.
 If no VPC ID is provided, an interactive selector will be shown.
+GCP networks can be referenced by name, numeric ID, or host-project/name.
 
 Examples:
   cml vpc describe                  # Interactive VPC selector
-  cml vpc describe vpc-12345678     # Describe specific VPC`,
+  cml vpc describe vpc-12345678     # Describe specific VPC
+  cml vpc describe shared-net -c gcp-prod`,
 	RunE: runVPCDescribe,
 }
 
@@ -43,11 +62,14 @@ var vpcSubnetsCmd = &cobra.Command{
 	Use:   "subnets [vpc-id]",
 	Short: "List subnets in a VPC",
 	Long: `List all subnets in a VPC with their CIDR, AZ, and availability.
+For GCP networks, shows each subnetwork's region, secondary ranges
+(e.g. GKE pod and service ranges), and Private Google Access.
 If no VPC ID is provided, an interactive selector will be shown.
 
 Examples:
   cml vpc subnets                   # Interactive VPC selector
-  cml vpc subnets vpc-12345678      # List subnets in specific VPC`,
+  cml vpc subnets vpc-12345678      # List subnets in specific VPC
+  cml vpc subnets shared-net -c gcp-prod`,
 	RunE: runVPCSubnets,
 }
 
@@ -57,16 +79,76 @@ func init() {
 	vpcCmd.AddCommand(vpcLsCmd)
 	vpcCmd.AddCommand(vpcDescribeCmd)
 	vpcCmd.AddCommand(vpcSubnetsCmd)
+
+	vpcCmd.PersistentFlags().StringVarP(&vpcContextFlag, "context", "c", "", "Use specific context")
 }
 
-func runVPCList(cmd *cobra.Command, args []string) error {
-	client, err := aws.NewClient(
-		context.Background(),
-		aws.WithProfile(GetProfile()),
-		aws.WithRegion(GetRegion()),
+// getVPCClient returns the client for the vpc commands. --context selects a
+// context explicitly; otherwise a GCP current context is used unless
+// --profile was given, and everything else falls back to the AWS profile.
+func getVPCClient(ctx context.Context) (vpcClient, bool, error) {
+	ctxConfig, err := resolveVPCContext()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ctxConfig != nil && ctxConfig.Provider == "gcp" {
+		client, err := gcpinternal.NewClient(ctx,
+			gcpinternal.WithProject(ctxConfig.Project),
+			gcpinternal.WithRegion(ctxConfig.Region),
+		)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create GCP client: %w", err)
+		}
+		return client, true, nil
+	}
+
+	profileName, region := GetProfile(), GetRegion()
+	if ctxConfig != nil && vpcContextFlag != "" {
+		profileName, region = ctxConfig.Profile, ctxConfig.Region
+	}
+
+	client, err := aws.NewClient(ctx,
+		aws.WithProfile(profileName),
+		aws.WithRegion(region),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create AWS client: %w", err)
+		return nil, false, fmt.Errorf("failed to create AWS client: %w", err)
+	}
+	return client, false, nil
+}
+
+// resolveVPCContext returns the context named by --context, or the current
+// context when --profile was not set. A nil context means legacy AWS mode.
+func resolveVPCContext() (*config.Context, error) {
+	if vpcContextFlag != "" {
+		cfg, err := config.LoadCMLConfig()
+		if err != nil {
+			return nil, err
+		}
+		ctxConfig := cfg.Contexts[vpcContextFlag]
+		if ctxConfig == nil {
+			return nil, fmt.Errorf("context %q not found", vpcContextFlag)
+		}
+		return ctxConfig, nil
+	}
+
+	if rootCmd.PersistentFlags().Changed("profile") {
+		return nil, nil
+	}
+
+	ctxConfig, _, err := config.GetCurrentContext()
+	if err != nil {
+		// Unreadable or missing config keeps the legacy profile behaviour
+		return nil, nil
+	}
+	return ctxConfig, nil
+}
+
+func runVPCList(cmd *cobra.Command, args []string) error {
+	client, isGCP, err := getVPCClient(context.Background())
+	if err != nil {
+		return err
 	}
 
 	vpcs, err := client.ListVPCs()
@@ -79,18 +161,18 @@ func runVPCList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if isGCP {
+		ui.PrintGCPVPCTable(vpcs)
+		return nil
+	}
 	ui.PrintVPCTable(vpcs)
 	return nil
 }
 
 func runVPCDescribe(cmd *cobra.Command, args []string) error {
-	client, err := aws.NewClient(
-		context.Background(),
-		aws.WithProfile(GetProfile()),
-		aws.WithRegion(GetRegion()),
-	)
+	client, isGCP, err := getVPCClient(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to create AWS client: %w", err)
+		return err
 	}
 
 	var vpcID string
@@ -123,12 +205,20 @@ func runVPCDescribe(cmd *cobra.Command, args []string) error {
 
 	// Print VPC details
 	fmt.Println()
-	fmt.Printf("VPC: %s\n", vpc.ID)
-	fmt.Printf("  Name:     %s\n", vpc.Name)
-	fmt.Printf("  CIDR:     %s\n", vpc.CIDR)
-	fmt.Printf("  State:    %s\n", vpc.State)
-	fmt.Printf("  Default:  %v\n", vpc.IsDefault)
-	fmt.Printf("  Owner:    %s\n", vpc.OwnerID)
+	if isGCP {
+		fmt.Printf("Network: %s\n", vpc.Name)
+		fmt.Printf("  ID:       %s\n", vpc.ID)
+		fmt.Printf("  Project:  %s\n", vpc.OwnerID)
+		fmt.Printf("  Default:  %v\n", vpc.IsDefault)
+		fmt.Printf("  Shared:   %v\n", vpc.Shared)
+	} else {
+		fmt.Printf("VPC: %s\n", vpc.ID)
+		fmt.Printf("  Name:     %s\n", vpc.Name)
+		fmt.Printf("  CIDR:     %s\n", vpc.CIDR)
+		fmt.Printf("  State:    %s\n", vpc.State)
+		fmt.Printf("  Default:  %v\n", vpc.IsDefault)
+		fmt.Printf("  Owner:    %s\n", vpc.OwnerID)
+	}
 	fmt.Println()
 
 	// Get and print subnets
@@ -137,24 +227,24 @@ func runVPCDescribe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to list subnets: %w", err)
 	}
 
-	if len(subnets) > 0 {
-		fmt.Println("Subnets:")
-		ui.PrintSubnetTable(subnets)
-	} else {
+	if len(subnets) == 0 {
 		fmt.Println("No subnets found in this VPC")
+		return nil
 	}
 
+	fmt.Println("Subnets:")
+	if isGCP {
+		ui.PrintGCPSubnetTable(subnets)
+		return nil
+	}
+	ui.PrintSubnetTable(subnets)
 	return nil
 }
 
 func runVPCSubnets(cmd *cobra.Command, args []string) error {
-	client, err := aws.NewClient(
-		context.Background(),
-		aws.WithProfile(GetProfile()),
-		aws.WithRegion(GetRegion()),
-	)
+	client, isGCP, err := getVPCClient(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to create AWS client: %w", err)
+		return err
 	}
 
 	var vpcID string
@@ -186,6 +276,10 @@ func runVPCSubnets(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if isGCP {
+		ui.PrintGCPSubnetTable(subnets)
+		return nil
+	}
 	ui.PrintSubnetTable(subnets)
 	return nil
 }
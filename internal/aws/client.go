@@ -1,5 +1,5 @@
 // Package aws implements the CloudProvider interfaces for Amazon Web Services.
-// Sub-clients (EC2, ASG, ELBv2, RDS, S3, EKS) are constructed lazily on first
+// Sub-clients (EC2, ASG, ELBv2, RDS, S3, EKS, SSM) are constructed lazily on first
 // access so short-lived CLI commands only pay for the services they use.
 package aws
 
@@ -16,6 +16,7 @@ import (
 	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 )
 
 // Client wraps AWS SDK clients. Sub-clients are constructed lazily on first
@@ -43,6 +44,9 @@ type Client struct {
 
 	eksOnce   sync.Once
 	eksClient *eks.Client
+
+	ssmOnce   sync.Once
+	ssmClient *ssm.Client
 }
 
 // ClientOption allows customizing the AWS Client
@@ -128,6 +132,12 @@ func (c *Client) EKS() *eks.Client {
 	return c.eksClient
 }
 
+// SSM returns the lazily-constructed Systems Manager client.
+func (c *Client) SSM() *ssm.Client {
+	c.ssmOnce.Do(func() { c.ssmClient = ssm.NewFromConfig(c.cfg) })
+	return c.ssmClient
+}
+
 // Config returns the underlying AWS config
 func (c *Client) Config() awsconfig.Config {
 	return c.cfg
@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// EC2 refreshes console output only every few minutes, so polling faster
+// than this just repeats requests.
+const consolePollInterval = 10 * time.Second
+
+// ConsoleOutput writes the instance's console output from GetConsoleOutput.
+// EC2 only exposes a recent window (64 KB) with no offsets, so --follow
+// prints whatever follows the previously seen output.
+func (p *AWSVMProvider) ConsoleOutput(ctx context.Context, vm *types.VM, follow bool, w io.Writer) error {
+	prev, err := p.consoleOutput(ctx, vm.ID)
+	if err != nil {
+		return err
+	}
+	_, _ = io.WriteString(w, prev)
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(consolePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := p.consoleOutput(ctx, vm.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		_, _ = io.WriteString(w, appendedOutput(prev, cur))
+		prev = cur
+	}
+}
+
+// consoleOutput fetches and decodes the latest console output. Latest is
+// only supported on Nitro instances, so Xen instances fall back to the
+// output captured at the last boot.
+func (p *AWSVMProvider) consoleOutput(ctx context.Context, instanceID string) (string, error) {
+	out, err := p.client.EC2().GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+		Latest:     aws.Bool(true),
+	})
+	if err != nil {
+		out, err = p.client.EC2().GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{
+			InstanceId: aws.String(instanceID),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to get console output: %w", err)
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(aws.ToString(out.Output))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode console output: %w", err)
+	}
+	return string(data), nil
+}
+
+// appendedOutput returns the part of cur that follows prev. When the window
+// has slid, the last line(s) of prev are located in cur; if they can't be
+// found, cur is returned whole.
+func appendedOutput(prev, cur string) string {
+	if strings.HasPrefix(cur, prev) {
+		return cur[len(prev):]
+	}
+	tail := prev
+	if len(tail) > 512 {
+		tail = tail[len(tail)-512:]
+	}
+	if i := strings.LastIndex(cur, tail); i >= 0 {
+		return cur[i+len(tail):]
+	}
+	return cur
+}
+
+// Screenshot returns a JPEG of the instance console via GetConsoleScreenshot.
+func (p *AWSVMProvider) Screenshot(ctx context.Context, vm *types.VM) ([]byte, error) {
+	out, err := p.client.EC2().GetConsoleScreenshot(ctx, &ec2.GetConsoleScreenshotInput{
+		InstanceId: aws.String(vm.ID),
+		WakeUp:     aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get console screenshot: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(aws.ToString(out.ImageData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode console screenshot: %w", err)
+	}
+	return data, nil
+}
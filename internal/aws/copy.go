@@ -0,0 +1,191 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/vietdv277/cumulus/internal/shell"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// Transfers are staged under this prefix in the staging bucket and deleted
+// once the copy finishes. The presigned URL is part of the Run Command
+// parameters, which SSM keeps in its command history, so it expires as soon
+// as possible: S3 checks the expiry when a request starts, so it only has to
+// cover delivering the command to the instance, not the transfer itself.
+const (
+	copyStagingPrefix = "cml-transfer/"
+	copyURLExpiry     = 2 * time.Minute
+)
+
+// CopyTo uploads localPath to remotePath on the instance. The file is staged
+// in the S3 bucket from opts and fetched by the instance with a presigned
+// URL through SSM Run Command, so the instance needs neither SSH nor S3
+// permissions — only curl or wget and outbound HTTPS to S3.
+func (p *AWSVMProvider) CopyTo(ctx context.Context, vm *types.VM, localPath, remotePath string, opts *provider.CopyOptions) error {
+	bucket, err := copyBucket(opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	key, err := stagingKey(path.Base(remotePath))
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.client.S3().PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	}); err != nil {
+		return fmt.Errorf("failed to stage file in s3://%s: %w", bucket, err)
+	}
+	defer p.deleteStaged(bucket, key)
+
+	req, err := s3.NewPresignClient(p.client.S3()).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(copyURLExpiry))
+	if err != nil {
+		return fmt.Errorf("failed to presign download: %w", err)
+	}
+
+	dst, url := shell.Quote(remotePath), shell.Quote(req.URL)
+	script := fmt.Sprintf(`if command -v curl >/dev/null 2>&1; then curl -fsS -o %s %s; else wget -q -O %s %s; fi`,
+		dst, url, dst, url)
+	return p.runTransfer(ctx, vm, script)
+}
+
+// CopyFrom downloads remotePath on the instance to localPath. The instance
+// uploads the file to a presigned S3 URL in the staging bucket, from which
+// it is downloaded and then deleted.
+func (p *AWSVMProvider) CopyFrom(ctx context.Context, vm *types.VM, remotePath, localPath string, opts *provider.CopyOptions) error {
+	bucket, err := copyBucket(opts)
+	if err != nil {
+		return err
+	}
+
+	key, err := stagingKey(path.Base(remotePath))
+	if err != nil {
+		return err
+	}
+
+	req, err := s3.NewPresignClient(p.client.S3()).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(copyURLExpiry))
+	if err != nil {
+		return fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	src, url := shell.Quote(remotePath), shell.Quote(req.URL)
+	script := fmt.Sprintf(`test -f %s || { echo "not a regular file" >&2; exit 1; }; `+
+		`if command -v curl >/dev/null 2>&1; then curl -fsS -T %s %s; else wget -q -O /dev/null --method=PUT --body-file=%s %s; fi`,
+		src, src, url, src, url)
+	defer p.deleteStaged(bucket, key)
+	if err := p.runTransfer(ctx, vm, script); err != nil {
+		return err
+	}
+
+	out, err := p.client.S3().GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch staged file from s3://%s: %w", bucket, err)
+	}
+	defer func() { _ = out.Body.Close() }()
+
+	// Download next to localPath and rename on success, so a failed
+	// transfer leaves an existing file untouched
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(localPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".cml-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := io.Copy(f, out.Body); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", localPath, err)
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, localPath)
+}
+
+// runTransfer runs the transfer script on the instance and turns a non-zero
+// exit into an error carrying the script's stderr.
+func (p *AWSVMProvider) runTransfer(ctx context.Context, vm *types.VM, script string) error {
+	var stderr bytes.Buffer
+	code, err := p.Exec(ctx, vm, script, io.Discard, &stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = fmt.Sprintf("exit code %d", code)
+		}
+		return fmt.Errorf("transfer on %s failed: %s", vm.ID, msg)
+	}
+	return nil
+}
+
+// deleteStaged removes a staged object, even after the command context has
+// been cancelled.
+func (p *AWSVMProvider) deleteStaged(bucket, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := p.client.S3().DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete staged s3://%s/%s: %v\n", bucket, key, err)
+	}
+}
+
+func copyBucket(opts *provider.CopyOptions) (string, error) {
+	if opts == nil || opts.StagingBucket == "" {
+		return "", fmt.Errorf("no staging bucket: set one with 'cml use update <context> --transfer-bucket <bucket>' or pass --bucket")
+	}
+	return opts.StagingBucket, nil
+}
+
+// stagingKey returns a unique object key for one transfer.
+func stagingKey(name string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return copyStagingPrefix + hex.EncodeToString(b) + "/" + name, nil
+}
@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// Create launches instances from an EC2 launch template. Template may be a
+// name or an lt- ID; Version defaults to the template's default version.
+//
+// Name and extra tags are applied with CreateTags after launch rather than as
+// RunInstances tag specifications, which would replace the template's own
+// instance tags instead of adding to them.
+func (p *AWSVMProvider) Create(ctx context.Context, opts *provider.VMCreateOptions) ([]types.VM, error) {
+	if opts == nil || opts.Template == "" {
+		return nil, fmt.Errorf("launch template required")
+	}
+	count := opts.Count
+	if count < 1 {
+		count = 1
+	}
+
+	spec := &ec2types.LaunchTemplateSpecification{
+		Version: aws.String("$Default"),
+	}
+	if opts.Version != "" {
+		spec.Version = aws.String(opts.Version)
+	}
+	if strings.HasPrefix(opts.Template, "lt-") {
+		spec.LaunchTemplateId = aws.String(opts.Template)
+	} else {
+		spec.LaunchTemplateName = aws.String(opts.Template)
+	}
+
+	output, err := p.client.EC2().RunInstances(ctx, &ec2.RunInstancesInput{
+		LaunchTemplate: spec,
+		MinCount:       aws.Int32(int32(count)),
+		MaxCount:       aws.Int32(int32(count)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run instances: %w", err)
+	}
+
+	vms := make([]types.VM, 0, len(output.Instances))
+	for i, inst := range output.Instances {
+		vm := ec2ToVM(inst)
+
+		tags := make(map[string]string, len(opts.Tags)+1)
+		for k, v := range opts.Tags {
+			tags[k] = v
+		}
+		if opts.Name != "" {
+			tags["Name"] = opts.Name
+			if count > 1 {
+				tags["Name"] = fmt.Sprintf("%s-%d", opts.Name, i+1)
+			}
+		}
+		if len(tags) > 0 {
+			if err := p.tagInstance(ctx, vm.ID, tags); err != nil {
+				return vms, err
+			}
+			for k, v := range tags {
+				vm.Tags[k] = v
+			}
+			vm.Name = vm.Tags["Name"]
+		}
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+// CheckTerminate refuses instances with termination protection
+// (DisableApiTermination) enabled.
+func (p *AWSVMProvider) CheckTerminate(ctx context.Context, vm *types.VM) error {
+	output, err := p.client.EC2().DescribeInstanceAttribute(ctx, &ec2.DescribeInstanceAttributeInput{
+		InstanceId: aws.String(vm.ID),
+		Attribute:  ec2types.InstanceAttributeNameDisableApiTermination,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check termination protection: %w", err)
+	}
+	if output.DisableApiTermination != nil && aws.ToBool(output.DisableApiTermination.Value) {
+		return fmt.Errorf("termination protection is enabled on %s; disable it first with 'aws ec2 modify-instance-attribute --instance-id %s --no-disable-api-termination'", vm.ID, vm.ID)
+	}
+	return nil
+}
+
+// Terminate terminates an EC2 instance
+func (p *AWSVMProvider) Terminate(ctx context.Context, vm *types.VM) error {
+	_, err := p.client.EC2().TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{vm.ID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to terminate instance: %w", err)
+	}
+	return nil
+}
@@ -2,11 +2,7 @@ package aws
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
-	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/service/rds"
@@ -93,37 +89,18 @@ func (p *AWSDBProvider) Connect(ctx context.Context, nameOrID string, opts *prov
 		localPort = db.Port
 	}
 
-	params := map[string][]string{
-		"host":            {db.Endpoint},
-		"portNumber":      {strconv.Itoa(db.Port)},
-		"localPortNumber": {strconv.Itoa(localPort)},
-	}
-	paramsJSON, err := json.Marshal(params)
+	ln, err := p.client.ListenSSM(ctx, fmt.Sprintf("127.0.0.1:%d", localPort), SSMTarget{
+		InstanceID: opts.Via,
+		Host:       db.Endpoint,
+		Port:       db.Port,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal parameters: %w", err)
-	}
-
-	args := []string{
-		"ssm", "start-session",
-		"--target", opts.Via,
-		"--document-name", "AWS-StartPortForwardingSessionToRemoteHost",
-		"--parameters", string(paramsJSON),
-	}
-	if p.profile != "" {
-		args = append(args, "--profile", p.profile)
-	}
-	if p.region != "" {
-		args = append(args, "--region", p.region)
+		return err
 	}
 
-	fmt.Printf("Tunneling %s -> localhost:%d via %s\n", db.Endpoint, localPort, opts.Via)
+	fmt.Printf("Tunneling %s -> %s via %s\n", db.Endpoint, ln.Addr(), opts.Via)
 	fmt.Println("Press Ctrl+C to close the tunnel")
-
-	ssmCmd := exec.Command("aws", args...)
-	ssmCmd.Stdin = os.Stdin
-	ssmCmd.Stdout = os.Stdout
-	ssmCmd.Stderr = os.Stderr
-	return ssmCmd.Run()
+	return ln.Wait()
 }
 
 // rdsToDatabase converts an RDS DBInstance to the unified Database type
@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// execPollInterval is how often GetCommandInvocation is polled.
+const execPollInterval = time.Second
+
+// Exec runs a shell command on the instance through SSM Run Command
+// (AWS-RunShellScript) and returns its exit code. Output is written as it
+// becomes available from GetCommandInvocation; SSM truncates each stream
+// at 24,000 characters.
+func (p *AWSVMProvider) Exec(ctx context.Context, vm *types.VM, command string, stdout, stderr io.Writer) (int, error) {
+	out, err := p.client.SSM().SendCommand(ctx, &ssm.SendCommandInput{
+		InstanceIds:  []string{vm.ID},
+		DocumentName: aws.String("AWS-RunShellScript"),
+		Parameters: map[string][]string{
+			"commands": {command},
+		},
+	})
+	if err != nil {
+		return -1, fmt.Errorf("failed to send command: %w", err)
+	}
+	commandID := aws.ToString(out.Command.CommandId)
+
+	var stdoutSeen, stderrSeen int
+	ticker := time.NewTicker(execPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_, _ = p.client.SSM().CancelCommand(context.Background(), &ssm.CancelCommandInput{
+				CommandId:   aws.String(commandID),
+				InstanceIds: []string{vm.ID},
+			})
+			return -1, ctx.Err()
+		case <-ticker.C:
+		}
+
+		inv, err := p.client.SSM().GetCommandInvocation(ctx, &ssm.GetCommandInvocationInput{
+			CommandId:  aws.String(commandID),
+			InstanceId: aws.String(vm.ID),
+		})
+		if err != nil {
+			// The invocation is created asynchronously after SendCommand
+			var notYet *ssmtypes.InvocationDoesNotExist
+			if errors.As(err, &notYet) {
+				continue
+			}
+			return -1, fmt.Errorf("failed to get command invocation: %w", err)
+		}
+
+		stdoutSeen = writeDelta(stdout, aws.ToString(inv.StandardOutputContent), stdoutSeen)
+		stderrSeen = writeDelta(stderr, aws.ToString(inv.StandardErrorContent), stderrSeen)
+
+		switch inv.Status {
+		case ssmtypes.CommandInvocationStatusPending,
+			ssmtypes.CommandInvocationStatusInProgress,
+			ssmtypes.CommandInvocationStatusDelayed:
+			continue
+		case ssmtypes.CommandInvocationStatusSuccess,
+			ssmtypes.CommandInvocationStatusFailed:
+			return int(inv.ResponseCode), nil
+		default:
+			detail := aws.ToString(inv.StatusDetails)
+			return -1, fmt.Errorf("command %s: %s", strings.ToLower(string(inv.Status)), detail)
+		}
+	}
+}
+
+// writeDelta writes the part of content past seen and returns the new length.
+func writeDelta(w io.Writer, content string, seen int) int {
+	if len(content) <= seen {
+		return seen
+	}
+	_, _ = io.WriteString(w, content[seen:])
+	return len(content)
+}
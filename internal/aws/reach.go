@@ -0,0 +1,398 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// ephemeralFrom and ephemeralTo bound the client ports return traffic is
+// sent to, which stateless network ACLs must allow.
+const (
+	ephemeralFrom = 1024
+	ephemeralTo   = 65535
+)
+
+// reachEnd holds what is known about one end of a reachability check
+type reachEnd struct {
+	label    string
+	ip       net.IP
+	inst     *ec2types.Instance // nil for a bare IP
+	groups   []ec2types.SecurityGroup
+	acl      *ec2types.NetworkAcl
+	routes   *ec2types.RouteTable
+	subnetID string
+}
+
+func (e *reachEnd) groupIDs() map[string]bool {
+	ids := make(map[string]bool)
+	if e.inst != nil {
+		for _, g := range e.inst.SecurityGroups {
+			ids[deref(g.GroupId)] = true
+		}
+	}
+	return ids
+}
+
+// Reach evaluates security groups, network ACLs and route tables for both
+// ends. Security groups are stateful; network ACLs are not, so the return
+// path to ephemeral ports is checked too. Prefix-list rules are reported but
+// not resolved.
+func (p *AWSVMProvider) Reach(ctx context.Context, src, dst provider.ReachEndpoint, protocol string, port int) (*types.ReachReport, error) {
+	report := &types.ReachReport{
+		Source:      reachLabel(src),
+		SourceIP:    src.IP,
+		Destination: reachLabel(dst),
+		DestIP:      dst.IP,
+		Protocol:    protocol,
+		Port:        port,
+	}
+
+	s, err := p.reachEnd(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	d, err := p.reachEnd(ctx, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.ip == nil {
+		report.Add("destination address", types.ReachDeny, "",
+			"the destination has no public IP, so it cannot be reached from outside the VPC")
+		return report, nil
+	}
+	fromInternet := s.inst == nil && !s.ip.IsPrivate()
+	sameSubnet := s.inst != nil && d.inst != nil && s.subnetID == d.subnetID
+
+	// Source side
+	if s.inst != nil {
+		report.Add(evalSecurityGroups("source security groups (egress)", s.groups, true, d, protocol, port))
+		report.Add(evalRoute("source route table", s, d.ip))
+		if !sameSubnet {
+			report.Add(evalNACL("source network ACL (outbound)", s.acl, true, d.ip, protocol, port, port))
+		}
+	} else {
+		report.Add("source", types.ReachInfo, "", fmt.Sprintf("%s is not an EC2 instance; its own firewall is not evaluated", s.label))
+	}
+
+	// Destination side
+	if d.inst != nil {
+		if fromInternet {
+			check, verdict, rule, detail := evalRoute("destination return route", d, s.ip)
+			if verdict == types.ReachAllow && !strings.Contains(rule, "igw-") {
+				verdict, detail = types.ReachDeny, "replies to the internet must route via an internet gateway"
+			}
+			report.Add(check, verdict, rule, detail)
+		}
+		if !sameSubnet {
+			report.Add(evalNACL("destination network ACL (inbound)", d.acl, false, s.ip, protocol, port, port))
+		}
+		report.Add(evalSecurityGroups("destination security groups (ingress)", d.groups, false, s, protocol, port))
+	} else {
+		report.Add("destination", types.ReachInfo, "", fmt.Sprintf("%s is not an EC2 instance; its own firewall is not evaluated", d.label))
+	}
+
+	// Return path through stateless network ACLs
+	if !sameSubnet {
+		if d.inst != nil {
+			report.Add(evalNACL("destination network ACL (return, outbound)", d.acl, true, s.ip, protocol, ephemeralFrom, ephemeralTo))
+		}
+		if s.inst != nil {
+			report.Add(evalNACL("source network ACL (return, inbound)", s.acl, false, d.ip, protocol, ephemeralFrom, ephemeralTo))
+		}
+	}
+
+	return report, nil
+}
+
+// reachEnd loads the security groups, network ACL and route table of an
+// endpoint's instance.
+func (p *AWSVMProvider) reachEnd(ctx context.Context, ep provider.ReachEndpoint) (*reachEnd, error) {
+	e := &reachEnd{label: reachLabel(ep), ip: net.ParseIP(ep.IP)}
+	if ep.VM == nil {
+		return e, nil
+	}
+	inst, ok := ep.VM.Raw.(ec2types.Instance)
+	if !ok {
+		return nil, fmt.Errorf("no instance details for %s", e.label)
+	}
+	e.inst = &inst
+	e.subnetID = deref(inst.SubnetId)
+
+	var ids []string
+	for _, g := range inst.SecurityGroups {
+		ids = append(ids, deref(g.GroupId))
+	}
+	if len(ids) > 0 {
+		out, err := p.client.EC2().DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{GroupIds: ids})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe security groups: %w", err)
+		}
+		e.groups = out.SecurityGroups
+	}
+
+	acls, err := p.client.EC2().DescribeNetworkAcls(ctx, &ec2.DescribeNetworkAclsInput{
+		Filters: []ec2types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{e.subnetID}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe network ACLs: %w", err)
+	}
+	if len(acls.NetworkAcls) > 0 {
+		e.acl = &acls.NetworkAcls[0]
+	}
+
+	// Subnets without an explicit association use the VPC's main route table
+	rts, err := p.client.EC2().DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("association.subnet-id"), Values: []string{e.subnetID}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables: %w", err)
+	}
+	if len(rts.RouteTables) == 0 {
+		rts, err = p.client.EC2().DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: []ec2types.Filter{
+				{Name: aws.String("vpc-id"), Values: []string{deref(inst.VpcId)}},
+				{Name: aws.String("association.main"), Values: []string{"true"}},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe route tables: %w", err)
+		}
+	}
+	if len(rts.RouteTables) > 0 {
+		e.routes = &rts.RouteTables[0]
+	}
+	return e, nil
+}
+
+// evalSecurityGroups checks whether any rule in groups allows the traffic.
+// Security groups only allow, so no match means the implicit deny.
+func evalSecurityGroups(check string, groups []ec2types.SecurityGroup, egress bool, peer *reachEnd, protocol string, port int) (string, types.ReachVerdict, string, string) {
+	peerGroups := peer.groupIDs()
+	direction, prep := "ingress", "from"
+	if egress {
+		direction, prep = "egress", "to"
+	}
+
+	var names []string
+	for _, g := range groups {
+		names = append(names, sgLabel(g))
+		perms := g.IpPermissions
+		if egress {
+			perms = g.IpPermissionsEgress
+		}
+		for _, perm := range perms {
+			if !sgProtocolMatches(perm, protocol, port) {
+				continue
+			}
+			for _, r := range perm.IpRanges {
+				if cidrContains(deref(r.CidrIp), peer.ip) {
+					return check, types.ReachAllow,
+						fmt.Sprintf("%s: %s %s %s %s", sgLabel(g), direction, sgPortString(perm), prep, deref(r.CidrIp)), ""
+				}
+			}
+			for _, pair := range perm.UserIdGroupPairs {
+				if peerGroups[deref(pair.GroupId)] {
+					return check, types.ReachAllow,
+						fmt.Sprintf("%s: %s %s %s %s", sgLabel(g), direction, sgPortString(perm), prep, deref(pair.GroupId)), ""
+				}
+			}
+		}
+	}
+
+	detail := fmt.Sprintf("no %s rule allows %s/%d %s %s", direction, protocol, port, prep, peer.ip)
+	if len(names) == 0 {
+		detail = "the instance has no security groups"
+	}
+	for _, g := range groups {
+		perms := g.IpPermissions
+		if egress {
+			perms = g.IpPermissionsEgress
+		}
+		for _, perm := range perms {
+			if len(perm.PrefixListIds) > 0 && sgProtocolMatches(perm, protocol, port) {
+				return check, types.ReachWarn, strings.Join(names, ", "),
+					detail + "; a prefix-list rule (not evaluated) may match"
+			}
+		}
+	}
+	return check, types.ReachDeny, strings.Join(names, ", "), detail
+}
+
+// evalNACL applies the first matching network ACL entry, by rule number, to
+// traffic to or from peer on ports from-to. A range partly allowed and
+// partly denied is reported as a warning.
+func evalNACL(check string, acl *ec2types.NetworkAcl, egress bool, peer net.IP, protocol string, from, to int) (string, types.ReachVerdict, string, string) {
+	if acl == nil {
+		return check, types.ReachInfo, "", "no network ACL found for the subnet"
+	}
+	entries := make([]ec2types.NetworkAclEntry, 0, len(acl.Entries))
+	for _, e := range acl.Entries {
+		if aws.ToBool(e.Egress) == egress && e.CidrBlock != nil {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return aws.ToInt32(entries[i].RuleNumber) < aws.ToInt32(entries[j].RuleNumber) })
+
+	aclID := deref(acl.NetworkAclId)
+	ports := fmt.Sprintf("%d", from)
+	if from != to {
+		ports = fmt.Sprintf("%d-%d", from, to)
+	}
+
+	for _, e := range entries {
+		if !naclProtocolMatches(deref(e.Protocol), protocol) || !cidrContains(deref(e.CidrBlock), peer) {
+			continue
+		}
+		lo, hi := 0, 65535
+		if e.PortRange != nil && deref(e.Protocol) != "-1" {
+			lo, hi = int(aws.ToInt32(e.PortRange.From)), int(aws.ToInt32(e.PortRange.To))
+		}
+		if hi < from || lo > to {
+			continue
+		}
+		rule := fmt.Sprintf("%s rule %s: %s %s", aclID, naclRuleNumber(e), e.RuleAction, naclEntryString(e))
+		if lo > from || hi < to {
+			return check, types.ReachWarn, rule, fmt.Sprintf("only ports %d-%d of %s are covered by this rule", lo, hi, ports)
+		}
+		if e.RuleAction == ec2types.RuleActionAllow {
+			return check, types.ReachAllow, rule, ""
+		}
+		return check, types.ReachDeny, rule, fmt.Sprintf("%s/%s is denied", protocol, ports)
+	}
+	return check, types.ReachDeny, aclID, fmt.Sprintf("no entry matches %s/%s; the default rule denies it", protocol, ports)
+}
+
+// evalRoute finds the most specific route for ip in the endpoint's route
+// table.
+func evalRoute(check string, e *reachEnd, ip net.IP) (string, types.ReachVerdict, string, string) {
+	if e.routes == nil {
+		return check, types.ReachInfo, "", "no route table found for the subnet"
+	}
+	rtID := deref(e.routes.RouteTableId)
+
+	var best *ec2types.Route
+	bestLen := -1
+	for i, r := range e.routes.Routes {
+		_, cidr, err := net.ParseCIDR(deref(r.DestinationCidrBlock))
+		if err != nil || !cidr.Contains(ip) {
+			continue
+		}
+		if ones, _ := cidr.Mask.Size(); ones > bestLen {
+			best, bestLen = &e.routes.Routes[i], ones
+		}
+	}
+	if best == nil {
+		return check, types.ReachDeny, rtID, fmt.Sprintf("no route to %s", ip)
+	}
+
+	target := routeTarget(*best)
+	rule := fmt.Sprintf("%s: %s → %s", rtID, deref(best.DestinationCidrBlock), target)
+	if best.State == ec2types.RouteStateBlackhole {
+		return check, types.ReachDeny, rule, "the route's target no longer exists (blackhole)"
+	}
+	if strings.HasPrefix(target, "igw-") && e.inst != nil && e.inst.PublicIpAddress == nil {
+		return check, types.ReachDeny, rule, "the route goes to an internet gateway but the instance has no public IP; use a NAT gateway route"
+	}
+	return check, types.ReachAllow, rule, ""
+}
+
+func routeTarget(r ec2types.Route) string {
+	for _, t := range []*string{
+		r.GatewayId, r.NatGatewayId, r.TransitGatewayId, r.VpcPeeringConnectionId,
+		r.NetworkInterfaceId, r.InstanceId, r.EgressOnlyInternetGatewayId, r.LocalGatewayId,
+		r.CarrierGatewayId, r.CoreNetworkArn,
+	} {
+		if t != nil && *t != "" {
+			return *t
+		}
+	}
+	return "unknown"
+}
+
+func sgProtocolMatches(perm ec2types.IpPermission, protocol string, port int) bool {
+	switch deref(perm.IpProtocol) {
+	case "-1":
+		return true
+	case protocol, ianaProtocol(protocol):
+		return int(aws.ToInt32(perm.FromPort)) <= port && port <= int(aws.ToInt32(perm.ToPort))
+	}
+	return false
+}
+
+func naclProtocolMatches(ruleProto, protocol string) bool {
+	return ruleProto == "-1" || ruleProto == ianaProtocol(protocol)
+}
+
+// ianaProtocol returns the protocol number EC2 uses for tcp and udp
+func ianaProtocol(protocol string) string {
+	switch protocol {
+	case "tcp":
+		return "6"
+	case "udp":
+		return "17"
+	}
+	return protocol
+}
+
+func cidrContains(cidr string, ip net.IP) bool {
+	_, n, err := net.ParseCIDR(cidr)
+	return err == nil && ip != nil && n.Contains(ip)
+}
+
+func sgLabel(g ec2types.SecurityGroup) string {
+	return fmt.Sprintf("%s (%s)", deref(g.GroupId), deref(g.GroupName))
+}
+
+func sgPortString(perm ec2types.IpPermission) string {
+	proto := deref(perm.IpProtocol)
+	if proto == "-1" {
+		return "all traffic"
+	}
+	from, to := aws.ToInt32(perm.FromPort), aws.ToInt32(perm.ToPort)
+	if from == to {
+		return fmt.Sprintf("%s %d", proto, from)
+	}
+	return fmt.Sprintf("%s %d-%d", proto, from, to)
+}
+
+func naclRuleNumber(e ec2types.NetworkAclEntry) string {
+	if n := aws.ToInt32(e.RuleNumber); n != 32767 {
+		return fmt.Sprintf("%d", n)
+	}
+	return "*"
+}
+
+func naclEntryString(e ec2types.NetworkAclEntry) string {
+	proto := map[string]string{"-1": "all", "6": "tcp", "17": "udp", "1": "icmp"}[deref(e.Protocol)]
+	if proto == "" {
+		proto = "proto " + deref(e.Protocol)
+	}
+	ports := ""
+	if e.PortRange != nil && deref(e.Protocol) != "-1" {
+		ports = fmt.Sprintf(" %d-%d", aws.ToInt32(e.PortRange.From), aws.ToInt32(e.PortRange.To))
+	}
+	dir := "from"
+	if aws.ToBool(e.Egress) {
+		dir = "to"
+	}
+	return fmt.Sprintf("%s%s %s %s", proto, ports, dir, deref(e.CidrBlock))
+}
+
+// reachLabel names an endpoint for the report
+func reachLabel(ep provider.ReachEndpoint) string {
+	if ep.VM == nil {
+		return ep.IP
+	}
+	return vmDisplayName(ep.VM)
+}
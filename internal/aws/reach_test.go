@@ -0,0 +1,308 @@
+package aws
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+func naclEntry(rule int32, egress bool, action ec2types.RuleAction, proto, cidr string, from, to int32) ec2types.NetworkAclEntry {
+	e := ec2types.NetworkAclEntry{
+		RuleNumber: aws.Int32(rule),
+		Egress:     aws.Bool(egress),
+		RuleAction: action,
+		Protocol:   aws.String(proto),
+		CidrBlock:  aws.String(cidr),
+	}
+	if from != 0 || to != 0 {
+		e.PortRange = &ec2types.PortRange{From: aws.Int32(from), To: aws.Int32(to)}
+	}
+	return e
+}
+
+func TestEvalNACL(t *testing.T) {
+	allow, deny := ec2types.RuleActionAllow, ec2types.RuleActionDeny
+	acl := func(entries ...ec2types.NetworkAclEntry) *ec2types.NetworkAcl {
+		return &ec2types.NetworkAcl{NetworkAclId: aws.String("acl-1"), Entries: entries}
+	}
+
+	tests := []struct {
+		name     string
+		acl      *ec2types.NetworkAcl
+		egress   bool
+		peer     string
+		protocol string
+		from, to int
+		want     types.ReachVerdict
+		rule     string
+	}{
+		{
+			name: "lowest rule number wins regardless of order",
+			acl: acl(
+				naclEntry(200, false, deny, "6", "0.0.0.0/0", 22, 22),
+				naclEntry(100, false, allow, "6", "10.0.0.0/16", 22, 22),
+			),
+			peer: "10.0.1.5", protocol: "tcp", from: 22, to: 22,
+			want: types.ReachAllow, rule: "rule 100",
+		},
+		{
+			name: "later deny applies when the earlier rule does not match the peer",
+			acl: acl(
+				naclEntry(200, false, deny, "6", "0.0.0.0/0", 22, 22),
+				naclEntry(100, false, allow, "6", "10.0.0.0/16", 22, 22),
+			),
+			peer: "192.168.1.1", protocol: "tcp", from: 22, to: 22,
+			want: types.ReachDeny, rule: "rule 200",
+		},
+		{
+			name: "deny before allow",
+			acl: acl(
+				naclEntry(100, false, deny, "6", "10.0.0.0/16", 0, 1023),
+				naclEntry(110, false, allow, "-1", "0.0.0.0/0", 0, 0),
+			),
+			peer: "10.0.1.5", protocol: "tcp", from: 443, to: 443,
+			want: types.ReachDeny, rule: "rule 100",
+		},
+		{
+			name:     "no matching entry hits the default deny",
+			acl:      acl(naclEntry(100, false, allow, "17", "0.0.0.0/0", 53, 53)),
+			peer:     "10.0.1.5",
+			protocol: "tcp", from: 53, to: 53,
+			want: types.ReachDeny, rule: "acl-1",
+		},
+		{
+			name: "egress entries are ignored for ingress",
+			acl: acl(
+				naclEntry(100, true, allow, "-1", "0.0.0.0/0", 0, 0),
+				naclEntry(32767, false, deny, "-1", "0.0.0.0/0", 0, 0),
+			),
+			peer: "10.0.1.5", protocol: "tcp", from: 22, to: 22,
+			want: types.ReachDeny, rule: "rule *",
+		},
+		{
+			name:   "egress rule",
+			acl:    acl(naclEntry(100, true, allow, "6", "0.0.0.0/0", 443, 443)),
+			egress: true,
+			peer:   "52.1.2.3", protocol: "tcp", from: 443, to: 443,
+			want: types.ReachAllow, rule: "rule 100",
+		},
+		{
+			name: "all protocols ignore the port range",
+			acl:  acl(naclEntry(100, false, allow, "-1", "0.0.0.0/0", 80, 80)),
+			peer: "10.0.1.5", protocol: "udp", from: 5000, to: 5000,
+			want: types.ReachAllow, rule: "rule 100",
+		},
+		{
+			name: "ephemeral range only partly covered",
+			acl:  acl(naclEntry(100, false, allow, "6", "0.0.0.0/0", 32768, 65535)),
+			peer: "10.0.1.5", protocol: "tcp", from: ephemeralFrom, to: ephemeralTo,
+			want: types.ReachWarn, rule: "rule 100",
+		},
+		{
+			name: "range fully covered",
+			acl:  acl(naclEntry(100, false, allow, "6", "0.0.0.0/0", 1024, 65535)),
+			peer: "10.0.1.5", protocol: "tcp", from: ephemeralFrom, to: ephemeralTo,
+			want: types.ReachAllow, rule: "rule 100",
+		},
+		{
+			name: "no ACL",
+			peer: "10.0.1.5", protocol: "tcp", from: 22, to: 22,
+			want: types.ReachInfo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, verdict, rule, detail := evalNACL("nacl", tt.acl, tt.egress, net.ParseIP(tt.peer), tt.protocol, tt.from, tt.to)
+			if verdict != tt.want {
+				t.Errorf("verdict = %s, want %s (rule %q, %s)", verdict, tt.want, rule, detail)
+			}
+			if !strings.Contains(rule, tt.rule) {
+				t.Errorf("rule = %q, want it to contain %q", rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestEvalSecurityGroups(t *testing.T) {
+	group := func(id string, ingress ...ec2types.IpPermission) ec2types.SecurityGroup {
+		return ec2types.SecurityGroup{GroupId: aws.String(id), GroupName: aws.String(id + "-name"), IpPermissions: ingress}
+	}
+	egressGroup := func(id string, egress ...ec2types.IpPermission) ec2types.SecurityGroup {
+		return ec2types.SecurityGroup{GroupId: aws.String(id), GroupName: aws.String(id + "-name"), IpPermissionsEgress: egress}
+	}
+	tcp := func(from, to int32) ec2types.IpPermission {
+		return ec2types.IpPermission{IpProtocol: aws.String("tcp"), FromPort: aws.Int32(from), ToPort: aws.Int32(to)}
+	}
+	fromCIDR := func(p ec2types.IpPermission, cidr string) ec2types.IpPermission {
+		p.IpRanges = append(p.IpRanges, ec2types.IpRange{CidrIp: aws.String(cidr)})
+		return p
+	}
+	fromGroup := func(p ec2types.IpPermission, id string) ec2types.IpPermission {
+		p.UserIdGroupPairs = append(p.UserIdGroupPairs, ec2types.UserIdGroupPair{GroupId: aws.String(id)})
+		return p
+	}
+	peer := func(ip string, groups ...string) *reachEnd {
+		e := &reachEnd{ip: net.ParseIP(ip)}
+		if len(groups) > 0 {
+			e.inst = &ec2types.Instance{}
+			for _, g := range groups {
+				e.inst.SecurityGroups = append(e.inst.SecurityGroups, ec2types.GroupIdentifier{GroupId: aws.String(g)})
+			}
+		}
+		return e
+	}
+
+	tests := []struct {
+		name   string
+		groups []ec2types.SecurityGroup
+		egress bool
+		peer   *reachEnd
+		port   int
+		want   types.ReachVerdict
+		rule   string
+	}{
+		{
+			name:   "CIDR rule",
+			groups: []ec2types.SecurityGroup{group("sg-db", fromCIDR(tcp(5432, 5432), "10.0.0.0/16"))},
+			peer:   peer("10.0.3.4"), port: 5432,
+			want: types.ReachAllow, rule: "10.0.0.0/16",
+		},
+		{
+			name:   "CIDR rule for another network",
+			groups: []ec2types.SecurityGroup{group("sg-db", fromCIDR(tcp(5432, 5432), "10.1.0.0/16"))},
+			peer:   peer("10.0.3.4"), port: 5432,
+			want: types.ReachDeny, rule: "sg-db",
+		},
+		{
+			name:   "peer security group reference",
+			groups: []ec2types.SecurityGroup{group("sg-db", fromGroup(tcp(5432, 5432), "sg-app"))},
+			peer:   peer("10.0.3.4", "sg-base", "sg-app"), port: 5432,
+			want: types.ReachAllow, rule: "sg-app",
+		},
+		{
+			name:   "peer not in the referenced group",
+			groups: []ec2types.SecurityGroup{group("sg-db", fromGroup(tcp(5432, 5432), "sg-app"))},
+			peer:   peer("10.0.3.4", "sg-web"), port: 5432,
+			want: types.ReachDeny,
+		},
+		{
+			name:   "group reference never matches a bare IP",
+			groups: []ec2types.SecurityGroup{group("sg-db", fromGroup(tcp(5432, 5432), "sg-app"))},
+			peer:   peer("10.0.3.4"), port: 5432,
+			want: types.ReachDeny,
+		},
+		{
+			name:   "port outside the range",
+			groups: []ec2types.SecurityGroup{group("sg-web", fromCIDR(tcp(80, 443), "0.0.0.0/0"))},
+			peer:   peer("1.2.3.4"), port: 8080,
+			want: types.ReachDeny,
+		},
+		{
+			name: "second group allows",
+			groups: []ec2types.SecurityGroup{
+				group("sg-web", fromCIDR(tcp(443, 443), "0.0.0.0/0")),
+				group("sg-admin", fromCIDR(tcp(22, 22), "10.0.0.0/8")),
+			},
+			peer: peer("10.9.9.9"), port: 22,
+			want: types.ReachAllow, rule: "sg-admin",
+		},
+		{
+			name: "all traffic egress",
+			groups: []ec2types.SecurityGroup{egressGroup("sg-web",
+				fromCIDR(ec2types.IpPermission{IpProtocol: aws.String("-1")}, "0.0.0.0/0"))},
+			egress: true,
+			peer:   peer("52.1.2.3"), port: 443,
+			want: types.ReachAllow, rule: "all traffic",
+		},
+		{
+			name:   "ingress rules do not apply to egress",
+			groups: []ec2types.SecurityGroup{group("sg-web", fromCIDR(tcp(443, 443), "0.0.0.0/0"))},
+			egress: true,
+			peer:   peer("52.1.2.3"), port: 443,
+			want: types.ReachDeny,
+		},
+		{
+			name: "unevaluated prefix list",
+			groups: []ec2types.SecurityGroup{group("sg-web", ec2types.IpPermission{
+				IpProtocol: aws.String("tcp"), FromPort: aws.Int32(443), ToPort: aws.Int32(443),
+				PrefixListIds: []ec2types.PrefixListId{{PrefixListId: aws.String("pl-1")}},
+			})},
+			peer: peer("1.2.3.4"), port: 443,
+			want: types.ReachWarn,
+		},
+		{
+			name: "no groups",
+			peer: peer("1.2.3.4"), port: 22,
+			want: types.ReachDeny,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, verdict, rule, detail := evalSecurityGroups("sg", tt.groups, tt.egress, tt.peer, "tcp", tt.port)
+			if verdict != tt.want {
+				t.Errorf("verdict = %s, want %s (rule %q, %s)", verdict, tt.want, rule, detail)
+			}
+			if !strings.Contains(rule, tt.rule) {
+				t.Errorf("rule = %q, want it to contain %q", rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestEvalRoute(t *testing.T) {
+	table := &ec2types.RouteTable{
+		RouteTableId: aws.String("rtb-1"),
+		Routes: []ec2types.Route{
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-1")},
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("10.0.5.0/24"), VpcPeeringConnectionId: aws.String("pcx-1")},
+			{DestinationCidrBlock: aws.String("10.0.5.128/25"), TransitGatewayId: aws.String("tgw-1")},
+			{DestinationCidrBlock: aws.String("172.16.0.0/12"), NatGatewayId: aws.String("nat-1"), State: ec2types.RouteStateBlackhole},
+		},
+	}
+	private := &ec2types.Instance{}
+	public := &ec2types.Instance{PublicIpAddress: aws.String("54.1.2.3")}
+
+	tests := []struct {
+		name   string
+		routes *ec2types.RouteTable
+		inst   *ec2types.Instance
+		ip     string
+		want   types.ReachVerdict
+		rule   string
+	}{
+		{name: "local route beats default", routes: table, inst: private, ip: "10.0.1.5", want: types.ReachAllow, rule: "local"},
+		{name: "/24 beats /16", routes: table, inst: private, ip: "10.0.5.9", want: types.ReachAllow, rule: "pcx-1"},
+		{name: "/25 beats /24", routes: table, inst: private, ip: "10.0.5.200", want: types.ReachAllow, rule: "tgw-1"},
+		{name: "internet gateway with a public IP", routes: table, inst: public, ip: "8.8.8.8", want: types.ReachAllow, rule: "igw-1"},
+		{name: "internet gateway without a public IP", routes: table, inst: private, ip: "8.8.8.8", want: types.ReachDeny, rule: "igw-1"},
+		{name: "blackhole", routes: table, inst: private, ip: "172.16.0.1", want: types.ReachDeny, rule: "nat-1"},
+		{
+			name:   "no route",
+			routes: &ec2types.RouteTable{RouteTableId: aws.String("rtb-2"), Routes: table.Routes[1:2]},
+			inst:   private, ip: "8.8.8.8",
+			want: types.ReachDeny, rule: "rtb-2",
+		},
+		{name: "no route table", inst: private, ip: "8.8.8.8", want: types.ReachInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &reachEnd{inst: tt.inst, routes: tt.routes}
+			_, verdict, rule, detail := evalRoute("route", e, net.ParseIP(tt.ip))
+			if verdict != tt.want {
+				t.Errorf("verdict = %s, want %s (rule %q, %s)", verdict, tt.want, rule, detail)
+			}
+			if !strings.Contains(rule, tt.rule) {
+				t.Errorf("rule = %q, want it to contain %q", rule, tt.rule)
+			}
+		})
+	}
+}
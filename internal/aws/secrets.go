@@ -2,7 +2,9 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
@@ -14,6 +16,13 @@ import (
 	"github.com/vietdv277/cumulus/pkg/types"
 )
 
+// Secrets backends, also the URL schemes that select them explicitly
+// (ssm:///app/x, sm://app/x)
+const (
+	SecretsBackendSSM = "ssm" // SSM Parameter Store
+	SecretsBackendSM  = "sm"  // Secrets Manager
+)
+
 // AWSSecretsProvider implements the SecretsProvider interface for AWS
 // It supports both SSM Parameter Store and Secrets Manager
 type AWSSecretsProvider struct {
@@ -22,36 +31,107 @@ type AWSSecretsProvider struct {
 	sm      *secretsmanager.Client
 	profile string
 	region  string
+
+	backend  string                 // default backend for names without a scheme; empty guesses by leading /
+	ssmType  ssmTypes.ParameterType // type for SSM writes; empty keeps the existing type (SecureString when new)
+	kmsKeyID string                 // KMS key for SSM SecureString writes
+}
+
+// SecretsOption is a functional option for configuring an AWSSecretsProvider.
+type SecretsOption func(*AWSSecretsProvider)
+
+// WithSecretsBackend sets the backend (SecretsBackendSSM or SecretsBackendSM)
+// used for names without an ssm:// or sm:// scheme.
+func WithSecretsBackend(backend string) SecretsOption {
+	return func(p *AWSSecretsProvider) { p.backend = backend }
+}
+
+// WithSSMParameterType sets the type (String, StringList or SecureString)
+// of SSM parameters written.
+func WithSSMParameterType(t string) SecretsOption {
+	return func(p *AWSSecretsProvider) { p.ssmType = ssmTypes.ParameterType(t) }
+}
+
+// WithKMSKey sets the KMS key ID, ARN or alias used to encrypt SSM
+// SecureString parameters written.
+func WithKMSKey(keyID string) SecretsOption {
+	return func(p *AWSSecretsProvider) { p.kmsKeyID = keyID }
 }
 
 // NewSecretsProvider creates a new AWS Secrets provider
-func NewSecretsProvider(client *Client, ssmClient *ssm.Client, smClient *secretsmanager.Client, profile, region string) *AWSSecretsProvider {
-	return &AWSSecretsProvider{
+func NewSecretsProvider(client *Client, ssmClient *ssm.Client, smClient *secretsmanager.Client, profile, region string, opts ...SecretsOption) *AWSSecretsProvider {
+	p := &AWSSecretsProvider{
 		client:  client,
 		ssm:     ssmClient,
 		sm:      smClient,
 		profile: profile,
 		region:  region,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
-// List returns secrets matching the filter
+// ParseSecretName splits an ssm:// or sm:// name into its backend and the
+// name within it. Without a scheme, the backend is empty.
+func ParseSecretName(name string) (backend, bare string) {
+	for _, b := range []string{SecretsBackendSSM, SecretsBackendSM} {
+		if rest, ok := strings.CutPrefix(name, b+"://"); ok {
+			return b, rest
+		}
+	}
+	return "", name
+}
+
+// route returns the backend a name belongs to: its scheme, else the
+// provider's default backend, else SSM for names starting with / and
+// Secrets Manager otherwise
+func (p *AWSSecretsProvider) route(name string) (backend, bare string) {
+	backend, bare = ParseSecretName(name)
+	switch {
+	case backend != "":
+	case p.backend != "":
+		backend = p.backend
+	case strings.HasPrefix(bare, "/"):
+		backend = SecretsBackendSSM
+	default:
+		backend = SecretsBackendSM
+	}
+	return backend, bare
+}
+
+// List returns secrets matching the filter. A prefix with a scheme, or
+// filter.Backend, restricts the listing to one backend.
 func (p *AWSSecretsProvider) List(ctx context.Context, filter *provider.SecretFilter) ([]types.Secret, error) {
+	var backend, prefix string
+	if filter != nil {
+		backend, prefix = ParseSecretName(filter.Prefix)
+		if backend == "" {
+			backend = filter.Backend
+		}
+	}
+	bareFilter := &provider.SecretFilter{Prefix: prefix}
+
 	var secrets []types.Secret
 
 	// List from SSM Parameter Store
-	ssmSecrets, err := p.listSSMParameters(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list SSM parameters: %w", err)
+	if backend == "" || backend == SecretsBackendSSM {
+		ssmSecrets, err := p.listSSMParameters(ctx, bareFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SSM parameters: %w", err)
+		}
+		secrets = append(secrets, ssmSecrets...)
 	}
-	secrets = append(secrets, ssmSecrets...)
 
 	// List from Secrets Manager
-	smSecrets, err := p.listSecretsManager(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list Secrets Manager secrets: %w", err)
+	if backend == "" || backend == SecretsBackendSM {
+		smSecrets, err := p.listSecretsManager(ctx, bareFilter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Secrets Manager secrets: %w", err)
+		}
+		secrets = append(secrets, smSecrets...)
 	}
-	secrets = append(secrets, smSecrets...)
 
 	return secrets, nil
 }
@@ -81,8 +161,11 @@ func (p *AWSSecretsProvider) listSSMParameters(ctx context.Context, filter *prov
 		for _, param := range page.Parameters {
 			secret := types.Secret{
 				Name:     deref(param.Name),
-				ARN:      deref(param.Name), // SSM doesn't have ARNs for parameters in this API
+				ARN:      deref(param.ARN),
 				Provider: "aws",
+				Backend:  SecretsBackendSSM,
+				Type:     string(param.Type),
+				Raw:      param,
 			}
 			if param.LastModifiedDate != nil {
 				secret.UpdatedAt = *param.LastModifiedDate
@@ -118,9 +201,12 @@ func (p *AWSSecretsProvider) listSecretsManager(ctx context.Context, filter *pro
 
 		for _, s := range page.SecretList {
 			secret := types.Secret{
-				Name:     deref(s.Name),
-				ARN:      deref(s.ARN),
-				Provider: "aws",
+				Name:            deref(s.Name),
+				ARN:             deref(s.ARN),
+				Provider:        "aws",
+				Backend:         SecretsBackendSM,
+				RotationEnabled: s.RotationEnabled != nil && *s.RotationEnabled,
+				Raw:             s,
 			}
 			if s.CreatedDate != nil {
 				secret.CreatedAt = *s.CreatedDate
@@ -137,12 +223,10 @@ func (p *AWSSecretsProvider) listSecretsManager(ctx context.Context, filter *pro
 
 // Get returns a secret value
 func (p *AWSSecretsProvider) Get(ctx context.Context, name string) (*types.SecretValue, error) {
-	// Try SSM Parameter Store first (for paths starting with /)
-	if len(name) > 0 && name[0] == '/' {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
 		return p.getSSMParameter(ctx, name)
 	}
-
-	// Try Secrets Manager
 	return p.getSecretsManager(ctx, name)
 }
 
@@ -161,7 +245,10 @@ func (p *AWSSecretsProvider) getSSMParameter(ctx context.Context, name string) (
 			Name:      deref(param.Name),
 			ARN:       deref(param.ARN),
 			Provider:  "aws",
+			Backend:   SecretsBackendSSM,
+			Type:      string(param.Type),
 			UpdatedAt: safeTime(param.LastModifiedDate),
+			Raw:       param,
 		},
 		Value:   deref(param.Value),
 		Version: fmt.Sprintf("%d", param.Version),
@@ -181,6 +268,7 @@ func (p *AWSSecretsProvider) getSecretsManager(ctx context.Context, name string)
 			Name:      deref(output.Name),
 			ARN:       deref(output.ARN),
 			Provider:  "aws",
+			Backend:   SecretsBackendSM,
 			CreatedAt: safeTime(output.CreatedDate),
 		},
 		Value:   deref(output.SecretString),
@@ -190,23 +278,70 @@ func (p *AWSSecretsProvider) getSecretsManager(ctx context.Context, name string)
 
 // Set creates or updates a secret
 func (p *AWSSecretsProvider) Set(ctx context.Context, name string, value string) error {
-	// Use SSM Parameter Store for paths starting with /
-	if len(name) > 0 && name[0] == '/' {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
 		return p.setSSMParameter(ctx, name, value)
 	}
-
-	// Use Secrets Manager for other secrets
 	return p.setSecretsManager(ctx, name, value)
 }
 
+// setSSMParameter writes a parameter. Without an explicit type an existing
+// parameter keeps its type and KMS key, and a new one is a SecureString.
 func (p *AWSSecretsProvider) setSSMParameter(ctx context.Context, name, value string) error {
-	_, err := p.ssm.PutParameter(ctx, &ssm.PutParameterInput{
+	paramType, keyID := p.ssmType, p.kmsKeyID
+
+	if paramType == "" || (paramType == ssmTypes.ParameterTypeSecureString && keyID == "") {
+		existing, err := p.describeSSMParameter(ctx, name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case existing == nil && paramType == "":
+			paramType = ssmTypes.ParameterTypeSecureString
+		case existing != nil:
+			if paramType == "" {
+				paramType = existing.Type
+			}
+			if keyID == "" && existing.Type == ssmTypes.ParameterTypeSecureString {
+				keyID = deref(existing.KeyId)
+			}
+		}
+	}
+
+	input := &ssm.PutParameterInput{
 		Name:      &name,
 		Value:     &value,
-		Type:      ssmTypes.ParameterTypeSecureString,
+		Type:      paramType,
 		Overwrite: boolPtr(true),
+	}
+	if paramType == ssmTypes.ParameterTypeSecureString && keyID != "" {
+		input.KeyId = &keyID
+	}
+	if _, err := p.ssm.PutParameter(ctx, input); err != nil {
+		return fmt.Errorf("failed to put SSM parameter: %w", err)
+	}
+	return nil
+}
+
+// describeSSMParameter returns a parameter's metadata, or nil if it does
+// not exist
+func (p *AWSSecretsProvider) describeSSMParameter(ctx context.Context, name string) (*ssmTypes.ParameterMetadata, error) {
+	output, err := p.ssm.DescribeParameters(ctx, &ssm.DescribeParametersInput{
+		ParameterFilters: []ssmTypes.ParameterStringFilter{
+			{
+				Key:    strPtr("Name"),
+				Option: strPtr("Equals"),
+				Values: []string{name},
+			},
+		},
 	})
-	return err
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe SSM parameter: %w", err)
+	}
+	if len(output.Parameters) == 0 {
+		return nil, nil
+	}
+	return &output.Parameters[0], nil
 }
 
 func (p *AWSSecretsProvider) setSecretsManager(ctx context.Context, name, value string) error {
@@ -215,7 +350,8 @@ func (p *AWSSecretsProvider) setSecretsManager(ctx context.Context, name, value
 		SecretId:     &name,
 		SecretString: &value,
 	})
-	if err != nil {
+	var notFound *smTypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
 		// If secret doesn't exist, create it
 		_, err = p.sm.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
 			Name:         &name,
@@ -227,8 +363,8 @@ func (p *AWSSecretsProvider) setSecretsManager(ctx context.Context, name, value
 
 // Delete removes a secret
 func (p *AWSSecretsProvider) Delete(ctx context.Context, name string) error {
-	// Use SSM Parameter Store for paths starting with /
-	if len(name) > 0 && name[0] == '/' {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
 		_, err := p.ssm.DeleteParameter(ctx, &ssm.DeleteParameterInput{
 			Name: &name,
 		})
@@ -0,0 +1,270 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smTypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// History returns the versions of an SSM parameter or a Secrets Manager
+// secret, newest first
+func (p *AWSSecretsProvider) History(ctx context.Context, name string) ([]types.SecretVersion, error) {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
+		entries, err := p.ssmHistory(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		versions := make([]types.SecretVersion, len(entries))
+		for i, e := range entries {
+			versions[i] = types.SecretVersion{
+				Version:   strconv.FormatInt(e.Version, 10),
+				Labels:    e.Labels,
+				Current:   i == 0,
+				CreatedAt: safeTime(e.LastModifiedDate),
+				CreatedBy: deref(e.LastModifiedUser),
+			}
+		}
+		return versions, nil
+	}
+
+	entries, err := p.smHistory(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]types.SecretVersion, len(entries))
+	for i, e := range entries {
+		versions[i] = types.SecretVersion{
+			Version:   deref(e.VersionId),
+			Labels:    e.VersionStages,
+			Current:   slices.Contains(e.VersionStages, "AWSCURRENT"),
+			CreatedAt: safeTime(e.CreatedDate),
+		}
+	}
+	return versions, nil
+}
+
+// ssmHistory returns a parameter's history, newest first
+func (p *AWSSecretsProvider) ssmHistory(ctx context.Context, name string) ([]ssmTypes.ParameterHistory, error) {
+	paginator := ssm.NewGetParameterHistoryPaginator(p.ssm, &ssm.GetParameterHistoryInput{
+		Name: &name,
+	})
+
+	var entries []ssmTypes.ParameterHistory
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SSM parameter history: %w", err)
+		}
+		entries = append(entries, page.Parameters...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Version > entries[j].Version })
+	return entries, nil
+}
+
+// smHistory returns a secret's versions (including deprecated ones that no
+// longer have a stage), newest first
+func (p *AWSSecretsProvider) smHistory(ctx context.Context, name string) ([]smTypes.SecretVersionsListEntry, error) {
+	paginator := secretsmanager.NewListSecretVersionIdsPaginator(p.sm, &secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          &name,
+		IncludeDeprecated: boolPtr(true),
+	})
+
+	var entries []smTypes.SecretVersionsListEntry
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list secret versions: %w", err)
+		}
+		entries = append(entries, page.Versions...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return safeTime(entries[i].CreatedDate).After(safeTime(entries[j].CreatedDate))
+	})
+	return entries, nil
+}
+
+// smFindVersion resolves a version ID or stage to a history entry
+func smFindVersion(entries []smTypes.SecretVersionsListEntry, version string) (smTypes.SecretVersionsListEntry, bool) {
+	for _, e := range entries {
+		if deref(e.VersionId) == version || slices.Contains(e.VersionStages, version) {
+			return e, true
+		}
+	}
+	return smTypes.SecretVersionsListEntry{}, false
+}
+
+// GetVersion returns the value of one version: an SSM version number or
+// label, or a Secrets Manager version ID or stage
+func (p *AWSSecretsProvider) GetVersion(ctx context.Context, name, version string) (*types.SecretValue, error) {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
+		return p.getSSMParameter(ctx, name+":"+version)
+	}
+
+	input := &secretsmanager.GetSecretValueInput{SecretId: &name}
+	entries, err := p.smHistory(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := smFindVersion(entries, version); ok {
+		input.VersionId = e.VersionId
+	} else {
+		input.VersionStage = &version
+	}
+
+	output, err := p.sm.GetSecretValue(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret version %s: %w", version, err)
+	}
+	return &types.SecretValue{
+		Secret: types.Secret{
+			Name:      deref(output.Name),
+			ARN:       deref(output.ARN),
+			Provider:  "aws",
+			Backend:   SecretsBackendSM,
+			CreatedAt: safeTime(output.CreatedDate),
+		},
+		Value:   deref(output.SecretString),
+		Version: deref(output.VersionId),
+	}, nil
+}
+
+// Rollback makes version current again. For Secrets Manager the AWSCURRENT
+// stage is moved to it (the old current becomes AWSPREVIOUS); for SSM its
+// value, type and KMS key are written as a new version.
+func (p *AWSSecretsProvider) Rollback(ctx context.Context, name, version string) error {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
+		return p.rollbackSSMParameter(ctx, name, version)
+	}
+
+	entries, err := p.smHistory(ctx, name)
+	if err != nil {
+		return err
+	}
+	target, ok := smFindVersion(entries, version)
+	if !ok {
+		return fmt.Errorf("version %s of %s not found", version, name)
+	}
+	if slices.Contains(target.VersionStages, "AWSCURRENT") {
+		return fmt.Errorf("version %s is already current", version)
+	}
+	current, _ := smFindVersion(entries, "AWSCURRENT")
+
+	_, err = p.sm.UpdateSecretVersionStage(ctx, &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            &name,
+		VersionStage:        strPtr("AWSCURRENT"),
+		MoveToVersionId:     target.VersionId,
+		RemoveFromVersionId: current.VersionId,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move AWSCURRENT to %s: %w", version, err)
+	}
+	return nil
+}
+
+func (p *AWSSecretsProvider) rollbackSSMParameter(ctx context.Context, name, version string) error {
+	entries, err := p.ssmHistory(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var target *ssmTypes.ParameterHistory
+	for i, e := range entries {
+		if strconv.FormatInt(e.Version, 10) == version || slices.Contains(e.Labels, version) {
+			target = &entries[i]
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("version %s of %s not found", version, name)
+	}
+	if target.Version == entries[0].Version {
+		return fmt.Errorf("version %s is already current", version)
+	}
+
+	old, err := p.getSSMParameter(ctx, fmt.Sprintf("%s:%d", name, target.Version))
+	if err != nil {
+		return err
+	}
+
+	input := &ssm.PutParameterInput{
+		Name:      &name,
+		Value:     &old.Value,
+		Type:      target.Type,
+		Overwrite: boolPtr(true),
+	}
+	if target.Type == ssmTypes.ParameterTypeSecureString {
+		input.KeyId = target.KeyId
+	}
+	if _, err := p.ssm.PutParameter(ctx, input); err != nil {
+		return fmt.Errorf("failed to put SSM parameter: %w", err)
+	}
+	return nil
+}
+
+// Rotate starts an immediate rotation of a Secrets Manager secret using its
+// configured rotation function
+func (p *AWSSecretsProvider) Rotate(ctx context.Context, name string) (*types.SecretRotation, error) {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
+		return nil, fmt.Errorf("rotation is only supported for Secrets Manager secrets, not SSM parameters")
+	}
+
+	output, err := p.sm.RotateSecret(ctx, &secretsmanager.RotateSecretInput{
+		SecretId: &name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate secret: %w", err)
+	}
+
+	status, err := p.RotationStatus(ctx, SecretsBackendSM+"://"+name)
+	if err != nil {
+		return nil, err
+	}
+	if status.PendingVersion == "" {
+		status.PendingVersion = deref(output.VersionId)
+	}
+	return status, nil
+}
+
+// RotationStatus returns a Secrets Manager secret's rotation configuration
+// and any version still staged as AWSPENDING
+func (p *AWSSecretsProvider) RotationStatus(ctx context.Context, name string) (*types.SecretRotation, error) {
+	backend, name := p.route(name)
+	if backend == SecretsBackendSSM {
+		return nil, fmt.Errorf("rotation is only supported for Secrets Manager secrets, not SSM parameters")
+	}
+
+	output, err := p.sm.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: &name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe secret: %w", err)
+	}
+
+	status := &types.SecretRotation{
+		Enabled:      output.RotationEnabled != nil && *output.RotationEnabled,
+		LambdaARN:    deref(output.RotationLambdaARN),
+		LastRotated:  safeTime(output.LastRotatedDate),
+		NextRotation: safeTime(output.NextRotationDate),
+	}
+	for id, stages := range output.VersionIdsToStages {
+		if slices.Contains(stages, "AWSPENDING") && !slices.Contains(stages, "AWSCURRENT") {
+			status.PendingVersion = id
+		}
+	}
+	return status, nil
+}
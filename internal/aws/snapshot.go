@@ -0,0 +1,303 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// CreateSnapshot creates an AMI of the instance, or crash-consistent EBS
+// snapshots of all its volumes (CreateSnapshots). Images are created without
+// a reboot unless opts.Reboot is set.
+func (p *AWSVMProvider) CreateSnapshot(ctx context.Context, vm *types.VM, opts *provider.SnapshotOptions) ([]types.Snapshot, error) {
+	if opts == nil || opts.Name == "" {
+		return nil, fmt.Errorf("snapshot name required")
+	}
+
+	tags := map[string]string{"Name": opts.Name, provider.SnapshotSourceTag: vm.ID}
+	for k, v := range opts.Tags {
+		tags[k] = v
+	}
+	description := fmt.Sprintf("Created by cml from %s", vmDisplayName(vm))
+
+	if opts.Kind == types.SnapshotKindDisk {
+		output, err := p.client.EC2().CreateSnapshots(ctx, &ec2.CreateSnapshotsInput{
+			InstanceSpecification: &ec2types.InstanceSpecification{InstanceId: aws.String(vm.ID)},
+			Description:           aws.String(description),
+			TagSpecifications: []ec2types.TagSpecification{
+				{ResourceType: ec2types.ResourceTypeSnapshot, Tags: toEC2Tags(tags)},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create snapshots: %w", err)
+		}
+		snaps := make([]types.Snapshot, 0, len(output.Snapshots))
+		for _, s := range output.Snapshots {
+			snaps = append(snaps, types.Snapshot{
+				ID:        deref(s.SnapshotId),
+				Name:      opts.Name,
+				Kind:      types.SnapshotKindDisk,
+				State:     ebsStateToSnapshotState(s.State),
+				Progress:  parseProgress(s.Progress),
+				SourceVM:  vm.ID,
+				SizeGB:    int64(aws.ToInt32(s.VolumeSize)),
+				CreatedAt: aws.ToTime(s.StartTime),
+				Tags:      fromEC2Tags(s.Tags),
+				Provider:  "aws",
+				Raw:       s,
+			})
+		}
+		return snaps, nil
+	}
+
+	output, err := p.client.EC2().CreateImage(ctx, &ec2.CreateImageInput{
+		InstanceId:  aws.String(vm.ID),
+		Name:        aws.String(opts.Name),
+		Description: aws.String(description),
+		NoReboot:    aws.Bool(!opts.Reboot),
+		TagSpecifications: []ec2types.TagSpecification{
+			{ResourceType: ec2types.ResourceTypeImage, Tags: toEC2Tags(tags)},
+			{ResourceType: ec2types.ResourceTypeSnapshot, Tags: toEC2Tags(tags)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image: %w", err)
+	}
+	return []types.Snapshot{{
+		ID:        deref(output.ImageId),
+		Name:      opts.Name,
+		Kind:      types.SnapshotKindImage,
+		State:     types.SnapshotStatePending,
+		SourceVM:  vm.ID,
+		CreatedAt: time.Now(),
+		Tags:      tags,
+		Provider:  "aws",
+	}}, nil
+}
+
+// GetSnapshot refreshes an AMI or EBS snapshot. AMI progress is the average
+// of its backing snapshots, since DescribeImages reports none.
+func (p *AWSVMProvider) GetSnapshot(ctx context.Context, snap *types.Snapshot) (*types.Snapshot, error) {
+	if snap.Kind == types.SnapshotKindDisk {
+		output, err := p.client.EC2().DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{
+			SnapshotIds: []string{snap.ID},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe snapshot: %w", err)
+		}
+		if len(output.Snapshots) == 0 {
+			return nil, fmt.Errorf("snapshot not found: %s", snap.ID)
+		}
+		s := ebsToSnapshot(output.Snapshots[0])
+		return &s, nil
+	}
+
+	output, err := p.client.EC2().DescribeImages(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []string{snap.ID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe image: %w", err)
+	}
+	if len(output.Images) == 0 {
+		return nil, fmt.Errorf("image not found: %s", snap.ID)
+	}
+	s := amiToSnapshot(output.Images[0])
+
+	if s.State == types.SnapshotStatePending {
+		var ids []string
+		for _, bdm := range output.Images[0].BlockDeviceMappings {
+			if bdm.Ebs != nil && bdm.Ebs.SnapshotId != nil {
+				ids = append(ids, *bdm.Ebs.SnapshotId)
+			}
+		}
+		if len(ids) > 0 {
+			backing, err := p.client.EC2().DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: ids})
+			if err == nil && len(backing.Snapshots) > 0 {
+				total := 0
+				for _, b := range backing.Snapshots {
+					total += max(parseProgress(b.Progress), 0)
+				}
+				s.Progress = total / len(backing.Snapshots)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// ListSnapshots returns AMIs and EBS snapshots owned by the account, newest
+// first. Snapshots backing an AMI are listed with the AMI, not as disks.
+func (p *AWSVMProvider) ListSnapshots(ctx context.Context, filter *provider.SnapshotFilter) ([]types.Snapshot, error) {
+	if filter == nil {
+		filter = &provider.SnapshotFilter{}
+	}
+
+	var filters []ec2types.Filter
+	switch {
+	case filter.SourceVM != "":
+		filters = append(filters, ec2types.Filter{
+			Name:   aws.String("tag:" + provider.SnapshotSourceTag),
+			Values: []string{filter.SourceVM},
+		})
+	case filter.Managed:
+		filters = append(filters, ec2types.Filter{
+			Name:   aws.String("tag-key"),
+			Values: []string{provider.SnapshotSourceTag},
+		})
+	}
+	for k, v := range filter.Tags {
+		filters = append(filters, ec2types.Filter{Name: aws.String("tag:" + k), Values: []string{v}})
+	}
+
+	var snaps []types.Snapshot
+
+	if filter.Kind != types.SnapshotKindDisk {
+		paginator := ec2.NewDescribeImagesPaginator(p.client.EC2(), &ec2.DescribeImagesInput{
+			Owners:  []string{"self"},
+			Filters: filters,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe images: %w", err)
+			}
+			for _, img := range page.Images {
+				snaps = append(snaps, amiToSnapshot(img))
+			}
+		}
+	}
+
+	if filter.Kind != types.SnapshotKindImage {
+		paginator := ec2.NewDescribeSnapshotsPaginator(p.client.EC2(), &ec2.DescribeSnapshotsInput{
+			OwnerIds: []string{"self"},
+			Filters:  filters,
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe snapshots: %w", err)
+			}
+			for _, s := range page.Snapshots {
+				if strings.HasPrefix(deref(s.Description), "Created by CreateImage") {
+					continue
+				}
+				snaps = append(snaps, ebsToSnapshot(s))
+			}
+		}
+	}
+
+	sort.SliceStable(snaps, func(i, j int) bool { return snaps[i].CreatedAt.After(snaps[j].CreatedAt) })
+	return snaps, nil
+}
+
+// DeleteSnapshot deregisters an AMI along with its backing snapshots, or
+// deletes an EBS snapshot.
+func (p *AWSVMProvider) DeleteSnapshot(ctx context.Context, snap *types.Snapshot) error {
+	if snap.Kind == types.SnapshotKindDisk {
+		_, err := p.client.EC2().DeleteSnapshot(ctx, &ec2.DeleteSnapshotInput{SnapshotId: aws.String(snap.ID)})
+		if err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+		return nil
+	}
+
+	_, err := p.client.EC2().DeregisterImage(ctx, &ec2.DeregisterImageInput{
+		ImageId:                   aws.String(snap.ID),
+		DeleteAssociatedSnapshots: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deregister image: %w", err)
+	}
+	return nil
+}
+
+// amiToSnapshot converts an AMI to the unified Snapshot type
+func amiToSnapshot(img ec2types.Image) types.Snapshot {
+	s := types.Snapshot{
+		ID:       deref(img.ImageId),
+		Name:     deref(img.Name),
+		Kind:     types.SnapshotKindImage,
+		Progress: -1,
+		Tags:     fromEC2Tags(img.Tags),
+		Provider: "aws",
+		Raw:      img,
+	}
+	s.SourceVM = s.Tags[provider.SnapshotSourceTag]
+
+	switch img.State {
+	case ec2types.ImageStateAvailable:
+		s.State = types.SnapshotStateAvailable
+		s.Progress = 100
+	case ec2types.ImageStatePending, ec2types.ImageStateTransient:
+		s.State = types.SnapshotStatePending
+	case ec2types.ImageStateDeregistered:
+		s.State = types.SnapshotStateDeleting
+	default:
+		s.State = types.SnapshotStateFailed
+	}
+
+	for _, bdm := range img.BlockDeviceMappings {
+		if bdm.Ebs != nil {
+			s.SizeGB += int64(aws.ToInt32(bdm.Ebs.VolumeSize))
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, deref(img.CreationDate)); err == nil {
+		s.CreatedAt = t
+	}
+	return s
+}
+
+// ebsToSnapshot converts an EBS snapshot to the unified Snapshot type
+func ebsToSnapshot(snap ec2types.Snapshot) types.Snapshot {
+	s := types.Snapshot{
+		ID:        deref(snap.SnapshotId),
+		Kind:      types.SnapshotKindDisk,
+		State:     ebsStateToSnapshotState(snap.State),
+		Progress:  parseProgress(snap.Progress),
+		SizeGB:    int64(aws.ToInt32(snap.VolumeSize)),
+		CreatedAt: aws.ToTime(snap.StartTime),
+		Tags:      fromEC2Tags(snap.Tags),
+		Provider:  "aws",
+		Raw:       snap,
+	}
+	s.Name = s.Tags["Name"]
+	s.SourceVM = s.Tags[provider.SnapshotSourceTag]
+	return s
+}
+
+func ebsStateToSnapshotState(state ec2types.SnapshotState) types.SnapshotState {
+	switch state {
+	case ec2types.SnapshotStateCompleted:
+		return types.SnapshotStateAvailable
+	case ec2types.SnapshotStateError:
+		return types.SnapshotStateFailed
+	default:
+		return types.SnapshotStatePending
+	}
+}
+
+// parseProgress parses an EBS progress string such as "45%"; -1 if unknown.
+func parseProgress(p *string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(deref(p), "%"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// vmDisplayName returns the VM name with its ID, or just the ID
+func vmDisplayName(vm *types.VM) string {
+	if vm.Name == "" {
+		return vm.ID
+	}
+	return fmt.Sprintf("%s (%s)", vm.Name, vm.ID)
+}
@@ -0,0 +1,218 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"golang.org/x/term"
+)
+
+// ssmSessionAPI is the subset of the SSM client used to open sessions.
+// Tests substitute a fake that points StreamUrl at a local server.
+type ssmSessionAPI interface {
+	StartSession(ctx context.Context, params *ssm.StartSessionInput, optFns ...func(*ssm.Options)) (*ssm.StartSessionOutput, error)
+	TerminateSession(ctx context.Context, params *ssm.TerminateSessionInput, optFns ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error)
+}
+
+// SSMTarget is a port reachable through an SSM-managed instance. An empty
+// Host forwards to the instance itself; otherwise the instance relays to
+// Host:Port (e.g. an RDS endpoint).
+type SSMTarget struct {
+	InstanceID string
+	Host       string
+	Port       int
+}
+
+func (t SSMTarget) sessionInput() *ssm.StartSessionInput {
+	input := &ssm.StartSessionInput{
+		Target:       aws.String(t.InstanceID),
+		DocumentName: aws.String("AWS-StartPortForwardingSession"),
+		Parameters: map[string][]string{
+			"portNumber": {strconv.Itoa(t.Port)},
+		},
+	}
+	if t.Host != "" {
+		input.DocumentName = aws.String("AWS-StartPortForwardingSessionToRemoteHost")
+		input.Parameters["host"] = []string{t.Host}
+	}
+	return input
+}
+
+// ssmSession is an open data channel plus the session ID to terminate.
+type ssmSession struct {
+	*SSMDataChannel
+	api       ssmSessionAPI
+	sessionID string
+}
+
+// startSSMSession calls StartSession and opens its data channel.
+func startSSMSession(ctx context.Context, api ssmSessionAPI, input *ssm.StartSessionInput) (*ssmSession, error) {
+	out, err := api.StartSession(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start SSM session: %w", err)
+	}
+
+	ch, err := openSSMDataChannel(ctx, aws.ToString(out.StreamUrl), aws.ToString(out.TokenValue))
+	if err != nil {
+		_, _ = api.TerminateSession(context.Background(), &ssm.TerminateSessionInput{SessionId: out.SessionId})
+		return nil, err
+	}
+	return &ssmSession{SSMDataChannel: ch, api: api, sessionID: aws.ToString(out.SessionId)}, nil
+}
+
+// Close ends the data channel and terminates the session server-side.
+func (s *ssmSession) Close() error {
+	err := s.SSMDataChannel.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = s.api.TerminateSession(ctx, &ssm.TerminateSessionInput{SessionId: aws.String(s.sessionID)})
+	return err
+}
+
+// DialSSM opens a single port-forwarding session to target and returns it
+// as a byte stream. Closing it terminates the session.
+func (c *Client) DialSSM(ctx context.Context, target SSMTarget) (io.ReadWriteCloser, error) {
+	return startSSMSession(ctx, c.SSM(), target.sessionInput())
+}
+
+// SSMListener accepts local TCP connections and forwards each one over its
+// own port-forwarding session, so several forwards can share one process.
+type SSMListener struct {
+	ln     net.Listener
+	api    ssmSessionAPI
+	target SSMTarget
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ListenSSM binds addr (e.g. "127.0.0.1:8888"; port 0 picks a free port)
+// and forwards accepted connections to target without the AWS CLI or
+// session-manager-plugin.
+func (c *Client) ListenSSM(ctx context.Context, addr string, target SSMTarget) (*SSMListener, error) {
+	return listenSSM(ctx, c.SSM(), addr, target)
+}
+
+func listenSSM(ctx context.Context, api ssmSessionAPI, addr string, target SSMTarget) (*SSMListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l := &SSMListener{ln: ln, api: api, target: target, ctx: ctx, cancel: cancel}
+
+	l.wg.Add(1)
+	go l.serve()
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	return l, nil
+}
+
+// Addr returns the local listening address.
+func (l *SSMListener) Addr() net.Addr { return l.ln.Addr() }
+
+// Close stops accepting connections and terminates open sessions.
+func (l *SSMListener) Close() error {
+	l.cancel()
+	l.wg.Wait()
+	return nil
+}
+
+// Wait blocks until the listener is closed or its context is cancelled.
+func (l *SSMListener) Wait() error {
+	<-l.ctx.Done()
+	l.wg.Wait()
+	return nil
+}
+
+func (l *SSMListener) serve() {
+	defer l.wg.Done()
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if l.ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "ssm: accept: %v\n", err)
+				l.cancel()
+			}
+			return
+		}
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			l.handle(conn)
+		}()
+	}
+}
+
+func (l *SSMListener) handle(local net.Conn) {
+	defer func() { _ = local.Close() }()
+
+	session, err := startSSMSession(l.ctx, l.api, l.target.sessionInput())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ssm: %v\n", err)
+		return
+	}
+	defer func() { _ = session.Close() }()
+
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(session, local); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(local, session); done <- struct{}{} }()
+
+	select {
+	case <-done:
+	case <-l.ctx.Done():
+	}
+}
+
+// StartShell opens an interactive Session Manager shell on the instance,
+// wired to the current terminal. Returns when the remote shell exits.
+func (c *Client) StartShell(ctx context.Context, instanceID string) error {
+	return startShell(ctx, c.SSM(), instanceID, os.Stdin, os.Stdout)
+}
+
+func startShell(ctx context.Context, api ssmSessionAPI, instanceID string, stdin *os.File, stdout io.Writer) error {
+	session, err := startSSMSession(ctx, api, &ssm.StartSessionInput{Target: aws.String(instanceID)})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = session.Close() }()
+
+	fd := int(stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("set terminal raw mode: %w", err)
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+
+		sendSize := func() {
+			if cols, rows, err := term.GetSize(fd); err == nil {
+				_ = session.SetSize(cols, rows)
+			}
+		}
+		sendSize()
+
+		defer watchResize(sendSize)()
+	}
+
+	// stdin is never closed, so only the output side decides when we're done
+	go func() { _, _ = io.Copy(session, stdin) }()
+
+	_, err = io.Copy(stdout, session)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
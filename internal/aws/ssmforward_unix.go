@@ -0,0 +1,25 @@
+//go:build !windows
+
+package aws
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize calls onResize whenever the terminal is resized (SIGWINCH)
+// until the returned stop function is called.
+func watchResize(onResize func()) (stop func()) {
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for range winch {
+			onResize()
+		}
+	}()
+	return func() {
+		signal.Stop(winch)
+		close(winch)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package aws
+
+// watchResize is a no-op on Windows, which has no SIGWINCH; the size sent
+// when the shell starts is kept.
+func watchResize(func()) (stop func()) {
+	return func() {}
+}
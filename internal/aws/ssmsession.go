@@ -0,0 +1,598 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Session Manager data channel framing. Every binary WebSocket message is a
+// ClientMessage: a fixed 116-byte header, a uint32 payload length and the
+// payload, all big-endian.
+const (
+	ssmHeaderLength     = 116
+	ssmMessageTypeLen   = 32
+	ssmClientVersion    = "1.1.0.0" // below 1.1.70 so port sessions stay un-multiplexed
+	ssmMaxInputPayload  = 1024
+	ssmHandshakeTimeout = 30 * time.Second
+	ssmPingInterval     = 5 * time.Minute
+	ssmResendInterval   = 100 * time.Millisecond
+	ssmResendTimeout    = time.Second
+)
+
+// Message types
+const (
+	ssmInputStreamData  = "input_stream_data"
+	ssmOutputStreamData = "output_stream_data"
+	ssmAcknowledge      = "acknowledge"
+	ssmChannelClosed    = "channel_closed"
+	ssmStartPublication = "start_publication"
+	ssmPausePublication = "pause_publication"
+)
+
+// Payload types
+const (
+	ssmPayloadOutput            uint32 = 1
+	ssmPayloadError             uint32 = 2
+	ssmPayloadSize              uint32 = 3
+	ssmPayloadHandshakeRequest  uint32 = 5
+	ssmPayloadHandshakeResponse uint32 = 6
+	ssmPayloadHandshakeComplete uint32 = 7
+	ssmPayloadFlag              uint32 = 10
+	ssmPayloadStdErr            uint32 = 11
+	ssmPayloadExitCode          uint32 = 12
+)
+
+// Flag payload values
+const (
+	ssmFlagDisconnectToPort   uint32 = 1
+	ssmFlagTerminateSession   uint32 = 2
+	ssmFlagConnectToPortError uint32 = 3
+)
+
+// ssmMessage is one data channel ClientMessage.
+type ssmMessage struct {
+	MessageType    string
+	SchemaVersion  uint32
+	CreatedDate    uint64 // ms since epoch
+	SequenceNumber int64
+	Flags          uint64
+	MessageID      uuid.UUID
+	PayloadType    uint32
+	Payload        []byte
+}
+
+// marshal encodes the message in wire format.
+func (m *ssmMessage) marshal() []byte {
+	buf := make([]byte, ssmHeaderLength+4+len(m.Payload))
+
+	binary.BigEndian.PutUint32(buf[0:], ssmHeaderLength)
+	copy(buf[4:4+ssmMessageTypeLen], bytes.Repeat([]byte{' '}, ssmMessageTypeLen))
+	copy(buf[4:4+ssmMessageTypeLen], m.MessageType)
+	binary.BigEndian.PutUint32(buf[36:], m.SchemaVersion)
+	binary.BigEndian.PutUint64(buf[40:], m.CreatedDate)
+	binary.BigEndian.PutUint64(buf[48:], uint64(m.SequenceNumber))
+	binary.BigEndian.PutUint64(buf[56:], m.Flags)
+	// The agent stores UUIDs least-significant half first
+	copy(buf[64:72], m.MessageID[8:])
+	copy(buf[72:80], m.MessageID[:8])
+	digest := sha256.Sum256(m.Payload)
+	copy(buf[80:112], digest[:])
+	binary.BigEndian.PutUint32(buf[112:], m.PayloadType)
+	binary.BigEndian.PutUint32(buf[116:], uint32(len(m.Payload)))
+	copy(buf[120:], m.Payload)
+	return buf
+}
+
+// unmarshalSSMMessage decodes a wire-format message and verifies its digest.
+func unmarshalSSMMessage(b []byte) (*ssmMessage, error) {
+	if len(b) < ssmHeaderLength+4 {
+		return nil, fmt.Errorf("short SSM message (%d bytes)", len(b))
+	}
+	headerLen := int(binary.BigEndian.Uint32(b[0:]))
+	if headerLen < ssmHeaderLength || len(b) < headerLen+4 {
+		return nil, fmt.Errorf("invalid SSM header length %d", headerLen)
+	}
+
+	m := &ssmMessage{
+		MessageType:    strings.TrimRight(string(b[4:4+ssmMessageTypeLen]), " \x00"),
+		SchemaVersion:  binary.BigEndian.Uint32(b[36:]),
+		CreatedDate:    binary.BigEndian.Uint64(b[40:]),
+		SequenceNumber: int64(binary.BigEndian.Uint64(b[48:])),
+		Flags:          binary.BigEndian.Uint64(b[56:]),
+		PayloadType:    binary.BigEndian.Uint32(b[112:]),
+	}
+	copy(m.MessageID[8:], b[64:72])
+	copy(m.MessageID[:8], b[72:80])
+
+	n := int(binary.BigEndian.Uint32(b[headerLen:]))
+	start := headerLen + 4
+	if n > len(b)-start {
+		return nil, fmt.Errorf("SSM payload length %d exceeds message", n)
+	}
+	m.Payload = b[start : start+n]
+
+	digest := sha256.Sum256(m.Payload)
+	if !bytes.Equal(digest[:], b[80:112]) {
+		return nil, fmt.Errorf("SSM payload digest mismatch")
+	}
+	return m, nil
+}
+
+// ssmHandshakeRequest is the agent's HandshakeRequest payload.
+type ssmHandshakeRequest struct {
+	AgentVersion           string
+	RequestedClientActions []struct {
+		ActionType       string
+		ActionParameters json.RawMessage
+	}
+}
+
+type ssmProcessedAction struct {
+	ActionType   string
+	ActionStatus int    // 1 success, 2 failed, 3 unsupported
+	Error        string `json:",omitempty"`
+}
+
+type ssmHandshakeResponse struct {
+	ClientVersion          string
+	ProcessedClientActions []ssmProcessedAction
+	Errors                 []string
+}
+
+// SSMDataChannel is a Session Manager data channel: the WebSocket stream
+// returned by StartSession. Read returns agent output in sequence order and
+// Write sends input; both may run concurrently.
+type SSMDataChannel struct {
+	ws *websocket.Conn
+
+	sessionType string // "Port", "Standard_Stream", ...
+
+	writeMu sync.Mutex
+	outSeq  int64
+	unacked map[int64]*ssmUnacked // input awaiting an agent ack, by sequence number
+
+	// reader state, owned by the goroutine calling Read
+	expectSeq int64
+	buffered  map[int64]*ssmMessage
+	pending   []byte
+
+	pubMu  sync.Mutex
+	pubCnd *sync.Cond
+	paused bool
+	closed bool
+
+	exitMu   sync.Mutex
+	exitCode *int
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// ssmUnacked is a sent input message kept for retransmission.
+type ssmUnacked struct {
+	raw    []byte
+	sentAt time.Time
+}
+
+// openSSMDataChannel dials the stream URL, sends the token and completes
+// the agent handshake.
+func openSSMDataChannel(ctx context.Context, streamURL, token string) (*SSMDataChannel, error) {
+	wsDialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: ssmHandshakeTimeout,
+	}
+	ws, _, err := wsDialer.DialContext(ctx, streamURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("connect to SSM data channel: %w", err)
+	}
+
+	ch := &SSMDataChannel{
+		ws:       ws,
+		buffered: make(map[int64]*ssmMessage),
+		unacked:  make(map[int64]*ssmUnacked),
+		done:     make(chan struct{}),
+	}
+	ch.pubCnd = sync.NewCond(&ch.pubMu)
+
+	open, err := json.Marshal(map[string]string{
+		"MessageSchemaVersion": "1.0",
+		"RequestId":            uuid.NewString(),
+		"TokenValue":           token,
+		"ClientId":             uuid.NewString(),
+		"ClientVersion":        ssmClientVersion,
+	})
+	if err != nil {
+		_ = ws.Close()
+		return nil, err
+	}
+	if err := ws.WriteMessage(websocket.TextMessage, open); err != nil {
+		_ = ws.Close()
+		return nil, fmt.Errorf("open SSM data channel: %w", err)
+	}
+
+	go ch.resend()
+	if err := ch.handshake(ctx); err != nil {
+		_ = ch.Close()
+		return nil, err
+	}
+
+	go ch.keepAlive()
+	return ch, nil
+}
+
+// handshake processes agent messages until HandshakeComplete. Output that
+// arrives early is kept for the first Read.
+func (ch *SSMDataChannel) handshake(ctx context.Context) error {
+	deadline := time.Now().Add(ssmHandshakeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = ch.ws.SetReadDeadline(deadline)
+	defer func() { _ = ch.ws.SetReadDeadline(time.Time{}) }()
+
+	complete := false
+	for !complete {
+		msg, err := ch.next()
+		if err != nil {
+			return fmt.Errorf("SSM handshake: %w", err)
+		}
+		switch msg.PayloadType {
+		case ssmPayloadHandshakeRequest:
+			if err := ch.respondHandshake(msg.Payload); err != nil {
+				return err
+			}
+		case ssmPayloadHandshakeComplete:
+			complete = true
+		default:
+			if err := ch.apply(msg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ch *SSMDataChannel) respondHandshake(payload []byte) error {
+	var req ssmHandshakeRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("decode SSM handshake request: %w", err)
+	}
+
+	resp := ssmHandshakeResponse{ClientVersion: ssmClientVersion, Errors: []string{}}
+	var unsupported error
+	for _, action := range req.RequestedClientActions {
+		switch action.ActionType {
+		case "SessionType":
+			var params struct{ SessionType string }
+			_ = json.Unmarshal(action.ActionParameters, &params)
+			ch.sessionType = params.SessionType
+			resp.ProcessedClientActions = append(resp.ProcessedClientActions,
+				ssmProcessedAction{ActionType: action.ActionType, ActionStatus: 1})
+		default:
+			// KMSEncryption and anything newer need the session-manager-plugin
+			unsupported = fmt.Errorf("SSM session requires %s, which cml does not support natively", action.ActionType)
+			resp.ProcessedClientActions = append(resp.ProcessedClientActions,
+				ssmProcessedAction{ActionType: action.ActionType, ActionStatus: 3, Error: unsupported.Error()})
+		}
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if err := ch.send(ssmPayloadHandshakeResponse, body); err != nil {
+		return fmt.Errorf("send SSM handshake response: %w", err)
+	}
+	return unsupported
+}
+
+// next returns the next agent message in sequence order. Acknowledgements
+// are sent for every output message, including duplicates.
+func (ch *SSMDataChannel) next() (*ssmMessage, error) {
+	for {
+		if msg, ok := ch.buffered[ch.expectSeq]; ok {
+			delete(ch.buffered, ch.expectSeq)
+			ch.expectSeq++
+			return msg, nil
+		}
+
+		msgType, raw, err := ch.ws.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if errors.As(err, &ce) && ce.Code == websocket.CloseNormalClosure {
+				return nil, io.EOF
+			}
+			select {
+			case <-ch.done:
+				return nil, io.EOF
+			default:
+			}
+			return nil, err
+		}
+		if msgType != websocket.BinaryMessage {
+			continue
+		}
+		msg, err := unmarshalSSMMessage(raw)
+		if err != nil {
+			// The agent resends unacknowledged messages; drop corrupt ones
+			continue
+		}
+
+		switch msg.MessageType {
+		case ssmOutputStreamData:
+			if err := ch.ack(msg); err != nil {
+				return nil, err
+			}
+			switch {
+			case msg.SequenceNumber == ch.expectSeq:
+				ch.expectSeq++
+				return msg, nil
+			case msg.SequenceNumber > ch.expectSeq:
+				ch.buffered[msg.SequenceNumber] = msg
+			}
+		case ssmChannelClosed:
+			var closed struct{ Output string }
+			_ = json.Unmarshal(msg.Payload, &closed)
+			if closed.Output != "" {
+				return nil, fmt.Errorf("SSM channel closed: %s", closed.Output)
+			}
+			return nil, io.EOF
+		case ssmPausePublication:
+			ch.setPaused(true)
+		case ssmStartPublication:
+			ch.setPaused(false)
+		case ssmAcknowledge:
+			var ack struct {
+				AcknowledgedMessageType           string
+				AcknowledgedMessageSequenceNumber int64
+			}
+			if err := json.Unmarshal(msg.Payload, &ack); err == nil && ack.AcknowledgedMessageType == ssmInputStreamData {
+				ch.writeMu.Lock()
+				delete(ch.unacked, ack.AcknowledgedMessageSequenceNumber)
+				ch.writeMu.Unlock()
+			}
+		}
+	}
+}
+
+// apply handles a non-handshake agent message.
+func (ch *SSMDataChannel) apply(msg *ssmMessage) error {
+	switch msg.PayloadType {
+	case ssmPayloadOutput, ssmPayloadError, ssmPayloadStdErr:
+		ch.pending = append(ch.pending, msg.Payload...)
+	case ssmPayloadExitCode:
+		var code int
+		if _, err := fmt.Sscanf(strings.TrimSpace(string(msg.Payload)), "%d", &code); err == nil {
+			ch.exitMu.Lock()
+			ch.exitCode = &code
+			ch.exitMu.Unlock()
+		}
+	case ssmPayloadFlag:
+		if len(msg.Payload) >= 4 {
+			switch binary.BigEndian.Uint32(msg.Payload) {
+			case ssmFlagConnectToPortError:
+				return fmt.Errorf("SSM agent could not connect to the target port")
+			case ssmFlagDisconnectToPort, ssmFlagTerminateSession:
+				return io.EOF
+			}
+		}
+	}
+	return nil
+}
+
+func (ch *SSMDataChannel) ack(msg *ssmMessage) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"AcknowledgedMessageType":           msg.MessageType,
+		"AcknowledgedMessageId":             msg.MessageID.String(),
+		"AcknowledgedMessageSequenceNumber": msg.SequenceNumber,
+		"IsSequentialMessage":               true,
+	})
+	if err != nil {
+		return err
+	}
+	return ch.writeMessage(&ssmMessage{
+		MessageType:   ssmAcknowledge,
+		SchemaVersion: 1,
+		CreatedDate:   uint64(time.Now().UnixMilli()),
+		Flags:         3,
+		MessageID:     uuid.New(),
+		Payload:       body,
+	})
+}
+
+// send writes one input_stream_data message with the next sequence number
+// and keeps it until the agent acknowledges it.
+func (ch *SSMDataChannel) send(payloadType uint32, payload []byte) error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+
+	msg := &ssmMessage{
+		MessageType:    ssmInputStreamData,
+		SchemaVersion:  1,
+		CreatedDate:    uint64(time.Now().UnixMilli()),
+		SequenceNumber: ch.outSeq,
+		MessageID:      uuid.New(),
+		PayloadType:    payloadType,
+		Payload:        payload,
+	}
+	raw := msg.marshal()
+	if err := ch.ws.WriteMessage(websocket.BinaryMessage, raw); err != nil {
+		return err
+	}
+	ch.unacked[msg.SequenceNumber] = &ssmUnacked{raw: raw, sentAt: time.Now()}
+	ch.outSeq++
+	return nil
+}
+
+func (ch *SSMDataChannel) writeMessage(msg *ssmMessage) error {
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	return ch.ws.WriteMessage(websocket.BinaryMessage, msg.marshal())
+}
+
+func (ch *SSMDataChannel) setPaused(paused bool) {
+	ch.pubMu.Lock()
+	ch.paused = paused
+	ch.pubMu.Unlock()
+	ch.pubCnd.Broadcast()
+}
+
+// waitPublication blocks while the agent has paused input.
+func (ch *SSMDataChannel) waitPublication() error {
+	ch.pubMu.Lock()
+	defer ch.pubMu.Unlock()
+	for ch.paused && !ch.closed {
+		ch.pubCnd.Wait()
+	}
+	if ch.closed {
+		return io.ErrClosedPipe
+	}
+	return nil
+}
+
+// SessionType returns the session type reported in the handshake.
+func (ch *SSMDataChannel) SessionType() string { return ch.sessionType }
+
+// ExitCode returns the remote exit code if the agent reported one.
+func (ch *SSMDataChannel) ExitCode() (int, bool) {
+	ch.exitMu.Lock()
+	defer ch.exitMu.Unlock()
+	if ch.exitCode == nil {
+		return 0, false
+	}
+	return *ch.exitCode, true
+}
+
+// Read returns agent output. It returns io.EOF when the session ends.
+func (ch *SSMDataChannel) Read(p []byte) (int, error) {
+	for len(ch.pending) == 0 {
+		msg, err := ch.next()
+		if err != nil {
+			return 0, err
+		}
+		if err := ch.apply(msg); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, ch.pending)
+	ch.pending = ch.pending[n:]
+	return n, nil
+}
+
+// Write sends input to the agent in 1 KiB messages, honouring flow control.
+func (ch *SSMDataChannel) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		if err := ch.waitPublication(); err != nil {
+			return written, err
+		}
+		end := written + ssmMaxInputPayload
+		if end > len(p) {
+			end = len(p)
+		}
+		if err := ch.send(ssmPayloadOutput, p[written:end]); err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+// SetSize reports the terminal size for interactive sessions.
+func (ch *SSMDataChannel) SetSize(cols, rows int) error {
+	body, err := json.Marshal(map[string]int{"cols": cols, "rows": rows})
+	if err != nil {
+		return err
+	}
+	return ch.send(ssmPayloadSize, body)
+}
+
+// Close asks the agent to end the session and closes the stream.
+func (ch *SSMDataChannel) Close() error {
+	var err error
+	ch.closeOnce.Do(func() {
+		close(ch.done)
+
+		flag := make([]byte, 4)
+		binary.BigEndian.PutUint32(flag, ssmFlagTerminateSession)
+		_ = ch.send(ssmPayloadFlag, flag)
+
+		ch.pubMu.Lock()
+		ch.closed = true
+		ch.pubMu.Unlock()
+		ch.pubCnd.Broadcast()
+
+		ch.writeMu.Lock()
+		_ = ch.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		ch.writeMu.Unlock()
+		err = ch.ws.Close()
+	})
+	return err
+}
+
+// keepAlive pings the stream so idle sessions are not dropped.
+func (ch *SSMDataChannel) keepAlive() {
+	ticker := time.NewTicker(ssmPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ch.done:
+			return
+		case <-ticker.C:
+			ch.writeMu.Lock()
+			err := ch.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+			ch.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// resend retransmits input the agent has not acknowledged within
+// ssmResendTimeout, in sequence order, as the session-manager-plugin does.
+// The agent drops duplicates it has already received.
+func (ch *SSMDataChannel) resend() {
+	ticker := time.NewTicker(ssmResendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ch.done:
+			return
+		case now := <-ticker.C:
+			ch.writeMu.Lock()
+			var err error
+			for _, seq := range slices.Sorted(maps.Keys(ch.unacked)) {
+				u := ch.unacked[seq]
+				if now.Sub(u.sentAt) < ssmResendTimeout {
+					continue
+				}
+				if err = ch.ws.WriteMessage(websocket.BinaryMessage, u.raw); err != nil {
+					break
+				}
+				u.sentAt = now
+			}
+			ch.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
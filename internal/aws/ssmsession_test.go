@@ -0,0 +1,672 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// fakeSSMAgent is the agent end of a data channel, served by newFakeSSMServer.
+// Like the real agent it acknowledges input and drops duplicates.
+type fakeSSMAgent struct {
+	t      *testing.T
+	ws     *websocket.Conn
+	token  string
+	seq    int64
+	inputs chan *ssmMessage // input_stream_data from the client, once each
+	acks   chan *ssmMessage // acknowledge from the client
+
+	writeMu sync.Mutex
+
+	mu    sync.Mutex
+	inSeq int64                 // next input sequence number to deliver
+	early map[int64]*ssmMessage // input received ahead of inSeq
+	lose  map[int64]bool        // input to discard unacknowledged once
+	dups  []int64               // sequence numbers received more than once
+}
+
+// newFakeSSMServer starts a WebSocket server that runs agent for every
+// connection and returns its ws:// URL.
+func newFakeSSMServer(t *testing.T, agent func(a *fakeSSMAgent)) string {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer func() { _ = ws.Close() }()
+
+		msgType, open, err := ws.ReadMessage()
+		if err != nil || msgType != websocket.TextMessage {
+			t.Errorf("open message: type %d, err %v", msgType, err)
+			return
+		}
+		var req struct{ TokenValue, ClientVersion string }
+		if err := json.Unmarshal(open, &req); err != nil {
+			t.Errorf("decode open message: %v", err)
+			return
+		}
+
+		a := &fakeSSMAgent{
+			t:      t,
+			ws:     ws,
+			token:  req.TokenValue,
+			inputs: make(chan *ssmMessage, 64),
+			acks:   make(chan *ssmMessage, 64),
+		}
+		go a.readLoop()
+		agent(a)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func (a *fakeSSMAgent) readLoop() {
+	defer close(a.inputs)
+	for {
+		_, raw, err := a.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg, err := unmarshalSSMMessage(raw)
+		if err != nil {
+			a.t.Errorf("client sent invalid message: %v", err)
+			return
+		}
+		switch msg.MessageType {
+		case ssmAcknowledge:
+			a.acks <- msg
+		case ssmInputStreamData:
+			for _, in := range a.receive(msg) {
+				a.inputs <- in
+			}
+		}
+	}
+}
+
+// receive acknowledges an input message and returns the input that is now
+// ready in sequence order.
+func (a *fakeSSMAgent) receive(msg *ssmMessage) []*ssmMessage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.lose[msg.SequenceNumber] {
+		delete(a.lose, msg.SequenceNumber)
+		return nil
+	}
+	if msg.SequenceNumber < a.inSeq || a.early[msg.SequenceNumber] != nil {
+		a.dups = append(a.dups, msg.SequenceNumber)
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"AcknowledgedMessageType":           msg.MessageType,
+		"AcknowledgedMessageId":             msg.MessageID.String(),
+		"AcknowledgedMessageSequenceNumber": msg.SequenceNumber,
+		"IsSequentialMessage":               true,
+	})
+	ack := &ssmMessage{MessageType: ssmAcknowledge, MessageID: uuid.New(), Payload: body}
+	// The client may already have closed after its last input
+	a.writeMu.Lock()
+	_ = a.ws.WriteMessage(websocket.BinaryMessage, ack.marshal())
+	a.writeMu.Unlock()
+	if msg.SequenceNumber < a.inSeq {
+		return nil
+	}
+	if a.early == nil {
+		a.early = make(map[int64]*ssmMessage)
+	}
+	a.early[msg.SequenceNumber] = msg
+
+	var ready []*ssmMessage
+	for a.early[a.inSeq] != nil {
+		ready = append(ready, a.early[a.inSeq])
+		delete(a.early, a.inSeq)
+		a.inSeq++
+	}
+	return ready
+}
+
+// send writes an output_stream_data message with the next sequence number.
+func (a *fakeSSMAgent) send(payloadType uint32, payload []byte) {
+	a.sendMessage(&ssmMessage{
+		MessageType:    ssmOutputStreamData,
+		SchemaVersion:  1,
+		SequenceNumber: a.seq,
+		MessageID:      uuid.New(),
+		PayloadType:    payloadType,
+		Payload:        payload,
+	})
+	a.seq++
+}
+
+func (a *fakeSSMAgent) sendMessage(msg *ssmMessage) {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	if err := a.ws.WriteMessage(websocket.BinaryMessage, msg.marshal()); err != nil {
+		a.t.Errorf("agent write: %v", err)
+	}
+}
+
+func (a *fakeSSMAgent) input() *ssmMessage {
+	select {
+	case msg, ok := <-a.inputs:
+		if !ok {
+			a.t.Fatal("client closed the channel")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		a.t.Fatal("timed out waiting for client input")
+		return nil
+	}
+}
+
+func (a *fakeSSMAgent) ack() *ssmMessage {
+	select {
+	case msg := <-a.acks:
+		return msg
+	case <-time.After(5 * time.Second):
+		a.t.Fatal("timed out waiting for acknowledge")
+		return nil
+	}
+}
+
+// ackedSequence returns the sequence number an acknowledge message names
+func ackedSequence(msg *ssmMessage) int64 {
+	var p struct{ AcknowledgedMessageSequenceNumber int64 }
+	_ = json.Unmarshal(msg.Payload, &p)
+	return p.AcknowledgedMessageSequenceNumber
+}
+
+// handshake runs the agent side of the handshake, requesting actions, and
+// returns the client's response.
+func (a *fakeSSMAgent) handshake(actions ...string) ssmHandshakeResponse {
+	req := map[string]interface{}{"AgentVersion": "3.3.0.0"}
+	var requested []map[string]interface{}
+	for _, action := range actions {
+		r := map[string]interface{}{"ActionType": action}
+		if action == "SessionType" {
+			r["ActionParameters"] = map[string]string{"SessionType": "Port"}
+		}
+		requested = append(requested, r)
+	}
+	req["RequestedClientActions"] = requested
+	body, _ := json.Marshal(req)
+	a.send(ssmPayloadHandshakeRequest, body)
+
+	if ack := a.ack(); ack == nil {
+		return ssmHandshakeResponse{}
+	}
+	msg := a.input()
+	if msg.PayloadType != ssmPayloadHandshakeResponse {
+		a.t.Fatalf("payload type = %d, want handshake response", msg.PayloadType)
+	}
+	var resp ssmHandshakeResponse
+	if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+		a.t.Fatalf("decode handshake response: %v", err)
+	}
+	return resp
+}
+
+// echo completes the handshake and echoes input until the client closes.
+func (a *fakeSSMAgent) echo() {
+	a.handshake("SessionType")
+	a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+	for msg := range a.inputs {
+		switch msg.PayloadType {
+		case ssmPayloadOutput:
+			a.send(ssmPayloadOutput, msg.Payload)
+		case ssmPayloadFlag:
+			return
+		}
+	}
+}
+
+func TestSSMMessageRoundTrip(t *testing.T) {
+	in := &ssmMessage{
+		MessageType:    ssmInputStreamData,
+		SchemaVersion:  1,
+		CreatedDate:    1700000000000,
+		SequenceNumber: 42,
+		Flags:          3,
+		MessageID:      uuid.MustParse("00112233-4455-6677-8899-aabbccddeeff"),
+		PayloadType:    ssmPayloadOutput,
+		Payload:        []byte("hello"),
+	}
+	raw := in.marshal()
+
+	if got := binary.BigEndian.Uint32(raw[0:]); got != ssmHeaderLength {
+		t.Errorf("header length = %d, want %d", got, ssmHeaderLength)
+	}
+	if got := string(raw[4:36]); got != ssmInputStreamData+strings.Repeat(" ", 32-len(ssmInputStreamData)) {
+		t.Errorf("message type field = %q", got)
+	}
+	// The agent expects the UUID's halves swapped
+	if !bytes.Equal(raw[64:72], in.MessageID[8:]) || !bytes.Equal(raw[72:80], in.MessageID[:8]) {
+		t.Errorf("message ID bytes = %x", raw[64:80])
+	}
+	if len(raw) != ssmHeaderLength+4+len(in.Payload) {
+		t.Errorf("length = %d", len(raw))
+	}
+
+	out, err := unmarshalSSMMessage(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.MessageType != in.MessageType || out.SchemaVersion != in.SchemaVersion ||
+		out.CreatedDate != in.CreatedDate || out.SequenceNumber != in.SequenceNumber ||
+		out.Flags != in.Flags || out.MessageID != in.MessageID ||
+		out.PayloadType != in.PayloadType || string(out.Payload) != "hello" {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestSSMMessageInvalid(t *testing.T) {
+	valid := (&ssmMessage{MessageType: ssmOutputStreamData, Payload: []byte("data")}).marshal()
+
+	corrupt := bytes.Clone(valid)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	badDigest := bytes.Clone(valid)
+	badDigest[80] ^= 0xff
+
+	overlong := bytes.Clone(valid)
+	binary.BigEndian.PutUint32(overlong[116:], 1000)
+
+	badHeader := bytes.Clone(valid)
+	binary.BigEndian.PutUint32(badHeader[0:], 10)
+
+	tests := []struct {
+		name string
+		raw  []byte
+		want string
+	}{
+		{"short", valid[:100], "short"},
+		{"corrupt payload", corrupt, "digest"},
+		{"corrupt digest", badDigest, "digest"},
+		{"payload length past end", overlong, "exceeds"},
+		{"header length too small", badHeader, "header length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := unmarshalSSMMessage(tt.raw)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSMHandshake(t *testing.T) {
+	responses := make(chan ssmHandshakeResponse, 1)
+	acks := make(chan []int64, 1)
+	tokens := make(chan string, 1)
+
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		tokens <- a.token
+		responses <- a.handshake("SessionType")
+		a.send(ssmPayloadOutput, []byte("early"))
+		a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+		acks <- []int64{ackedSequence(a.ack()), ackedSequence(a.ack())}
+		// Keep the channel open until the client closes it
+		for range a.inputs {
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ch, err := openSSMDataChannel(ctx, url, "tok-123")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	if got := <-tokens; got != "tok-123" {
+		t.Errorf("token = %q", got)
+	}
+	resp := <-responses
+	if resp.ClientVersion != ssmClientVersion {
+		t.Errorf("client version = %q", resp.ClientVersion)
+	}
+	if len(resp.ProcessedClientActions) != 1 || resp.ProcessedClientActions[0].ActionStatus != 1 {
+		t.Errorf("processed actions = %+v", resp.ProcessedClientActions)
+	}
+	if ch.SessionType() != "Port" {
+		t.Errorf("session type = %q", ch.SessionType())
+	}
+
+	// The handshake request, early output and HandshakeComplete are each
+	// acknowledged; the first ack was consumed by handshake()
+	if seqs := <-acks; seqs[0] != 1 || seqs[1] != 2 {
+		t.Errorf("acknowledged sequence numbers = %v, want [1 2]", seqs)
+	}
+
+	// Output sent before HandshakeComplete is kept for the first Read
+	buf := make([]byte, 16)
+	n, err := ch.Read(buf)
+	if err != nil || string(buf[:n]) != "early" {
+		t.Errorf("Read = %q, %v", buf[:n], err)
+	}
+}
+
+func TestSSMHandshakeAcknowledgesSequence(t *testing.T) {
+	payloads := make(chan map[string]interface{}, 1)
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		a.handshake("SessionType")
+		a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+		var p map[string]interface{}
+		_ = json.Unmarshal(a.ack().Payload, &p)
+		payloads <- p
+		for range a.inputs {
+		}
+	})
+
+	ch, err := openSSMDataChannel(context.Background(), url, "tok")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	p := <-payloads
+	if p["AcknowledgedMessageType"] != ssmOutputStreamData || p["AcknowledgedMessageSequenceNumber"] != float64(1) {
+		t.Errorf("ack payload = %v", p)
+	}
+}
+
+func TestSSMHandshakeUnsupportedAction(t *testing.T) {
+	responses := make(chan ssmHandshakeResponse, 1)
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		responses <- a.handshake("SessionType", "KMSEncryption")
+		for range a.inputs {
+		}
+	})
+
+	_, err := openSSMDataChannel(context.Background(), url, "tok")
+	if err == nil || !strings.Contains(err.Error(), "KMSEncryption") {
+		t.Fatalf("err = %v, want KMSEncryption unsupported", err)
+	}
+	resp := <-responses
+	if len(resp.ProcessedClientActions) != 2 || resp.ProcessedClientActions[1].ActionStatus != 3 {
+		t.Errorf("processed actions = %+v", resp.ProcessedClientActions)
+	}
+}
+
+func TestSSMReadReordersOutput(t *testing.T) {
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		a.handshake("SessionType")
+		a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+
+		// Send seq 3 before seq 2, and repeat seq 2
+		first := a.seq
+		a.seq = first + 1
+		a.send(ssmPayloadOutput, []byte("b"))
+		a.seq = first
+		a.send(ssmPayloadOutput, []byte("a"))
+		a.seq = first
+		a.send(ssmPayloadOutput, []byte("a"))
+		a.seq = first + 2
+		a.sendMessage(&ssmMessage{
+			MessageType: ssmChannelClosed,
+			MessageID:   uuid.New(),
+			Payload:     []byte(`{}`),
+		})
+		for range a.inputs {
+		}
+	})
+
+	ch, err := openSSMDataChannel(context.Background(), url, "tok")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	got, err := io.ReadAll(ch)
+	if err != nil || string(got) != "ab" {
+		t.Errorf("ReadAll = %q, %v; want \"ab\"", got, err)
+	}
+}
+
+func TestSSMPublicationPause(t *testing.T) {
+	start := make(chan struct{})
+	finished := make(chan struct{})
+	defer close(finished)
+	received := make(chan string, 4)
+
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		a.handshake("SessionType")
+		a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+
+		a.sendMessage(&ssmMessage{MessageType: ssmPausePublication, MessageID: uuid.New()})
+		// Output after the pause tells the client the pause was processed
+		a.send(ssmPayloadOutput, []byte("paused"))
+
+		go func() {
+			for msg := range a.inputs {
+				if msg.PayloadType == ssmPayloadOutput {
+					received <- string(msg.Payload)
+				}
+			}
+		}()
+
+		<-start
+		a.sendMessage(&ssmMessage{MessageType: ssmStartPublication, MessageID: uuid.New()})
+		a.send(ssmPayloadOutput, []byte("resumed"))
+		<-finished
+	})
+
+	ch, err := openSSMDataChannel(context.Background(), url, "tok")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	buf := make([]byte, 16)
+	if n, err := ch.Read(buf); err != nil || string(buf[:n]) != "paused" {
+		t.Fatalf("Read = %q, %v", buf[:n], err)
+	}
+
+	wrote := make(chan error, 1)
+	go func() {
+		_, err := ch.Write([]byte("input"))
+		wrote <- err
+	}()
+
+	select {
+	case <-wrote:
+		t.Fatal("Write returned while publication was paused")
+	case msg := <-received:
+		t.Fatalf("agent received %q while paused", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// start_publication is processed by the reader
+	close(start)
+	go func() { _, _ = ch.Read(buf) }()
+
+	select {
+	case err := <-wrote:
+		if err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write still blocked after start_publication")
+	}
+	if msg := <-received; msg != "input" {
+		t.Errorf("agent received %q", msg)
+	}
+}
+
+func TestSSMWriteSplitsInput(t *testing.T) {
+	sizes := make(chan []int, 1)
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		a.handshake("SessionType")
+		a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+		var got []int
+		var seqs []int64
+		for len(got) < 3 {
+			msg := a.input()
+			got = append(got, len(msg.Payload))
+			seqs = append(seqs, msg.SequenceNumber)
+		}
+		// The handshake response took sequence number 0
+		if seqs[0] != 1 || seqs[1] != 2 || seqs[2] != 3 {
+			a.t.Errorf("input sequence numbers = %v", seqs)
+		}
+		sizes <- got
+		for range a.inputs {
+		}
+	})
+
+	ch, err := openSSMDataChannel(context.Background(), url, "tok")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+
+	if _, err := ch.Write(make([]byte, 2*ssmMaxInputPayload+10)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got := <-sizes
+	if got[0] != ssmMaxInputPayload || got[1] != ssmMaxInputPayload || got[2] != 10 {
+		t.Errorf("message sizes = %v", got)
+	}
+}
+
+func TestSSMResendsUnacknowledgedInput(t *testing.T) {
+	received := make(chan []string, 1)
+	dups := make(chan []int64, 1)
+	url := newFakeSSMServer(t, func(a *fakeSSMAgent) {
+		// Lose the first input after the handshake response
+		a.mu.Lock()
+		a.lose = map[int64]bool{1: true}
+		a.mu.Unlock()
+
+		a.handshake("SessionType")
+		a.send(ssmPayloadHandshakeComplete, []byte(`{}`))
+		got := []string{string(a.input().Payload), string(a.input().Payload)}
+		received <- got
+
+		// Both inputs are acknowledged now, so neither may arrive again
+		time.Sleep(ssmResendTimeout + 3*ssmResendInterval)
+		a.mu.Lock()
+		dups <- a.dups
+		a.mu.Unlock()
+		for range a.inputs {
+		}
+	})
+
+	ch, err := openSSMDataChannel(context.Background(), url, "tok")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = ch.Close() }()
+	// Acknowledgements are processed by the reader
+	go func() { _, _ = io.Copy(io.Discard, ch) }()
+
+	for _, s := range []string{"a", "b"} {
+		if _, err := ch.Write([]byte(s)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	// "a" only arrives if it is resent
+	if got := <-received; got[0] != "a" || got[1] != "b" {
+		t.Errorf("agent received %q, want [a b]", got)
+	}
+	if got := <-dups; len(got) != 0 {
+		t.Errorf("acknowledged input resent: sequence numbers %v", got)
+	}
+	ch.writeMu.Lock()
+	defer ch.writeMu.Unlock()
+	if len(ch.unacked) != 0 {
+		t.Errorf("%d input messages still unacknowledged", len(ch.unacked))
+	}
+}
+
+// fakeSSMAPI starts sessions on a fake agent server.
+type fakeSSMAPI struct {
+	url string
+
+	mu         sync.Mutex
+	started    []*ssm.StartSessionInput
+	terminated []string
+}
+
+func (f *fakeSSMAPI) StartSession(_ context.Context, in *ssm.StartSessionInput, _ ...func(*ssm.Options)) (*ssm.StartSessionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = append(f.started, in)
+	return &ssm.StartSessionOutput{
+		SessionId:  aws.String("sess-1"),
+		StreamUrl:  aws.String(f.url),
+		TokenValue: aws.String("tok"),
+	}, nil
+}
+
+func (f *fakeSSMAPI) TerminateSession(_ context.Context, in *ssm.TerminateSessionInput, _ ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.terminated = append(f.terminated, aws.ToString(in.SessionId))
+	return &ssm.TerminateSessionOutput{}, nil
+}
+
+func TestListenSSM(t *testing.T) {
+	api := &fakeSSMAPI{url: newFakeSSMServer(t, func(a *fakeSSMAgent) { a.echo() })}
+
+	target := SSMTarget{InstanceID: "i-0123456789abcdef0", Host: "db.internal", Port: 5432}
+	l, err := listenSSM(context.Background(), api, "127.0.0.1:0", target)
+	if err != nil {
+		t.Fatalf("listenSSM: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	payload := bytes.Repeat([]byte("0123456789"), 300) // spans several input messages
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("echoed bytes differ")
+	}
+
+	_ = conn.Close()
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.started) != 1 {
+		t.Fatalf("StartSession calls = %d", len(api.started))
+	}
+	in := api.started[0]
+	if aws.ToString(in.Target) != target.InstanceID ||
+		aws.ToString(in.DocumentName) != "AWS-StartPortForwardingSessionToRemoteHost" ||
+		in.Parameters["host"][0] != "db.internal" || in.Parameters["portNumber"][0] != "5432" {
+		t.Errorf("StartSession input = %+v", in)
+	}
+	if len(api.terminated) != 1 || api.terminated[0] != "sess-1" {
+		t.Errorf("terminated = %v", api.terminated)
+	}
+}
@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// SetTags adds or overwrites tags on an EC2 instance
+func (p *AWSVMProvider) SetTags(ctx context.Context, vm *types.VM, tags map[string]string) error {
+	for k := range tags {
+		if err := checkTagKey(k); err != nil {
+			return err
+		}
+	}
+	return p.tagInstance(ctx, vm.ID, tags)
+}
+
+// RemoveTags deletes tags from an EC2 instance. Missing keys are ignored.
+func (p *AWSVMProvider) RemoveTags(ctx context.Context, vm *types.VM, keys []string) error {
+	ec2Tags := make([]ec2types.Tag, 0, len(keys))
+	for _, k := range keys {
+		if err := checkTagKey(k); err != nil {
+			return err
+		}
+		ec2Tags = append(ec2Tags, ec2types.Tag{Key: aws.String(k)})
+	}
+	_, err := p.client.EC2().DeleteTags(ctx, &ec2.DeleteTagsInput{
+		Resources: []string{vm.ID},
+		Tags:      ec2Tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to untag instance %s: %w", vm.ID, err)
+	}
+	return nil
+}
+
+// checkTagKey rejects the reserved aws: prefix, which EC2 refuses anyway
+// but with a less helpful error.
+func checkTagKey(key string) error {
+	if strings.HasPrefix(strings.ToLower(key), "aws:") {
+		return fmt.Errorf("tag %q: keys starting with aws: are reserved", key)
+	}
+	return nil
+}
+
+// tagInstance sets tags on an instance
+func (p *AWSVMProvider) tagInstance(ctx context.Context, instanceID string, tags map[string]string) error {
+	_, err := p.client.EC2().CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{instanceID},
+		Tags:      toEC2Tags(tags),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to tag instance %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// toEC2Tags converts a tag map to EC2 tags, keys in sorted order
+func toEC2Tags(tags map[string]string) []ec2types.Tag {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]ec2types.Tag, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, ec2types.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return out
+}
+
+func fromEC2Tags(tags []ec2types.Tag) map[string]string {
+	out := make(map[string]string, len(tags))
+	for _, t := range tags {
+		out[deref(t.Key)] = deref(t.Value)
+	}
+	return out
+}
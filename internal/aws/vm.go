@@ -2,20 +2,25 @@ package aws
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"strconv"
+	"regexp"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 
 	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/query"
 	"github.com/vietdv277/cumulus/pkg/types"
 )
 
+// instanceIDPattern matches EC2 instance IDs in the short (8 hex digits) and
+// long (17 hex digits) formats
+var instanceIDPattern = regexp.MustCompile(`^i-[0-9a-f]{8}([0-9a-f]{9})?$`)
+
 // AWSVMProvider implements the VMProvider interface for AWS EC2
 type AWSVMProvider struct {
 	client  *Client
@@ -38,12 +43,15 @@ func (p *AWSVMProvider) List(ctx context.Context, filter *provider.VMFilter) ([]
 	filters := []ec2types.Filter{}
 
 	// State filter
-	if filter != nil && filter.State != "" {
+	switch {
+	case filter != nil && filter.State == "all":
+		// no state filter
+	case filter != nil && filter.State != "":
 		filters = append(filters, ec2types.Filter{
 			Name:   aws.String("instance-state-name"),
 			Values: []string{filter.State},
 		})
-	} else {
+	default:
 		// Default to running instances
 		filters = append(filters, ec2types.Filter{
 			Name:   aws.String("instance-state-name"),
@@ -69,6 +77,11 @@ func (p *AWSVMProvider) List(ctx context.Context, filter *provider.VMFilter) ([]
 		}
 	}
 
+	// --where conditions EC2 can evaluate server-side
+	if filter != nil && filter.Where != nil {
+		filters = append(filters, whereToEC2Filters(filter.Where)...)
+	}
+
 	// Call AWS API
 	input := &ec2.DescribeInstancesInput{
 		Filters: filters,
@@ -172,84 +185,96 @@ func (p *AWSVMProvider) Reboot(ctx context.Context, nameOrID string) error {
 	return err
 }
 
-// Connect establishes an SSM session to the instance
+// Connect opens an interactive Session Manager shell on the instance.
 func (p *AWSVMProvider) Connect(ctx context.Context, nameOrID string) error {
 	vm, err := p.Get(ctx, nameOrID)
 	if err != nil {
 		return err
 	}
-
-	args := []string{"ssm", "start-session", "--target", vm.ID}
-
-	if p.profile != "" {
-		args = append(args, "--profile", p.profile)
-	}
-
-	if p.region != "" {
-		args = append(args, "--region", p.region)
-	}
-
-	ssmCmd := exec.Command("aws", args...)
-	ssmCmd.Stdin = os.Stdin
-	ssmCmd.Stdout = os.Stdout
-	ssmCmd.Stderr = os.Stderr
-
-	return ssmCmd.Run()
+	return p.client.StartShell(ctx, vm.ID)
 }
 
-// StartPortForward starts an SSM port-forwarding session and returns the running command.
-// Caller owns the process lifecycle (Wait/Kill). Used by Tunnel (blocks on Wait) and by
-// k8s connect (manages lifecycle around a subshell). Output is wired to os.Stderr so the
-// caller's stdout stays clean for subshells / pipes.
-func (p *AWSVMProvider) StartPortForward(ctx context.Context, instanceID string, remotePort, localPort int) (*exec.Cmd, error) {
-	params := map[string][]string{
-		"portNumber":      {strconv.Itoa(remotePort)},
-		"localPortNumber": {strconv.Itoa(localPort)},
-	}
-	paramsJSON, err := json.Marshal(params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal parameters: %w", err)
-	}
-
-	args := []string{
-		"ssm", "start-session",
-		"--target", instanceID,
-		"--document-name", "AWS-StartPortForwardingSession",
-		"--parameters", string(paramsJSON),
-	}
-	if p.profile != "" {
-		args = append(args, "--profile", p.profile)
-	}
-	if p.region != "" {
-		args = append(args, "--region", p.region)
-	}
-
-	cmd := exec.CommandContext(ctx, "aws", args...)
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start SSM session: %w", err)
-	}
-	return cmd, nil
+// StartPortForward starts forwarding 127.0.0.1:localPort to remotePort on the
+// instance over native SSM sessions and returns the listener. Caller owns its
+// lifecycle (Wait/Close). Used by Tunnel (blocks on Wait) and by k8s connect.
+func (p *AWSVMProvider) StartPortForward(ctx context.Context, instanceID string, remotePort, localPort int) (*SSMListener, error) {
+	return p.client.ListenSSM(ctx, fmt.Sprintf("127.0.0.1:%d", localPort), SSMTarget{
+		InstanceID: instanceID,
+		Port:       remotePort,
+	})
 }
 
-// Tunnel creates a port forwarding tunnel via SSM (blocking).
+// Tunnel creates port forwarding tunnels via SSM (blocking). Each forward
+// gets its own local listener; forwards with a RemoteHost are relayed by the
+// instance (AWS-StartPortForwardingSessionToRemoteHost).
 func (p *AWSVMProvider) Tunnel(ctx context.Context, nameOrID string, opts *provider.TunnelOptions) error {
 	if opts == nil {
 		return fmt.Errorf("tunnel options required")
 	}
+	forwards := opts.AllForwards()
+	if len(forwards) == 0 {
+		return fmt.Errorf("no forwards specified")
+	}
 	vm, err := p.Get(ctx, nameOrID)
 	if err != nil {
 		return err
 	}
 
-	cmd, err := p.StartPortForward(ctx, vm.ID, opts.RemotePort, opts.LocalPort)
-	if err != nil {
-		return err
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var listeners []*SSMListener
+	defer func() {
+		for _, ln := range listeners {
+			_ = ln.Close()
+		}
+	}()
+
+	for _, f := range forwards {
+		ln, err := p.client.ListenSSM(ctx, fmt.Sprintf("127.0.0.1:%d", f.LocalPort), SSMTarget{
+			InstanceID: vm.ID,
+			Host:       f.RemoteHost,
+			Port:       f.RemotePort,
+		})
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, ln)
+
+		target := vm.ID
+		if f.RemoteHost != "" {
+			target = vm.ID + " → " + f.RemoteHost
+		}
+		fmt.Fprintf(os.Stderr, "Listening on %s (SSM %s:%d)\n", ln.Addr(), target, f.RemotePort)
 	}
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	return cmd.Wait()
+
+	// Run until interrupted or until any listener fails
+	done := make(chan struct{}, len(listeners))
+	for _, ln := range listeners {
+		go func(ln *SSMListener) {
+			_ = ln.Wait()
+			done <- struct{}{}
+		}(ln)
+	}
+	select {
+	case <-ctx.Done():
+	case <-done:
+	}
+	return nil
+}
+
+// Dial opens an SSM port-forwarding session to port on the instance. An
+// instance ID is used as-is, saving a DescribeInstances call per connection.
+func (p *AWSVMProvider) Dial(ctx context.Context, nameOrID string, port int) (io.ReadWriteCloser, error) {
+	instanceID := nameOrID
+	if !instanceIDPattern.MatchString(nameOrID) {
+		vm, err := p.Get(ctx, nameOrID)
+		if err != nil {
+			return nil, err
+		}
+		instanceID = vm.ID
+	}
+	return p.client.DialSSM(ctx, SSMTarget{InstanceID: instanceID, Port: port})
 }
 
 // ec2ToVM converts an EC2 instance to the unified VM type
@@ -296,6 +321,57 @@ func ec2ToVM(i ec2types.Instance) types.VM {
 	return vm
 }
 
+// whereFieldToEC2 maps --where fields to DescribeInstances filter names.
+var whereFieldToEC2 = map[string]string{
+	"id":         "instance-id",
+	"name":       "tag:Name",
+	"type":       "instance-type",
+	"zone":       "availability-zone",
+	"private_ip": "private-ip-address",
+	"public_ip":  "ip-address",
+	"asg":        "tag:aws:autoscaling:groupName",
+}
+
+// whereToEC2Filters translates the pushable terms of a --where expression
+// into EC2 filters. Untranslatable terms are left to client-side filtering.
+func whereToEC2Filters(q *query.Query) []ec2types.Filter {
+	var filters []ec2types.Filter
+	for _, term := range q.Terms() {
+		name, ok := whereFieldToEC2[term.Field]
+		values := term.Values
+
+		switch {
+		case strings.HasPrefix(term.Field, "tags."):
+			name, ok = "tag:"+strings.TrimPrefix(term.Field, "tags."), true
+		case term.Field == "state":
+			values, ok = vmStatesToEC2(term.Values)
+			name = "instance-state-name"
+		}
+		if !ok {
+			continue
+		}
+		filters = append(filters, ec2types.Filter{Name: aws.String(name), Values: values})
+	}
+	return filters
+}
+
+// vmStatesToEC2 maps unified states to EC2 state names; false if any state
+// has no EC2 equivalent.
+func vmStatesToEC2(states []string) ([]string, bool) {
+	var out []string
+	for _, s := range states {
+		switch types.VMState(s) {
+		case types.VMStateRunning, types.VMStateStopped, types.VMStatePending:
+			out = append(out, s)
+		case types.VMStateStopping:
+			out = append(out, "stopping", "shutting-down")
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
 // ec2StateToVMState converts EC2 state to unified VMState
 func ec2StateToVMState(state ec2types.InstanceStateName) types.VMState {
 	switch state {
@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/vietdv277/cumulus/pkg/query"
+)
+
+func TestWhereToEC2Filters(t *testing.T) {
+	tests := []struct {
+		where string
+		want  []string // name=value,value
+	}{
+		{`name=web`, []string{"tag:Name=web"}},
+		{`id in (i-1, i-2) && zone=us-east-1a`, []string{"instance-id=i-1,i-2", "availability-zone=us-east-1a"}},
+		{`tags.team=payments && asg=web-asg`, []string{"tag:team=payments", "tag:aws:autoscaling:groupName=web-asg"}},
+		{`private_ip=10.0.0.1 && public_ip=1.2.3.4`, []string{"private-ip-address=10.0.0.1", "ip-address=1.2.3.4"}},
+		{`state=running`, []string{"instance-state-name=running"}},
+		{`state in (stopping, stopped)`, []string{"instance-state-name=stopping,shutting-down,stopped"}},
+		{`type=m5.large && provider=aws`, []string{"instance-type=m5.large"}},
+
+		// Left to client-side filtering
+		{`state=unknown`, nil},
+		{`name=web || name=db`, nil},
+		{`type=~"m5.*" && private_ip in 10.0.0.0/8`, nil},
+		{`!name=web`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			q, err := query.Parse(tt.where)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			var got []string
+			for _, f := range whereToEC2Filters(q) {
+				got = append(got, aws.ToString(f.Name)+"="+strings.Join(f.Values, ","))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filters = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
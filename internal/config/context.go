@@ -20,10 +20,13 @@ type Context struct {
 	Region   string `yaml:"region,omitempty"`  // Region or zone
 	// Bastion host settings (AWS: EC2 instance ID; GCP: VM name)
 	Bastion        string `yaml:"bastion,omitempty"`
-	BastionPort    int    `yaml:"bastion_port,omitempty"`    // remote port on bastion (AWS k8s connect; default 8888)
+	BastionPort    int    `yaml:"bastion_port,omitempty"`    // remote port on bastion (k8s connect proxy; default 8888)
 	BastionProject string `yaml:"bastion_project,omitempty"` // GCP only
 	BastionZone    string `yaml:"bastion_zone,omitempty"`    // GCP only
 	BastionIAP     bool   `yaml:"bastion_iap,omitempty"`     // GCP only: --tunnel-through-iap
+	TransferBucket string `yaml:"transfer_bucket,omitempty"` // AWS only: S3 bucket staging vm cp transfers
+	SecretsBackend string `yaml:"secrets_backend,omitempty"` // AWS only: ssm or sm for secret names without a scheme
+	SecretsKMSKey  string `yaml:"secrets_kms_key,omitempty"` // AWS only: KMS key for SSM SecureString writes
 }
 
 // TunnelConfig represents a saved tunnel configuration
@@ -0,0 +1,78 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+const consolePollInterval = 2 * time.Second
+
+// ConsoleOutput writes serial port 1 output. GCE returns a byte offset with
+// each read, so --follow fetches only what was appended since.
+func (p *GCPVMProvider) ConsoleOutput(ctx context.Context, vm *types.VM, follow bool, w io.Writer) error {
+	ic, err := p.newInstancesClient(ctx)
+	if err != nil {
+		return fmt.Errorf("create instances client: %w", err)
+	}
+	defer func() { _ = ic.Close() }()
+
+	var next int64
+	port := int32(1)
+	for {
+		out, err := ic.GetSerialPortOutput(ctx, &computepb.GetSerialPortOutputInstanceRequest{
+			Project:  p.client.Project(),
+			Zone:     vm.Zone,
+			Instance: vm.Name,
+			Port:     &port,
+			Start:    &next,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("get serial port output: %w", err)
+		}
+		_, _ = io.WriteString(w, out.GetContents())
+		next = out.GetNext()
+
+		if !follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(consolePollInterval):
+		}
+	}
+}
+
+// Screenshot returns a PNG of the instance display. The instance must have
+// the virtual display device enabled.
+func (p *GCPVMProvider) Screenshot(ctx context.Context, vm *types.VM) ([]byte, error) {
+	ic, err := p.newInstancesClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create instances client: %w", err)
+	}
+	defer func() { _ = ic.Close() }()
+
+	shot, err := ic.GetScreenshot(ctx, &computepb.GetScreenshotInstanceRequest{
+		Project:  p.client.Project(),
+		Zone:     vm.Zone,
+		Instance: vm.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get screenshot: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(shot.GetContents())
+	if err != nil {
+		return nil, fmt.Errorf("decode screenshot: %w", err)
+	}
+	return data, nil
+}
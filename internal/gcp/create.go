@@ -0,0 +1,214 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/option"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// Create creates instances from a GCE instance template and waits for them to
+// be provisioned. Template is a global template name, or a full (possibly
+// regional) template URL. Instance templates are immutable, so a version is
+// rejected.
+func (p *GCPVMProvider) Create(ctx context.Context, opts *provider.VMCreateOptions) ([]types.VM, error) {
+	if opts == nil || opts.Template == "" {
+		return nil, fmt.Errorf("instance template required")
+	}
+	if opts.Version != "" {
+		return nil, fmt.Errorf("GCE instance templates are not versioned; create a new template instead of using :%s", opts.Version)
+	}
+
+	zone := opts.Zone
+	if zone == "" {
+		if !isZone(p.client.Region()) {
+			return nil, fmt.Errorf("zone required: context region %q is not a zone, use --zone", p.client.Region())
+		}
+		zone = p.client.Region()
+	}
+
+	count := opts.Count
+	if count < 1 {
+		count = 1
+	}
+
+	template := opts.Template
+	if !strings.Contains(template, "/") {
+		template = fmt.Sprintf("projects/%s/global/instanceTemplates/%s", p.client.Project(), template)
+	}
+
+	// Labels set on the instance replace the template's, so merge them
+	var labels map[string]string
+	if len(opts.Tags) > 0 {
+		tmplLabels, err := p.templateLabels(ctx, template)
+		if err != nil {
+			return nil, err
+		}
+		labels = make(map[string]string, len(tmplLabels)+len(opts.Tags))
+		for k, v := range tmplLabels {
+			labels[k] = v
+		}
+		for k, v := range opts.Tags {
+			labels[k] = v
+		}
+	}
+
+	base := opts.Name
+	if base == "" {
+		suffix, err := randomSuffix()
+		if err != nil {
+			return nil, err
+		}
+		base = path.Base(template) + "-" + suffix
+	}
+	names := []string{base}
+	if count > 1 {
+		names = names[:0]
+		for i := 1; i <= count; i++ {
+			names = append(names, fmt.Sprintf("%s-%d", base, i))
+		}
+	}
+
+	ic, err := p.newInstancesClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create instances client: %w", err)
+	}
+	defer func() { _ = ic.Close() }()
+
+	// Start every insert before waiting so the instances provision in parallel
+	ops := make([]*compute.Operation, 0, len(names))
+	for _, name := range names {
+		name := name
+		op, err := ic.Insert(ctx, &computepb.InsertInstanceRequest{
+			Project:                p.client.Project(),
+			Zone:                   zone,
+			SourceInstanceTemplate: &template,
+			InstanceResource: &computepb.Instance{
+				Name:   &name,
+				Labels: labels,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("insert instance %s: %w", name, err)
+		}
+		ops = append(ops, op)
+	}
+
+	vms := make([]types.VM, 0, len(names))
+	for i, op := range ops {
+		if err := op.Wait(ctx); err != nil {
+			return vms, fmt.Errorf("create instance %s: %w", names[i], err)
+		}
+		inst, err := ic.Get(ctx, &computepb.GetInstanceRequest{
+			Project:  p.client.Project(),
+			Zone:     zone,
+			Instance: names[i],
+		})
+		if err != nil {
+			return vms, fmt.Errorf("get instance %s: %w", names[i], err)
+		}
+		vms = append(vms, gceToVM(inst))
+	}
+	return vms, nil
+}
+
+// templateLabels returns the instance labels defined by a global or regional
+// instance template URL.
+func (p *GCPVMProvider) templateLabels(ctx context.Context, template string) (map[string]string, error) {
+	parts := strings.Split(strings.TrimPrefix(template, "https://www.googleapis.com/compute/v1/"), "/")
+	opt := option.WithTokenSource(p.client.Credentials().TokenSource)
+
+	var tmpl *computepb.InstanceTemplate
+	switch {
+	case len(parts) == 5 && parts[0] == "projects" && parts[2] == "global":
+		c, err := compute.NewInstanceTemplatesRESTClient(ctx, opt)
+		if err != nil {
+			return nil, fmt.Errorf("create instance templates client: %w", err)
+		}
+		defer func() { _ = c.Close() }()
+		tmpl, err = c.Get(ctx, &computepb.GetInstanceTemplateRequest{
+			Project:          parts[1],
+			InstanceTemplate: parts[4],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get instance template: %w", err)
+		}
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "regions":
+		c, err := compute.NewRegionInstanceTemplatesRESTClient(ctx, opt)
+		if err != nil {
+			return nil, fmt.Errorf("create region instance templates client: %w", err)
+		}
+		defer func() { _ = c.Close() }()
+		tmpl, err = c.Get(ctx, &computepb.GetRegionInstanceTemplateRequest{
+			Project:          parts[1],
+			Region:           parts[3],
+			InstanceTemplate: parts[5],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get instance template: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised instance template %q", template)
+	}
+	return tmpl.GetProperties().GetLabels(), nil
+}
+
+// randomSuffix returns a short random suffix valid in a GCE instance name.
+func randomSuffix() (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CheckTerminate refuses instances with deletion protection enabled.
+func (p *GCPVMProvider) CheckTerminate(ctx context.Context, vm *types.VM) error {
+	inst, ok := vm.Raw.(*computepb.Instance)
+	if !ok {
+		resolved, err := p.resolveVM(ctx, vm.ID)
+		if err != nil {
+			return err
+		}
+		if inst, ok = resolved.Raw.(*computepb.Instance); !ok {
+			return fmt.Errorf("could not read deletion protection for %s", vm.Name)
+		}
+	}
+	if inst.GetDeletionProtection() {
+		return fmt.Errorf("deletion protection is enabled on %s; disable it first with 'gcloud compute instances update %s --zone %s --no-deletion-protection'",
+			vm.Name, vm.Name, vm.Zone)
+	}
+	return nil
+}
+
+// Terminate deletes a GCE instance and waits for the operation to complete.
+func (p *GCPVMProvider) Terminate(ctx context.Context, vm *types.VM) error {
+	if vm.Zone == "" {
+		return fmt.Errorf("could not determine zone for instance %s", vm.Name)
+	}
+
+	ic, err := p.newInstancesClient(ctx)
+	if err != nil {
+		return fmt.Errorf("create instances client: %w", err)
+	}
+	defer func() { _ = ic.Close() }()
+
+	op, err := ic.Delete(ctx, &computepb.DeleteInstanceRequest{
+		Project:  p.client.Project(),
+		Zone:     vm.Zone,
+		Instance: vm.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("delete instance: %w", err)
+	}
+	return op.Wait(ctx)
+}
@@ -0,0 +1,129 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/vietdv277/cumulus/internal/shell"
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// Exec runs a shell command on the instance over `gcloud compute ssh
+// --command` and returns its exit code. With a bastion configured the
+// command hops through it (honouring bastion IAP); otherwise instances
+// without an external IP are reached through IAP directly. ssh itself
+// reports connection failures as exit code 255.
+func (p *GCPVMProvider) Exec(ctx context.Context, vm *types.VM, command string, stdout, stderr io.Writer) (int, error) {
+	return p.runSSH(ctx, vm, command, nil, stdout, stderr)
+}
+
+// CopyTo uploads localPath to remotePath by streaming it into `cat` on the
+// instance over the same ssh path as Exec, so it also works through the
+// bastion where `gcloud compute scp` cannot.
+func (p *GCPVMProvider) CopyTo(ctx context.Context, vm *types.VM, localPath, remotePath string, _ *provider.CopyOptions) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return p.runCopy(ctx, vm, "cat > "+shell.Quote(remotePath), f, io.Discard)
+}
+
+// CopyFrom downloads remotePath on the instance to localPath by streaming
+// it out of `cat` over ssh. The download goes to a temp file next to
+// localPath, which is only replaced once the transfer succeeds.
+func (p *GCPVMProvider) CopyFrom(ctx context.Context, vm *types.VM, remotePath, localPath string, _ *provider.CopyOptions) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(localPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".cml-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+
+	src := shell.Quote(remotePath)
+	command := fmt.Sprintf(`test -f %s || { echo "not a regular file" >&2; exit 1; }; cat %s`, src, src)
+	if err := p.runCopy(ctx, vm, command, nil, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, localPath)
+}
+
+// runCopy runs a transfer command and turns a non-zero exit into an error
+// carrying its stderr.
+func (p *GCPVMProvider) runCopy(ctx context.Context, vm *types.VM, command string, stdin io.Reader, stdout io.Writer) error {
+	var stderr strings.Builder
+	code, err := p.runSSH(ctx, vm, command, stdin, stdout, &stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = fmt.Sprintf("exit code %d", code)
+		}
+		return fmt.Errorf("transfer on %s failed: %s", vm.Name, msg)
+	}
+	return nil
+}
+
+// runSSH runs command on the instance with the given stdio and returns the
+// remote exit code.
+func (p *GCPVMProvider) runSSH(ctx context.Context, vm *types.VM, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.CommandContext(ctx, "gcloud", p.sshCommandArgs(vm, command)...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return exitErr.ExitCode(), nil
+		}
+		return -1, fmt.Errorf("gcloud compute ssh: %w", err)
+	}
+	return 0, nil
+}
+
+// sshCommandArgs builds the gcloud arguments that run command on vm.
+func (p *GCPVMProvider) sshCommandArgs(vm *types.VM, command string) []string {
+	if bastion := p.client.Bastion(); bastion != "" {
+		return append(p.bastionSSHArgs(bastion),
+			"--quiet",
+			"--ssh-flag=-A",
+			"--command", fmt.Sprintf("ssh -o BatchMode=yes -o StrictHostKeyChecking=accept-new %s %s",
+				vm.PrivateIP, shell.Quote(command)),
+		)
+	}
+
+	args := []string{
+		"compute", "ssh", vm.Name,
+		"--project", p.client.Project(),
+		"--zone", vm.Zone,
+		"--quiet",
+		"--command", command,
+	}
+	if vm.PublicIP == "" {
+		args = append(args, "--tunnel-through-iap")
+	}
+	return args
+}
@@ -0,0 +1,225 @@
+package gcp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	crm "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// PermissionTest is the result of testIamPermissions against one resource.
+type PermissionTest struct {
+	Resource string
+	Granted  []string
+	Missing  []string
+}
+
+// Resource kinds accepted by TestPermissions.
+const (
+	resourceProject      = "project"
+	resourceFolder       = "folder"
+	resourceOrganization = "organization"
+	resourceBucket       = "bucket"
+	resourceInstance     = "instance"
+)
+
+// DefaultPermissions lists the permissions checked when the caller does not
+// name any, keyed by resource kind. They cover what cml itself needs.
+var DefaultPermissions = map[string][]string{
+	resourceProject: {
+		"resourcemanager.projects.get",
+		"compute.instances.list",
+		"compute.instances.get",
+		"compute.instances.start",
+		"compute.instances.stop",
+		"compute.instances.setMetadata",
+		"compute.networks.list",
+		"compute.subnetworks.list",
+		"container.clusters.list",
+		"container.clusters.getCredentials",
+		"iap.tunnelInstances.accessViaIAP",
+		"secretmanager.secrets.list",
+		"secretmanager.versions.access",
+		"storage.buckets.list",
+	},
+	resourceFolder: {
+		"resourcemanager.folders.get",
+		"resourcemanager.projects.list",
+	},
+	resourceOrganization: {
+		"resourcemanager.organizations.get",
+		"resourcemanager.projects.list",
+	},
+	resourceBucket: {
+		"storage.buckets.get",
+		"storage.objects.list",
+		"storage.objects.get",
+		"storage.objects.create",
+		"storage.objects.delete",
+	},
+	resourceInstance: {
+		"compute.instances.get",
+		"compute.instances.start",
+		"compute.instances.stop",
+		"compute.instances.setMetadata",
+		"compute.instances.getSerialPortOutput",
+	},
+}
+
+// gcpResource is a parsed resource reference.
+type gcpResource struct {
+	kind    string
+	name    string // full name for CRM ("projects/x"), bucket or instance name
+	project string // instance only
+	zone    string // instance only
+}
+
+// parseResource accepts a project ID, "projects/ID", "folders/N",
+// "organizations/N", "gs://bucket", "buckets/NAME", or
+// "[projects/P/]zones/Z/instances/NAME". An empty reference is the client project.
+func (c *Client) parseResource(ref string) (*gcpResource, error) {
+	switch {
+	case ref == "":
+		if c.project == "" {
+			return nil, fmt.Errorf("no project configured")
+		}
+		return &gcpResource{kind: resourceProject, name: "projects/" + c.project}, nil
+	case strings.HasPrefix(ref, "gs://"):
+		return &gcpResource{kind: resourceBucket, name: strings.TrimSuffix(strings.TrimPrefix(ref, "gs://"), "/")}, nil
+	case strings.HasPrefix(ref, "buckets/"):
+		return &gcpResource{kind: resourceBucket, name: strings.TrimPrefix(ref, "buckets/")}, nil
+	case strings.HasPrefix(ref, "folders/"):
+		return &gcpResource{kind: resourceFolder, name: ref}, nil
+	case strings.HasPrefix(ref, "organizations/"):
+		return &gcpResource{kind: resourceOrganization, name: ref}, nil
+	case strings.Contains(ref, "/instances/"):
+		parts := strings.Split(ref, "/")
+		project := c.project
+		if parts[0] == "projects" && len(parts) >= 2 {
+			project = parts[1]
+			parts = parts[2:]
+		}
+		if len(parts) != 4 || parts[0] != "zones" || parts[2] != "instances" {
+			return nil, fmt.Errorf("invalid instance resource %q (want [projects/P/]zones/Z/instances/NAME)", ref)
+		}
+		return &gcpResource{kind: resourceInstance, name: parts[3], project: project, zone: parts[1]}, nil
+	case strings.HasPrefix(ref, "projects/"):
+		return &gcpResource{kind: resourceProject, name: ref}, nil
+	case !strings.Contains(ref, "/"):
+		return &gcpResource{kind: resourceProject, name: "projects/" + ref}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resource %q", ref)
+	}
+}
+
+// TestPermissions calls testIamPermissions on the resource and splits the
+// requested permissions into granted and missing. When permissions is empty
+// the DefaultPermissions for the resource kind are checked.
+func (c *Client) TestPermissions(ref string, permissions []string) (*PermissionTest, error) {
+	res, err := c.parseResource(ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions) == 0 {
+		permissions = DefaultPermissions[res.kind]
+	}
+
+	var granted []string
+	switch res.kind {
+	case resourceProject, resourceFolder, resourceOrganization:
+		granted, err = c.testCRMPermissions(res, permissions)
+	case resourceBucket:
+		granted, err = c.testBucketPermissions(res, permissions)
+	case resourceInstance:
+		granted, err = c.testInstancePermissions(res, permissions)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PermissionTest{Resource: res.name, Granted: granted}
+	if res.kind == resourceBucket {
+		result.Resource = "gs://" + res.name
+	}
+	if res.kind == resourceInstance {
+		result.Resource = fmt.Sprintf("projects/%s/zones/%s/instances/%s", res.project, res.zone, res.name)
+	}
+
+	has := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		has[p] = true
+	}
+	for _, p := range permissions {
+		if !has[p] {
+			result.Missing = append(result.Missing, p)
+		}
+	}
+	sort.Strings(result.Granted)
+	sort.Strings(result.Missing)
+	return result, nil
+}
+
+func (c *Client) testCRMPermissions(res *gcpResource, permissions []string) ([]string, error) {
+	svc, err := c.newResourceManagerService(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create resource manager service: %w", err)
+	}
+
+	req := &crm.TestIamPermissionsRequest{Permissions: permissions}
+	var resp *crm.TestIamPermissionsResponse
+	switch res.kind {
+	case resourceFolder:
+		resp, err = svc.Folders.TestIamPermissions(res.name, req).Context(c.ctx).Do()
+	case resourceOrganization:
+		resp, err = svc.Organizations.TestIamPermissions(res.name, req).Context(c.ctx).Do()
+	default:
+		resp, err = svc.Projects.TestIamPermissions(res.name, req).Context(c.ctx).Do()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("test permissions on %s: %w", res.name, err)
+	}
+	return resp.Permissions, nil
+}
+
+func (c *Client) testBucketPermissions(res *gcpResource, permissions []string) ([]string, error) {
+	svc, err := storage.NewService(c.ctx,
+		option.WithTokenSource(c.Credentials().TokenSource),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create storage service: %w", err)
+	}
+
+	resp, err := svc.Buckets.TestIamPermissions(res.name, permissions).Context(c.ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("test permissions on gs://%s: %w", res.name, err)
+	}
+	return resp.Permissions, nil
+}
+
+func (c *Client) testInstancePermissions(res *gcpResource, permissions []string) ([]string, error) {
+	ic, err := compute.NewInstancesRESTClient(c.ctx,
+		option.WithTokenSource(c.Credentials().TokenSource),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create instances client: %w", err)
+	}
+	defer func() { _ = ic.Close() }()
+
+	resp, err := ic.TestIamPermissions(c.ctx, &computepb.TestIamPermissionsInstanceRequest{
+		Project:  res.project,
+		Zone:     res.zone,
+		Resource: res.name,
+		TestPermissionsRequestResource: &computepb.TestPermissionsRequest{
+			Permissions: permissions,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("test permissions on instance %s: %w", res.name, err)
+	}
+	return resp.GetPermissions(), nil
+}
@@ -0,0 +1,377 @@
+package gcp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/oauth2"
+)
+
+// IAP TCP forwarding relay protocol. Every WebSocket binary message carries
+// one frame that starts with a big-endian uint16 tag.
+const (
+	iapDefaultEndpoint = "wss://tunnel.cloudproxy.app/v4"
+	iapSubprotocol     = "relay.tunnel.cloudproxy.app"
+	iapOrigin          = "bot:iap-tunneler"
+
+	iapTagConnectSuccessSID   uint16 = 0x0001 // len uint32 + session ID
+	iapTagReconnectSuccessAck uint16 = 0x0002 // uint64 bytes acked
+	iapTagData                uint16 = 0x0004 // len uint32 + payload
+	iapTagAck                 uint16 = 0x0007 // uint64 bytes received
+
+	iapMaxDataFrame     = 16 * 1024
+	iapHandshakeTimeout = 30 * time.Second
+)
+
+// IAPTarget identifies an instance port reachable through IAP.
+type IAPTarget struct {
+	Project   string
+	Zone      string
+	Instance  string
+	Interface string // network interface, defaults to nic0
+	Port      int
+}
+
+// IAPDialer opens IAP TCP forwarding tunnels over WebSocket, the protocol
+// behind `gcloud compute start-iap-tunnel`.
+type IAPDialer struct {
+	TokenSource oauth2.TokenSource
+	// Endpoint is the relay base URL. Tests point it at a local ws:// server.
+	Endpoint string
+}
+
+// NewIAPDialer returns a dialer for the public IAP relay.
+func NewIAPDialer(ts oauth2.TokenSource) *IAPDialer {
+	return &IAPDialer{TokenSource: ts, Endpoint: iapDefaultEndpoint}
+}
+
+func (d *IAPDialer) connectURL(t IAPTarget) string {
+	endpoint := d.Endpoint
+	if endpoint == "" {
+		endpoint = iapDefaultEndpoint
+	}
+	iface := t.Interface
+	if iface == "" {
+		iface = "nic0"
+	}
+	q := url.Values{}
+	q.Set("project", t.Project)
+	q.Set("zone", t.Zone)
+	q.Set("instance", t.Instance)
+	q.Set("interface", iface)
+	q.Set("port", strconv.Itoa(t.Port))
+	q.Set("newWebsocket", "true")
+	return endpoint + "/connect?" + q.Encode()
+}
+
+// Dial opens a tunnel to the target and waits for the relay to confirm the
+// backend connection. The returned connection is ready for Read and Write.
+func (d *IAPDialer) Dial(ctx context.Context, target IAPTarget) (*IAPConn, error) {
+	header := http.Header{}
+	header.Set("Origin", iapOrigin)
+	if d.TokenSource != nil {
+		token, err := d.TokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("get IAP access token: %w", err)
+		}
+		header.Set("Authorization", "Bearer "+token.AccessToken)
+	}
+
+	wsDialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: iapHandshakeTimeout,
+		Subprotocols:     []string{iapSubprotocol},
+	}
+	ws, resp, err := wsDialer.DialContext(ctx, d.connectURL(target), header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("IAP tunnel to %s:%d: %s: %w", target.Instance, target.Port, resp.Status, err)
+		}
+		return nil, fmt.Errorf("IAP tunnel to %s:%d: %w", target.Instance, target.Port, err)
+	}
+
+	c := &IAPConn{ws: ws}
+	if err := c.awaitConnect(ctx); err != nil {
+		_ = ws.Close()
+		return nil, fmt.Errorf("IAP tunnel to %s:%d: %w", target.Instance, target.Port, err)
+	}
+	return c, nil
+}
+
+// IAPConn is one IAP tunnel. Read and Write may be used from separate
+// goroutines; each must only be used from one goroutine at a time.
+type IAPConn struct {
+	ws  *websocket.Conn
+	sid string
+
+	writeMu sync.Mutex
+
+	pending  []byte // unread remainder of the last DATA frame
+	received uint64 // payload bytes received
+	acked    uint64 // payload bytes acknowledged to the relay
+
+	closeOnce sync.Once
+}
+
+// SessionID returns the relay session ID from CONNECT_SUCCESS_SID.
+func (c *IAPConn) SessionID() string { return c.sid }
+
+// awaitConnect reads frames until CONNECT_SUCCESS_SID arrives.
+func (c *IAPConn) awaitConnect(ctx context.Context) error {
+	deadline := time.Now().Add(iapHandshakeTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.ws.SetReadDeadline(deadline)
+	defer func() { _ = c.ws.SetReadDeadline(time.Time{}) }()
+
+	for {
+		tag, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case iapTagConnectSuccessSID:
+			c.sid = string(payload)
+			return nil
+		case iapTagAck:
+			// Harmless before the session is up
+		default:
+			return fmt.Errorf("unexpected frame 0x%04x before connect", tag)
+		}
+	}
+}
+
+// readFrame reads one WebSocket message and splits it into tag and payload.
+// ACK payloads are returned as their 8 raw bytes.
+func (c *IAPConn) readFrame() (uint16, []byte, error) {
+	msgType, msg, err := c.ws.ReadMessage()
+	if err != nil {
+		var ce *websocket.CloseError
+		if errors.As(err, &ce) && ce.Code == websocket.CloseNormalClosure {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, err
+	}
+	if msgType != websocket.BinaryMessage {
+		return 0, nil, fmt.Errorf("unexpected non-binary message")
+	}
+	return parseIAPFrame(msg)
+}
+
+// parseIAPFrame decodes one relay frame.
+func parseIAPFrame(msg []byte) (uint16, []byte, error) {
+	if len(msg) < 2 {
+		return 0, nil, fmt.Errorf("short IAP frame (%d bytes)", len(msg))
+	}
+	tag := binary.BigEndian.Uint16(msg)
+	body := msg[2:]
+
+	switch tag {
+	case iapTagConnectSuccessSID, iapTagData:
+		if len(body) < 4 {
+			return 0, nil, fmt.Errorf("short IAP frame 0x%04x", tag)
+		}
+		n := binary.BigEndian.Uint32(body)
+		if uint64(n) > uint64(len(body)-4) {
+			return 0, nil, fmt.Errorf("IAP frame 0x%04x length %d exceeds message", tag, n)
+		}
+		return tag, body[4 : 4+n], nil
+	case iapTagAck, iapTagReconnectSuccessAck:
+		if len(body) < 8 {
+			return 0, nil, fmt.Errorf("short IAP frame 0x%04x", tag)
+		}
+		return tag, body[:8], nil
+	default:
+		// Unknown tags are skipped, like the reference client
+		return tag, nil, nil
+	}
+}
+
+// encodeIAPData builds a DATA frame.
+func encodeIAPData(p []byte) []byte {
+	frame := make([]byte, 6+len(p))
+	binary.BigEndian.PutUint16(frame, iapTagData)
+	binary.BigEndian.PutUint32(frame[2:], uint32(len(p)))
+	copy(frame[6:], p)
+	return frame
+}
+
+// encodeIAPAck builds an ACK frame for the total bytes received.
+func encodeIAPAck(received uint64) []byte {
+	frame := make([]byte, 10)
+	binary.BigEndian.PutUint16(frame, iapTagAck)
+	binary.BigEndian.PutUint64(frame[2:], received)
+	return frame
+}
+
+// Read returns tunnelled bytes from the instance. It returns io.EOF when the
+// relay closes the session normally.
+func (c *IAPConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		tag, payload, err := c.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		if tag != iapTagData {
+			continue
+		}
+		c.pending = payload
+		c.received += uint64(len(payload))
+
+		// Acknowledge in batches so the relay keeps its send window open
+		if c.received-c.acked > 2*iapMaxDataFrame {
+			if err := c.writeMessage(encodeIAPAck(c.received)); err != nil {
+				return 0, err
+			}
+			c.acked = c.received
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write sends p to the instance in DATA frames of at most 16 KiB.
+func (c *IAPConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := written + iapMaxDataFrame
+		if end > len(p) {
+			end = len(p)
+		}
+		if err := c.writeMessage(encodeIAPData(p[written:end])); err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+func (c *IAPConn) writeMessage(frame []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteMessage(websocket.BinaryMessage, frame)
+}
+
+// Close ends the session.
+func (c *IAPConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.writeMu.Lock()
+		_ = c.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		c.writeMu.Unlock()
+		err = c.ws.Close()
+	})
+	return err
+}
+
+// IAPListener accepts local TCP connections and forwards each one over its
+// own IAP tunnel to the same target.
+type IAPListener struct {
+	ln     net.Listener
+	dialer *IAPDialer
+	target IAPTarget
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Listen binds addr (e.g. "127.0.0.1:8888"; port 0 picks a free port) and
+// starts forwarding accepted connections to target.
+func (d *IAPDialer) Listen(ctx context.Context, addr string, target IAPTarget) (*IAPListener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l := &IAPListener{ln: ln, dialer: d, target: target, ctx: ctx, cancel: cancel}
+
+	l.wg.Add(1)
+	go l.serve()
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+	return l, nil
+}
+
+// Addr returns the local listening address.
+func (l *IAPListener) Addr() net.Addr { return l.ln.Addr() }
+
+// Close stops accepting connections and tears down open tunnels.
+func (l *IAPListener) Close() error {
+	l.cancel()
+	l.wg.Wait()
+	return nil
+}
+
+// Wait blocks until the listener is closed or its context is cancelled.
+func (l *IAPListener) Wait() error {
+	<-l.ctx.Done()
+	l.wg.Wait()
+	return nil
+}
+
+func (l *IAPListener) serve() {
+	defer l.wg.Done()
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if l.ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "iap: accept: %v\n", err)
+				l.cancel()
+			}
+			return
+		}
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			l.handle(conn)
+		}()
+	}
+}
+
+func (l *IAPListener) handle(local net.Conn) {
+	defer func() { _ = local.Close() }()
+
+	remote, err := l.dialer.Dial(l.ctx, l.target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "iap: %v\n", err)
+		return
+	}
+	defer func() { _ = remote.Close() }()
+
+	pipeIAP(l.ctx, local, remote)
+}
+
+// pipeIAP copies in both directions until either side finishes or ctx ends.
+// IAP has no half-close, so the first EOF tears down both sides.
+func pipeIAP(ctx context.Context, local io.ReadWriteCloser, remote *IAPConn) {
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(remote, local); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(local, remote); done <- struct{}{} }()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	_ = local.Close()
+	_ = remote.Close()
+}
@@ -0,0 +1,283 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/oauth2"
+)
+
+// newFakeIAPRelay starts a WebSocket server speaking the relay subprotocol
+// and returns an IAPDialer pointed at it. relay runs for every tunnel.
+func newFakeIAPRelay(t *testing.T, relay func(ws *websocket.Conn, r *http.Request)) *IAPDialer {
+	t.Helper()
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{iapSubprotocol},
+		// The relay sees the bot:iap-tunneler origin, which is not the host
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer func() { _ = ws.Close() }()
+		relay(ws, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return &IAPDialer{
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"}),
+		Endpoint:    "ws" + strings.TrimPrefix(srv.URL, "http") + "/v4",
+	}
+}
+
+// encodeIAPConnectSID builds a CONNECT_SUCCESS_SID frame.
+func encodeIAPConnectSID(sid string) []byte {
+	frame := make([]byte, 6+len(sid))
+	binary.BigEndian.PutUint16(frame, iapTagConnectSuccessSID)
+	binary.BigEndian.PutUint32(frame[2:], uint32(len(sid)))
+	copy(frame[6:], sid)
+	return frame
+}
+
+// echoRelay confirms the connection and echoes DATA frames back, recording
+// the size of each frame it receives.
+func echoRelay(frameSizes chan<- int) func(ws *websocket.Conn, r *http.Request) {
+	return func(ws *websocket.Conn, r *http.Request) {
+		if err := ws.WriteMessage(websocket.BinaryMessage, encodeIAPConnectSID("sid-echo")); err != nil {
+			return
+		}
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			tag, payload, err := parseIAPFrame(msg)
+			if err != nil || tag != iapTagData {
+				continue
+			}
+			if frameSizes != nil {
+				frameSizes <- len(payload)
+			}
+			if err := ws.WriteMessage(websocket.BinaryMessage, encodeIAPData(payload)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func TestIAPFrameRoundTrip(t *testing.T) {
+	tag, payload, err := parseIAPFrame(encodeIAPData([]byte("hello")))
+	if err != nil || tag != iapTagData || string(payload) != "hello" {
+		t.Errorf("DATA round trip = 0x%04x %q %v", tag, payload, err)
+	}
+
+	tag, payload, err = parseIAPFrame(encodeIAPData(nil))
+	if err != nil || tag != iapTagData || len(payload) != 0 {
+		t.Errorf("empty DATA round trip = 0x%04x %q %v", tag, payload, err)
+	}
+
+	tag, payload, err = parseIAPFrame(encodeIAPAck(1 << 40))
+	if err != nil || tag != iapTagAck || binary.BigEndian.Uint64(payload) != 1<<40 {
+		t.Errorf("ACK round trip = 0x%04x %x %v", tag, payload, err)
+	}
+
+	tag, payload, err = parseIAPFrame(encodeIAPConnectSID("session-1"))
+	if err != nil || tag != iapTagConnectSuccessSID || string(payload) != "session-1" {
+		t.Errorf("CONNECT_SUCCESS_SID = 0x%04x %q %v", tag, payload, err)
+	}
+
+	// Unknown tags are skipped rather than failing the tunnel
+	tag, payload, err = parseIAPFrame([]byte{0x00, 0x99, 1, 2, 3})
+	if err != nil || tag != 0x0099 || payload != nil {
+		t.Errorf("unknown tag = 0x%04x %q %v", tag, payload, err)
+	}
+}
+
+func TestIAPFrameTruncated(t *testing.T) {
+	data := encodeIAPData([]byte("hello"))
+	ack := encodeIAPAck(42)
+	sid := encodeIAPConnectSID("session-1")
+
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"empty", nil},
+		{"tag only half", data[:1]},
+		{"DATA without length", data[:4]},
+		{"DATA payload cut", data[:len(data)-1]},
+		{"ACK cut", ack[:9]},
+		{"CONNECT_SUCCESS_SID cut", sid[:len(sid)-2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseIAPFrame(tt.frame); err == nil {
+				t.Errorf("parseIAPFrame(%x) succeeded, want error", tt.frame)
+			}
+		})
+	}
+}
+
+func TestIAPDialHandshake(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	protocols := make(chan string, 1)
+	d := newFakeIAPRelay(t, func(ws *websocket.Conn, r *http.Request) {
+		requests <- r
+		protocols <- ws.Subprotocol()
+		// An ACK before the session is up is ignored
+		_ = ws.WriteMessage(websocket.BinaryMessage, encodeIAPAck(0))
+		_ = ws.WriteMessage(websocket.BinaryMessage, encodeIAPConnectSID("sid-123"))
+		_, _, _ = ws.ReadMessage()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := d.Dial(ctx, IAPTarget{Project: "proj", Zone: "asia-southeast1-b", Instance: "web-01", Port: 22})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if conn.SessionID() != "sid-123" {
+		t.Errorf("session ID = %q", conn.SessionID())
+	}
+
+	r := <-requests
+	if r.URL.Path != "/v4/connect" {
+		t.Errorf("path = %q", r.URL.Path)
+	}
+	q := r.URL.Query()
+	for key, want := range map[string]string{
+		"project": "proj", "zone": "asia-southeast1-b", "instance": "web-01",
+		"interface": "nic0", "port": "22", "newWebsocket": "true",
+	} {
+		if q.Get(key) != want {
+			t.Errorf("query %s = %q, want %q", key, q.Get(key), want)
+		}
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := r.Header.Get("Origin"); got != iapOrigin {
+		t.Errorf("Origin = %q", got)
+	}
+	if got := <-protocols; got != iapSubprotocol {
+		t.Errorf("subprotocol = %q", got)
+	}
+}
+
+func TestIAPDialUnexpectedFrame(t *testing.T) {
+	d := newFakeIAPRelay(t, func(ws *websocket.Conn, r *http.Request) {
+		_ = ws.WriteMessage(websocket.BinaryMessage, encodeIAPData([]byte("too early")))
+		_, _, _ = ws.ReadMessage()
+	})
+
+	_, err := d.Dial(context.Background(), IAPTarget{Project: "p", Zone: "z", Instance: "vm", Port: 22})
+	if err == nil || !strings.Contains(err.Error(), "before connect") {
+		t.Errorf("err = %v, want unexpected frame before connect", err)
+	}
+}
+
+func TestIAPDialRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	d := &IAPDialer{Endpoint: "ws" + strings.TrimPrefix(srv.URL, "http")}
+
+	_, err := d.Dial(context.Background(), IAPTarget{Project: "p", Zone: "z", Instance: "vm", Port: 22})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want the 403 status", err)
+	}
+}
+
+func TestIAPAckAccounting(t *testing.T) {
+	const frames = 5
+	acks := make(chan uint64, frames)
+
+	d := newFakeIAPRelay(t, func(ws *websocket.Conn, r *http.Request) {
+		_ = ws.WriteMessage(websocket.BinaryMessage, encodeIAPConnectSID("sid"))
+		for i := 0; i < frames; i++ {
+			_ = ws.WriteMessage(websocket.BinaryMessage, encodeIAPData(make([]byte, iapMaxDataFrame)))
+		}
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				close(acks)
+				return
+			}
+			if tag, payload, err := parseIAPFrame(msg); err == nil && tag == iapTagAck {
+				acks <- binary.BigEndian.Uint64(payload)
+			}
+		}
+	})
+
+	conn, err := d.Dial(context.Background(), IAPTarget{Project: "p", Zone: "z", Instance: "vm", Port: 22})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, frames*iapMaxDataFrame)); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	_ = conn.Close()
+
+	// An ACK goes out once more than two frames are unacknowledged: after
+	// frame 3 (48 KiB), then not again until another 32 KiB+ arrives
+	var got []uint64
+	for a := range acks {
+		got = append(got, a)
+	}
+	if len(got) != 1 || got[0] != 3*iapMaxDataFrame {
+		t.Errorf("acks = %v, want [%d]", got, 3*iapMaxDataFrame)
+	}
+}
+
+func TestIAPListenerForwards(t *testing.T) {
+	frameSizes := make(chan int, 64)
+	d := newFakeIAPRelay(t, echoRelay(frameSizes))
+
+	l, err := d.Listen(context.Background(), "127.0.0.1:0", IAPTarget{Project: "p", Zone: "z", Instance: "vm", Port: 5432})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer func() { _ = l.Close() }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	payload := bytes.Repeat([]byte("abcdefgh"), 5000) // 40000 bytes, several frames
+	go func() { _, _ = conn.Write(payload) }()
+
+	got := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("forwarded bytes differ")
+	}
+
+	total := 0
+	for total < len(payload) {
+		n := <-frameSizes
+		if n > iapMaxDataFrame {
+			t.Errorf("DATA frame of %d bytes exceeds %d", n, iapMaxDataFrame)
+		}
+		total += n
+	}
+}
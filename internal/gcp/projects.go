@@ -0,0 +1,74 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	crm "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+)
+
+// Project is a GCP project visible to the caller.
+type Project struct {
+	ID     string // project ID, e.g. "my-project"
+	Name   string // display name
+	Number string // project number, e.g. "123456789012"
+	Parent string // "organizations/N" or "folders/N"
+	State  string // ACTIVE, DELETE_REQUESTED, ...
+}
+
+func (c *Client) newResourceManagerService(ctx context.Context) (*crm.Service, error) {
+	return crm.NewService(ctx,
+		option.WithTokenSource(c.Credentials().TokenSource),
+	)
+}
+
+// ListProjects returns the active projects the caller has resourcemanager.projects.get
+// on, sorted by project ID.
+func (c *Client) ListProjects() ([]Project, error) {
+	svc, err := c.newResourceManagerService(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create resource manager service: %w", err)
+	}
+
+	var projects []Project
+	err = svc.Projects.Search().Query("state:ACTIVE").Pages(c.ctx, func(resp *crm.SearchProjectsResponse) error {
+		for _, p := range resp.Projects {
+			projects = append(projects, crmToProject(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search projects: %w", err)
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+// GetProject returns a project by ID or number.
+func (c *Client) GetProject(id string) (*Project, error) {
+	svc, err := c.newResourceManagerService(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create resource manager service: %w", err)
+	}
+
+	p, err := svc.Projects.Get("projects/" + id).Context(c.ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("get project %s: %w", id, err)
+	}
+	project := crmToProject(p)
+	return &project, nil
+}
+
+func crmToProject(p *crm.Project) Project {
+	return Project{
+		ID:     p.ProjectId,
+		Name:   p.DisplayName,
+		Number: strings.TrimPrefix(p.Name, "projects/"),
+		Parent: p.Parent,
+		State:  p.State,
+	}
+}
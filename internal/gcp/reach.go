@@ -0,0 +1,428 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// reachEnd holds what is known about one end of a reachability check
+type reachEnd struct {
+	label    string
+	ip       net.IP
+	inst     *computepb.Instance // nil for a bare IP
+	network  string              // "projects/<p>/global/networks/<n>"
+	tags     map[string]bool
+	accounts map[string]bool
+}
+
+// fwProtocol is satisfied by both computepb.Allowed and computepb.Denied
+type fwProtocol interface {
+	GetIPProtocol() string
+	GetPorts() []string
+}
+
+// Reach evaluates VPC firewall rules and routes for both ends. GCE firewall
+// rules are stateful, so replies need no rule of their own. Hierarchical and
+// network firewall policies are not evaluated.
+func (p *GCPVMProvider) Reach(ctx context.Context, src, dst provider.ReachEndpoint, protocol string, port int) (*types.ReachReport, error) {
+	report := &types.ReachReport{
+		Source:      reachLabel(src),
+		SourceIP:    src.IP,
+		Destination: reachLabel(dst),
+		DestIP:      dst.IP,
+		Protocol:    protocol,
+		Port:        port,
+	}
+
+	s, err := newReachEnd(src)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newReachEnd(dst)
+	if err != nil {
+		return nil, err
+	}
+	if d.ip == nil {
+		report.Add("destination address", types.ReachDeny, "",
+			"the destination has no external IP, so it cannot be reached from outside the VPC network")
+		return report, nil
+	}
+
+	opt := option.WithTokenSource(p.client.Credentials().TokenSource)
+	fc, err := compute.NewFirewallsRESTClient(ctx, opt)
+	if err != nil {
+		return nil, fmt.Errorf("create firewalls client: %w", err)
+	}
+	defer func() { _ = fc.Close() }()
+	rc, err := compute.NewRoutesRESTClient(ctx, opt)
+	if err != nil {
+		return nil, fmt.Errorf("create routes client: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	// Source side
+	if s.inst != nil {
+		firewalls, err := listNetworkFirewalls(ctx, fc, s.network)
+		if err != nil {
+			return nil, err
+		}
+		report.Add(evalFirewalls("source firewall rules (egress)", firewalls, "EGRESS", s, d, protocol, port))
+
+		routes, err := listNetworkRoutes(ctx, rc, s.network)
+		if err != nil {
+			return nil, err
+		}
+		report.Add(evalRoutes("source routes", routes, s, d.ip))
+	} else {
+		report.Add("source", types.ReachInfo, "", fmt.Sprintf("%s is not a GCE instance; its own firewall is not evaluated", s.label))
+	}
+
+	// Destination side
+	if d.inst != nil {
+		firewalls, err := listNetworkFirewalls(ctx, fc, d.network)
+		if err != nil {
+			return nil, err
+		}
+		report.Add(evalFirewalls("destination firewall rules (ingress)", firewalls, "INGRESS", d, s, protocol, port))
+	} else {
+		report.Add("destination", types.ReachInfo, "", fmt.Sprintf("%s is not a GCE instance; its own firewall is not evaluated", d.label))
+	}
+
+	report.Add("firewall policies", types.ReachInfo, "",
+		"hierarchical and network firewall policies are evaluated before VPC rules and are not checked")
+	return report, nil
+}
+
+func newReachEnd(ep provider.ReachEndpoint) (*reachEnd, error) {
+	e := &reachEnd{label: reachLabel(ep), ip: net.ParseIP(ep.IP), tags: map[string]bool{}, accounts: map[string]bool{}}
+	if ep.VM == nil {
+		return e, nil
+	}
+	inst, ok := ep.VM.Raw.(*computepb.Instance)
+	if !ok {
+		return nil, fmt.Errorf("no instance details for %s", e.label)
+	}
+	e.inst = inst
+	if nics := inst.GetNetworkInterfaces(); len(nics) > 0 {
+		e.network = networkPath(nics[0].GetNetwork())
+	}
+	for _, t := range inst.GetTags().GetItems() {
+		e.tags[t] = true
+	}
+	for _, sa := range inst.GetServiceAccounts() {
+		e.accounts[sa.GetEmail()] = true
+	}
+	return e, nil
+}
+
+// networkPath trims a network URL to "projects/<p>/global/networks/<n>"
+func networkPath(url string) string {
+	if i := strings.Index(url, "projects/"); i >= 0 {
+		return url[i:]
+	}
+	return url
+}
+
+func listNetworkFirewalls(ctx context.Context, fc *compute.FirewallsClient, network string) ([]*computepb.Firewall, error) {
+	project := strings.Split(network, "/")[1]
+	var out []*computepb.Firewall
+	it := fc.List(ctx, &computepb.ListFirewallsRequest{Project: project})
+	for {
+		fw, err := it.Next()
+		if err == iterator.Done {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list firewalls: %w", err)
+		}
+		if networkPath(fw.GetNetwork()) == network && !fw.GetDisabled() {
+			out = append(out, fw)
+		}
+	}
+}
+
+func listNetworkRoutes(ctx context.Context, rc *compute.RoutesClient, network string) ([]*computepb.Route, error) {
+	project := strings.Split(network, "/")[1]
+	var out []*computepb.Route
+	it := rc.List(ctx, &computepb.ListRoutesRequest{Project: project})
+	for {
+		r, err := it.Next()
+		if err == iterator.Done {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list routes: %w", err)
+		}
+		if networkPath(r.GetNetwork()) == network {
+			out = append(out, r)
+		}
+	}
+}
+
+// evalFirewalls picks the highest-priority (lowest number) rule in direction
+// that applies to target and matches peer, protocol and port; deny wins a
+// tie. With no match the implied rules apply: allow egress, deny ingress.
+func evalFirewalls(check string, firewalls []*computepb.Firewall, direction string, target, peer *reachEnd, protocol string, port int) (string, types.ReachVerdict, string, string) {
+	var best *computepb.Firewall
+	bestDeny := false
+	for _, fw := range firewalls {
+		if fw.GetDirection() != direction || !firewallTargets(fw, target) || !firewallMatchesPeer(fw, direction, target, peer) {
+			continue
+		}
+		deny := false
+		matched := false
+		for _, a := range fw.GetAllowed() {
+			if fwProtocolMatches(a, protocol, port) {
+				matched = true
+			}
+		}
+		for _, d := range fw.GetDenied() {
+			if fwProtocolMatches(d, protocol, port) {
+				matched, deny = true, true
+			}
+		}
+		if !matched {
+			continue
+		}
+		if best == nil || fw.GetPriority() < best.GetPriority() || (fw.GetPriority() == best.GetPriority() && deny && !bestDeny) {
+			best, bestDeny = fw, deny
+		}
+	}
+
+	if best == nil {
+		if direction == "EGRESS" {
+			return check, types.ReachAllow, "implied allow egress (priority 65535)", ""
+		}
+		return check, types.ReachDeny, "implied deny ingress (priority 65535)",
+			fmt.Sprintf("no ingress rule allows %s/%d from %s to this instance", protocol, port, peer.ip)
+	}
+
+	rule := fmt.Sprintf("%s (priority %d): %s", best.GetName(), best.GetPriority(), firewallSummary(best))
+	if bestDeny {
+		return check, types.ReachDeny, rule, ""
+	}
+	return check, types.ReachAllow, rule, ""
+}
+
+// firewallTargets reports whether the rule applies to the instance
+func firewallTargets(fw *computepb.Firewall, e *reachEnd) bool {
+	if len(fw.GetTargetTags()) == 0 && len(fw.GetTargetServiceAccounts()) == 0 {
+		return true
+	}
+	for _, t := range fw.GetTargetTags() {
+		if e.tags[t] {
+			return true
+		}
+	}
+	for _, sa := range fw.GetTargetServiceAccounts() {
+		if e.accounts[sa] {
+			return true
+		}
+	}
+	return false
+}
+
+// firewallMatchesPeer reports whether the rule's source (ingress) or
+// destination (egress) matches peer. Source tags and service accounts only
+// match instances in the same network.
+func firewallMatchesPeer(fw *computepb.Firewall, direction string, target, peer *reachEnd) bool {
+	if direction == "EGRESS" {
+		return rangesContain(fw.GetDestinationRanges(), peer.ip)
+	}
+	if len(fw.GetDestinationRanges()) > 0 && !rangesContain(fw.GetDestinationRanges(), target.ip) {
+		return false
+	}
+	if rangesContain(fw.GetSourceRanges(), peer.ip) {
+		return true
+	}
+	if peer.inst == nil || peer.network != target.network {
+		return false
+	}
+	for _, t := range fw.GetSourceTags() {
+		if peer.tags[t] {
+			return true
+		}
+	}
+	for _, sa := range fw.GetSourceServiceAccounts() {
+		if peer.accounts[sa] {
+			return true
+		}
+	}
+	return false
+}
+
+func fwProtocolMatches(p fwProtocol, protocol string, port int) bool {
+	proto := p.GetIPProtocol()
+	if proto == "all" {
+		return true
+	}
+	if proto != protocol && proto != map[string]string{"tcp": "6", "udp": "17"}[protocol] {
+		return false
+	}
+	if len(p.GetPorts()) == 0 {
+		return true
+	}
+	for _, spec := range p.GetPorts() {
+		lo, hi, _ := strings.Cut(spec, "-")
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		to := from
+		if hi != "" {
+			if to, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		if from <= port && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func rangesContain(ranges []string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, r := range ranges {
+		if _, n, err := net.ParseCIDR(r); err == nil && n.Contains(ip) {
+			return true
+		}
+		if other := net.ParseIP(r); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// firewallSummary describes a rule, e.g. "allow tcp:22,443 from 35.235.240.0/20"
+func firewallSummary(fw *computepb.Firewall) string {
+	action := "allow"
+	var protos []fwProtocol
+	for _, a := range fw.GetAllowed() {
+		protos = append(protos, a)
+	}
+	if len(fw.GetDenied()) > 0 {
+		action = "deny"
+		protos = protos[:0]
+		for _, d := range fw.GetDenied() {
+			protos = append(protos, d)
+		}
+	}
+
+	var parts []string
+	for _, p := range protos {
+		if len(p.GetPorts()) == 0 {
+			parts = append(parts, p.GetIPProtocol())
+		} else {
+			parts = append(parts, p.GetIPProtocol()+":"+strings.Join(p.GetPorts(), ","))
+		}
+	}
+
+	var peers []string
+	if fw.GetDirection() == "EGRESS" {
+		peers = append(peers, "to "+strings.Join(fw.GetDestinationRanges(), ","))
+	} else {
+		if len(fw.GetSourceRanges()) > 0 {
+			peers = append(peers, "from "+strings.Join(fw.GetSourceRanges(), ","))
+		}
+		if len(fw.GetSourceTags()) > 0 {
+			peers = append(peers, "from tags "+strings.Join(fw.GetSourceTags(), ","))
+		}
+		if len(fw.GetSourceServiceAccounts()) > 0 {
+			peers = append(peers, "from "+strings.Join(fw.GetSourceServiceAccounts(), ","))
+		}
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", action, strings.Join(parts, " "), strings.Join(peers, " ")))
+}
+
+// evalRoutes selects the route GCE would use for ip: the most specific
+// destination range among routes applying to the instance, then the lowest
+// priority.
+func evalRoutes(check string, routes []*computepb.Route, e *reachEnd, ip net.IP) (string, types.ReachVerdict, string, string) {
+	var best *computepb.Route
+	bestLen := -1
+	for _, r := range routes {
+		_, n, err := net.ParseCIDR(r.GetDestRange())
+		if err != nil || !n.Contains(ip) {
+			continue
+		}
+		if len(r.GetTags()) > 0 {
+			tagged := false
+			for _, t := range r.GetTags() {
+				tagged = tagged || e.tags[t]
+			}
+			if !tagged {
+				continue
+			}
+		}
+		ones, _ := n.Mask.Size()
+		if ones > bestLen || (ones == bestLen && r.GetPriority() < best.GetPriority()) {
+			best, bestLen = r, ones
+		}
+	}
+	if best == nil {
+		return check, types.ReachDeny, "", fmt.Sprintf("no route to %s", ip)
+	}
+
+	hop := routeNextHop(best)
+	rule := fmt.Sprintf("%s: %s → %s", best.GetName(), best.GetDestRange(), hop)
+	if best.GetNextHopGateway() != "" && !ip.IsPrivate() && !instanceHasExternalIP(e.inst) {
+		return check, types.ReachWarn, rule, "the instance has no external IP; this needs Cloud NAT on the subnet"
+	}
+	return check, types.ReachAllow, rule, ""
+}
+
+func routeNextHop(r *computepb.Route) string {
+	switch {
+	case r.GetNextHopGateway() != "":
+		return "internet gateway"
+	case r.GetNextHopNetwork() != "":
+		return "subnet (local)"
+	case r.GetNextHopPeering() != "":
+		return "peering " + r.GetNextHopPeering()
+	case r.GetNextHopInstance() != "":
+		return "instance " + path.Base(r.GetNextHopInstance())
+	case r.GetNextHopIlb() != "":
+		return "load balancer " + path.Base(r.GetNextHopIlb())
+	case r.GetNextHopVpnTunnel() != "":
+		return "VPN tunnel " + path.Base(r.GetNextHopVpnTunnel())
+	case r.GetNextHopIp() != "":
+		return r.GetNextHopIp()
+	case r.GetNextHopInterconnectAttachment() != "":
+		return "interconnect " + path.Base(r.GetNextHopInterconnectAttachment())
+	}
+	return "unknown"
+}
+
+func instanceHasExternalIP(inst *computepb.Instance) bool {
+	for _, nic := range inst.GetNetworkInterfaces() {
+		for _, ac := range nic.GetAccessConfigs() {
+			if ac.GetNatIP() != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reachLabel names an endpoint for the report
+func reachLabel(ep provider.ReachEndpoint) string {
+	if ep.VM == nil {
+		return ep.IP
+	}
+	return ep.VM.Name
+}
@@ -0,0 +1,204 @@
+package gcp
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+const testNetwork = "projects/p/global/networks/default"
+
+func testReachEnd(ip string, tags ...string) *reachEnd {
+	e := &reachEnd{
+		ip:       net.ParseIP(ip),
+		inst:     &computepb.Instance{},
+		network:  testNetwork,
+		tags:     map[string]bool{},
+		accounts: map[string]bool{},
+	}
+	for _, t := range tags {
+		e.tags[t] = true
+	}
+	return e
+}
+
+func TestEvalFirewalls(t *testing.T) {
+	allowTCP := func(name string, priority int32, ports ...string) *computepb.Firewall {
+		return &computepb.Firewall{
+			Name:         ptr(name),
+			Direction:    ptr("INGRESS"),
+			Priority:     ptr(priority),
+			SourceRanges: []string{"0.0.0.0/0"},
+			Allowed:      []*computepb.Allowed{{IPProtocol: ptr("tcp"), Ports: ports}},
+		}
+	}
+	denyTCP := func(name string, priority int32, ports ...string) *computepb.Firewall {
+		return &computepb.Firewall{
+			Name:         ptr(name),
+			Direction:    ptr("INGRESS"),
+			Priority:     ptr(priority),
+			SourceRanges: []string{"0.0.0.0/0"},
+			Denied:       []*computepb.Denied{{IPProtocol: ptr("tcp"), Ports: ports}},
+		}
+	}
+	withTargetTags := func(fw *computepb.Firewall, tags ...string) *computepb.Firewall {
+		fw.TargetTags = tags
+		return fw
+	}
+	fromTags := func(fw *computepb.Firewall, tags ...string) *computepb.Firewall {
+		fw.SourceRanges, fw.SourceTags = nil, tags
+		return fw
+	}
+	egress := &computepb.Firewall{
+		Name:              ptr("deny-egress-smtp"),
+		Direction:         ptr("EGRESS"),
+		Priority:          ptr(int32(1000)),
+		DestinationRanges: []string{"0.0.0.0/0"},
+		Denied:            []*computepb.Denied{{IPProtocol: ptr("tcp"), Ports: []string{"25"}}},
+	}
+
+	target := testReachEnd("10.128.0.2", "web")
+	otherNetwork := testReachEnd("10.128.0.3", "bastion")
+	otherNetwork.network = "projects/p/global/networks/other"
+
+	tests := []struct {
+		name      string
+		firewalls []*computepb.Firewall
+		direction string
+		peer      *reachEnd
+		port      int
+		want      types.ReachVerdict
+		rule      string
+	}{
+		{
+			name:      "deny wins a priority tie (allow listed first)",
+			firewalls: []*computepb.Firewall{allowTCP("allow-ssh", 1000, "22"), denyTCP("deny-ssh", 1000, "22")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 22,
+			want: types.ReachDeny, rule: "deny-ssh",
+		},
+		{
+			name:      "deny wins a priority tie (deny listed first)",
+			firewalls: []*computepb.Firewall{denyTCP("deny-ssh", 1000, "22"), allowTCP("allow-ssh", 1000, "22")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 22,
+			want: types.ReachDeny, rule: "deny-ssh",
+		},
+		{
+			name:      "lower priority number wins",
+			firewalls: []*computepb.Firewall{denyTCP("deny-all", 65534), allowTCP("allow-ssh", 900, "22")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 22,
+			want: types.ReachAllow, rule: "allow-ssh (priority 900)",
+		},
+		{
+			name:      "port range",
+			firewalls: []*computepb.Firewall{allowTCP("allow-app", 1000, "80", "8000-9000")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 8443,
+			want: types.ReachAllow, rule: "allow-app",
+		},
+		{
+			name:      "port outside the ranges",
+			firewalls: []*computepb.Firewall{allowTCP("allow-app", 1000, "80", "8000-9000")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 9001,
+			want: types.ReachDeny, rule: "implied deny ingress",
+		},
+		{
+			name:      "target tag present",
+			firewalls: []*computepb.Firewall{withTargetTags(allowTCP("allow-web", 1000, "443"), "web")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 443,
+			want: types.ReachAllow, rule: "allow-web",
+		},
+		{
+			name:      "rule for other targets",
+			firewalls: []*computepb.Firewall{withTargetTags(allowTCP("allow-db", 1000, "443"), "db")},
+			direction: "INGRESS", peer: testReachEnd("1.2.3.4"), port: 443,
+			want: types.ReachDeny, rule: "implied deny ingress",
+		},
+		{
+			name:      "source tag in the same network",
+			firewalls: []*computepb.Firewall{fromTags(allowTCP("allow-bastion", 1000, "22"), "bastion")},
+			direction: "INGRESS", peer: testReachEnd("10.128.0.9", "bastion"), port: 22,
+			want: types.ReachAllow, rule: "allow-bastion",
+		},
+		{
+			name:      "source tag in another network",
+			firewalls: []*computepb.Firewall{fromTags(allowTCP("allow-bastion", 1000, "22"), "bastion")},
+			direction: "INGRESS", peer: otherNetwork, port: 22,
+			want: types.ReachDeny, rule: "implied deny ingress",
+		},
+		{
+			name:      "implied allow egress",
+			firewalls: []*computepb.Firewall{allowTCP("allow-ssh", 1000, "22")},
+			direction: "EGRESS", peer: testReachEnd("8.8.8.8"), port: 443,
+			want: types.ReachAllow, rule: "implied allow egress",
+		},
+		{
+			name:      "egress deny",
+			firewalls: []*computepb.Firewall{egress},
+			direction: "EGRESS", peer: testReachEnd("8.8.8.8"), port: 25,
+			want: types.ReachDeny, rule: "deny-egress-smtp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, verdict, rule, detail := evalFirewalls("firewall", tt.firewalls, tt.direction, target, tt.peer, "tcp", tt.port)
+			if verdict != tt.want {
+				t.Errorf("verdict = %s, want %s (rule %q, %s)", verdict, tt.want, rule, detail)
+			}
+			if !strings.Contains(rule, tt.rule) {
+				t.Errorf("rule = %q, want it to contain %q", rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestEvalRoutes(t *testing.T) {
+	gateway := "projects/p/global/gateways/default-internet-gateway"
+	routes := []*computepb.Route{
+		{Name: ptr("default-route"), DestRange: ptr("0.0.0.0/0"), Priority: ptr(uint32(1000)), NextHopGateway: ptr(gateway)},
+		{Name: ptr("subnet-route"), DestRange: ptr("10.128.0.0/20"), Priority: ptr(uint32(0)), NextHopNetwork: ptr(testNetwork)},
+		{Name: ptr("peer-route"), DestRange: ptr("10.200.0.0/16"), Priority: ptr(uint32(0)), NextHopPeering: ptr("peer-a")},
+		{Name: ptr("vpn-low"), DestRange: ptr("10.200.5.0/24"), Priority: ptr(uint32(200)), NextHopVpnTunnel: ptr("projects/p/regions/r/vpnTunnels/backup")},
+		{Name: ptr("vpn-high"), DestRange: ptr("10.200.5.0/24"), Priority: ptr(uint32(100)), NextHopVpnTunnel: ptr("projects/p/regions/r/vpnTunnels/primary")},
+		{Name: ptr("proxy-route"), DestRange: ptr("0.0.0.0/0"), Priority: ptr(uint32(900)), Tags: []string{"egress-proxy"}, NextHopIp: ptr("10.128.0.50")},
+	}
+
+	withExternalIP := testReachEnd("10.128.0.2")
+	withExternalIP.inst = &computepb.Instance{NetworkInterfaces: []*computepb.NetworkInterface{{
+		AccessConfigs: []*computepb.AccessConfig{{NatIP: ptr("34.1.2.3")}},
+	}}}
+
+	tests := []struct {
+		name   string
+		routes []*computepb.Route
+		end    *reachEnd
+		ip     string
+		want   types.ReachVerdict
+		rule   string
+	}{
+		{name: "subnet route beats default", routes: routes, end: testReachEnd("10.128.0.2"), ip: "10.128.0.9", want: types.ReachAllow, rule: "subnet-route"},
+		{name: "/16 beats default", routes: routes, end: testReachEnd("10.128.0.2"), ip: "10.200.1.1", want: types.ReachAllow, rule: "peer-route"},
+		{name: "/24 beats /16, then lowest priority", routes: routes, end: testReachEnd("10.128.0.2"), ip: "10.200.5.1", want: types.ReachAllow, rule: "vpn-high"},
+		{name: "tagged route applies to tagged instances", routes: routes, end: testReachEnd("10.128.0.2", "egress-proxy"), ip: "8.8.8.8", want: types.ReachAllow, rule: "proxy-route"},
+		{name: "internet gateway without an external IP", routes: routes, end: testReachEnd("10.128.0.2"), ip: "8.8.8.8", want: types.ReachWarn, rule: "default-route"},
+		{name: "internet gateway with an external IP", routes: routes, end: withExternalIP, ip: "8.8.8.8", want: types.ReachAllow, rule: "default-route"},
+		{name: "no route", routes: routes[1:3], end: testReachEnd("10.128.0.2"), ip: "8.8.8.8", want: types.ReachDeny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, verdict, rule, detail := evalRoutes("route", tt.routes, tt.end, net.ParseIP(tt.ip))
+			if verdict != tt.want {
+				t.Errorf("verdict = %s, want %s (rule %q, %s)", verdict, tt.want, rule, detail)
+			}
+			if !strings.Contains(rule, tt.rule) {
+				t.Errorf("rule = %q, want it to contain %q", rule, tt.rule)
+			}
+		})
+	}
+}
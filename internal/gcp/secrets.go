@@ -0,0 +1,274 @@
+package gcp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	sm "google.golang.org/api/secretmanager/v1"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// secretNamePattern is the set of names Secret Manager accepts
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,255}$`)
+
+// GCPSecretsProvider implements provider.SecretsProvider for Secret Manager.
+type GCPSecretsProvider struct {
+	client *Client
+}
+
+// NewSecretsProvider creates a new Secret Manager provider backed by the given Client.
+func NewSecretsProvider(client *Client) *GCPSecretsProvider {
+	return &GCPSecretsProvider{client: client}
+}
+
+func (p *GCPSecretsProvider) newService(ctx context.Context) (*sm.Service, error) {
+	svc, err := sm.NewService(ctx, option.WithTokenSource(p.client.Credentials().TokenSource))
+	if err != nil {
+		return nil, fmt.Errorf("create secret manager service: %w", err)
+	}
+	return svc, nil
+}
+
+func (p *GCPSecretsProvider) secretPath(name string) string {
+	return fmt.Sprintf("projects/%s/secrets/%s", p.client.Project(), name)
+}
+
+// List returns the project's secrets whose names start with the filter prefix,
+// sorted by name.
+func (p *GCPSecretsProvider) List(ctx context.Context, filter *provider.SecretFilter) ([]types.Secret, error) {
+	svc, err := p.newService(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix string
+	if filter != nil {
+		if prefix, err = secretName(filter.Prefix); err != nil {
+			return nil, err
+		}
+	}
+
+	var secrets []types.Secret
+	call := svc.Projects.Secrets.List("projects/" + p.client.Project())
+	err = call.Pages(ctx, func(resp *sm.ListSecretsResponse) error {
+		for _, s := range resp.Secrets {
+			secret := smToSecret(s)
+			if strings.HasPrefix(secret.Name, prefix) {
+				secrets = append(secrets, secret)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list secrets: %w", err)
+	}
+
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+// Get returns the latest enabled version of a secret
+func (p *GCPSecretsProvider) Get(ctx context.Context, name string) (*types.SecretValue, error) {
+	return p.GetVersion(ctx, name, "latest")
+}
+
+// GetVersion returns one version of a secret by number, alias or "latest"
+func (p *GCPSecretsProvider) GetVersion(ctx context.Context, name, version string) (*types.SecretValue, error) {
+	name, err := secretName(name)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := p.newService(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := svc.Projects.Secrets.Versions.Access(p.secretPath(name) + "/versions/" + version).Context(ctx).Do()
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("secret %s version %s: %w", name, version, provider.ErrNotFound)
+		}
+		return nil, fmt.Errorf("access secret %s: %w", name, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode secret %s: %w", name, err)
+	}
+
+	sv := &types.SecretValue{
+		Secret: types.Secret{
+			Name:     name,
+			ARN:      p.secretPath(name),
+			Provider: "gcp",
+			Backend:  "secretmanager",
+		},
+		Value:   string(data),
+		Version: resp.Name[strings.LastIndex(resp.Name, "/")+1:],
+	}
+
+	// The access response has no timestamps; the version resource does
+	if v, err := svc.Projects.Secrets.Versions.Get(resp.Name).Context(ctx).Do(); err == nil {
+		sv.UpdatedAt = parseRFC3339(v.CreateTime)
+	}
+	if s, err := svc.Projects.Secrets.Get(p.secretPath(name)).Context(ctx).Do(); err == nil {
+		sv.CreatedAt = parseRFC3339(s.CreateTime)
+		sv.Raw = s
+	}
+	return sv, nil
+}
+
+// History returns a secret's versions, newest first. The newest enabled
+// version is current.
+func (p *GCPSecretsProvider) History(ctx context.Context, name string) ([]types.SecretVersion, error) {
+	name, err := secretName(name)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := p.newService(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []types.SecretVersion
+	err = svc.Projects.Secrets.Versions.List(p.secretPath(name)).Pages(ctx, func(resp *sm.ListSecretVersionsResponse) error {
+		for _, v := range resp.Versions {
+			versions = append(versions, types.SecretVersion{
+				Version:   v.Name[strings.LastIndex(v.Name, "/")+1:],
+				State:     strings.ToLower(v.State),
+				CreatedAt: parseRFC3339(v.CreateTime),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list secret versions %s: %w", name, err)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		a, _ := strconv.Atoi(versions[i].Version)
+		b, _ := strconv.Atoi(versions[j].Version)
+		return a > b
+	})
+	for i := range versions {
+		if versions[i].State == "enabled" {
+			versions[i].Current = true
+			break
+		}
+	}
+	return versions, nil
+}
+
+// Rollback writes the value of version as a new version, so it becomes
+// latest while the history stays intact
+func (p *GCPSecretsProvider) Rollback(ctx context.Context, name, version string) error {
+	old, err := p.GetVersion(ctx, name, version)
+	if err != nil {
+		return err
+	}
+	current, err := p.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if old.Version == current.Version {
+		return fmt.Errorf("version %s is already current", version)
+	}
+	return p.Set(ctx, name, old.Value)
+}
+
+// Set adds a new version to a secret, creating the secret (with automatic
+// replication) if it does not exist
+func (p *GCPSecretsProvider) Set(ctx context.Context, name string, value string) error {
+	name, err := secretName(name)
+	if err != nil {
+		return err
+	}
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: Secret Manager names may only contain letters, digits, - and _", name)
+	}
+
+	svc, err := p.newService(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &sm.AddSecretVersionRequest{
+		Payload: &sm.SecretPayload{Data: base64.StdEncoding.EncodeToString([]byte(value))},
+	}
+	_, err = svc.Projects.Secrets.AddVersion(p.secretPath(name), req).Context(ctx).Do()
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("add secret version %s: %w", name, err)
+	}
+
+	secret := &sm.Secret{Replication: &sm.Replication{Automatic: &sm.Automatic{}}}
+	if _, err := svc.Projects.Secrets.Create("projects/"+p.client.Project(), secret).SecretId(name).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("create secret %s: %w", name, err)
+	}
+	if _, err := svc.Projects.Secrets.AddVersion(p.secretPath(name), req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("add secret version %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes a secret and all of its versions
+func (p *GCPSecretsProvider) Delete(ctx context.Context, name string) error {
+	name, err := secretName(name)
+	if err != nil {
+		return err
+	}
+	svc, err := p.newService(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := svc.Projects.Secrets.Delete(p.secretPath(name)).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("delete secret %s: %w", name, err)
+	}
+	return nil
+}
+
+func smToSecret(s *sm.Secret) types.Secret {
+	return types.Secret{
+		Name:      s.Name[strings.LastIndex(s.Name, "/")+1:],
+		ARN:       s.Name,
+		CreatedAt: parseRFC3339(s.CreateTime),
+		Provider:  "gcp",
+		Backend:   "secretmanager",
+		Raw:       s,
+	}
+}
+
+// secretName strips an sm:// scheme, which on GCP addresses Secret Manager,
+// and rejects ssm:// names
+func secretName(name string) (string, error) {
+	if strings.HasPrefix(name, "ssm://") {
+		return "", fmt.Errorf("%s: ssm:// addresses AWS SSM Parameter Store, not available in a GCP context", name)
+	}
+	return strings.TrimPrefix(name, "sm://"), nil
+}
+
+// parseRFC3339 returns the zero time for an empty or malformed timestamp
+func parseRFC3339(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// isNotFound reports whether err is a googleapi 404
+func isNotFound(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusNotFound
+}
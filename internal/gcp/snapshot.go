@@ -0,0 +1,352 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// CreateSnapshot creates a machine image of the instance, or a snapshot of
+// each attached disk. Names are lowercased and trimmed to GCE's rules; disk
+// snapshots are named <name>-<device-name>.
+func (p *GCPVMProvider) CreateSnapshot(ctx context.Context, vm *types.VM, opts *provider.SnapshotOptions) ([]types.Snapshot, error) {
+	if opts == nil || opts.Name == "" {
+		return nil, fmt.Errorf("snapshot name required")
+	}
+	if vm.Zone == "" {
+		return nil, fmt.Errorf("could not determine zone for instance %s", vm.Name)
+	}
+
+	labels := map[string]string{provider.SnapshotSourceTag: vm.ID}
+	for k, v := range opts.Tags {
+		labels[k] = v
+	}
+	description := fmt.Sprintf("Created by cml from %s", vm.Name)
+	instanceURL := fmt.Sprintf("projects/%s/zones/%s/instances/%s", p.client.Project(), vm.Zone, vm.Name)
+
+	if opts.Kind != types.SnapshotKindDisk {
+		mc, err := p.newMachineImagesClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = mc.Close() }()
+
+		name := gceResourceName(opts.Name)
+		_, err = mc.Insert(ctx, &computepb.InsertMachineImageRequest{
+			Project: p.client.Project(),
+			MachineImageResource: &computepb.MachineImage{
+				Name:           &name,
+				Description:    &description,
+				SourceInstance: &instanceURL,
+				Labels:         labels,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("insert machine image: %w", err)
+		}
+		return []types.Snapshot{{
+			ID:        name,
+			Name:      name,
+			Kind:      types.SnapshotKindImage,
+			State:     types.SnapshotStatePending,
+			Progress:  -1,
+			SourceVM:  vm.ID,
+			CreatedAt: time.Now(),
+			Tags:      labels,
+			Provider:  "gcp",
+		}}, nil
+	}
+
+	inst, ok := vm.Raw.(*computepb.Instance)
+	if !ok {
+		return nil, fmt.Errorf("could not read disks of %s", vm.Name)
+	}
+
+	sc, err := p.newSnapshotsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = sc.Close() }()
+
+	var snaps []types.Snapshot
+	for _, disk := range inst.GetDisks() {
+		name := gceResourceName(opts.Name + "-" + disk.GetDeviceName())
+		source := disk.GetSource()
+		_, err := sc.Insert(ctx, &computepb.InsertSnapshotRequest{
+			Project: p.client.Project(),
+			SnapshotResource: &computepb.Snapshot{
+				Name:        &name,
+				Description: &description,
+				SourceDisk:  &source,
+				Labels:      labels,
+			},
+		})
+		if err != nil {
+			return snaps, fmt.Errorf("insert snapshot of %s: %w", disk.GetDeviceName(), err)
+		}
+		snaps = append(snaps, types.Snapshot{
+			ID:        name,
+			Name:      name,
+			Kind:      types.SnapshotKindDisk,
+			State:     types.SnapshotStatePending,
+			Progress:  -1,
+			SourceVM:  vm.ID,
+			SizeGB:    disk.GetDiskSizeGb(),
+			CreatedAt: time.Now(),
+			Tags:      labels,
+			Provider:  "gcp",
+		})
+	}
+	return snaps, nil
+}
+
+// GetSnapshot refreshes a machine image or disk snapshot. GCE reports
+// status only, so Progress stays -1 until the snapshot is ready.
+func (p *GCPVMProvider) GetSnapshot(ctx context.Context, snap *types.Snapshot) (*types.Snapshot, error) {
+	if snap.Kind == types.SnapshotKindDisk {
+		sc, err := p.newSnapshotsClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = sc.Close() }()
+
+		s, err := sc.Get(ctx, &computepb.GetSnapshotRequest{Project: p.client.Project(), Snapshot: snap.ID})
+		if err != nil {
+			return nil, fmt.Errorf("get snapshot: %w", err)
+		}
+		out := gceSnapshotToSnapshot(s)
+		return &out, nil
+	}
+
+	mc, err := p.newMachineImagesClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = mc.Close() }()
+
+	img, err := mc.Get(ctx, &computepb.GetMachineImageRequest{Project: p.client.Project(), MachineImage: snap.ID})
+	if err != nil {
+		return nil, fmt.Errorf("get machine image: %w", err)
+	}
+	out := machineImageToSnapshot(img)
+	return &out, nil
+}
+
+// ListSnapshots returns machine images and disk snapshots in the project,
+// newest first.
+func (p *GCPVMProvider) ListSnapshots(ctx context.Context, filter *provider.SnapshotFilter) ([]types.Snapshot, error) {
+	if filter == nil {
+		filter = &provider.SnapshotFilter{}
+	}
+
+	var conds []string
+	switch {
+	case filter.SourceVM != "":
+		conds = append(conds, fmt.Sprintf("labels.%s = %q", provider.SnapshotSourceTag, filter.SourceVM))
+	case filter.Managed:
+		conds = append(conds, fmt.Sprintf("labels.%s:*", provider.SnapshotSourceTag))
+	}
+	for k, v := range filter.Tags {
+		conds = append(conds, fmt.Sprintf("labels.%s = %q", k, v))
+	}
+	sort.Strings(conds)
+	var gceFilter *string
+	if len(conds) > 0 {
+		f := strings.Join(conds, " AND ")
+		gceFilter = &f
+	}
+
+	var snaps []types.Snapshot
+
+	if filter.Kind != types.SnapshotKindDisk {
+		mc, err := p.newMachineImagesClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = mc.Close() }()
+
+		it := mc.List(ctx, &computepb.ListMachineImagesRequest{Project: p.client.Project(), Filter: gceFilter})
+		for {
+			img, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("list machine images: %w", err)
+			}
+			snaps = append(snaps, machineImageToSnapshot(img))
+		}
+	}
+
+	if filter.Kind != types.SnapshotKindImage {
+		sc, err := p.newSnapshotsClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = sc.Close() }()
+
+		it := sc.List(ctx, &computepb.ListSnapshotsRequest{Project: p.client.Project(), Filter: gceFilter})
+		for {
+			s, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("list snapshots: %w", err)
+			}
+			snaps = append(snaps, gceSnapshotToSnapshot(s))
+		}
+	}
+
+	sort.SliceStable(snaps, func(i, j int) bool { return snaps[i].CreatedAt.After(snaps[j].CreatedAt) })
+	return snaps, nil
+}
+
+// DeleteSnapshot deletes a machine image or disk snapshot and waits for the
+// operation to complete.
+func (p *GCPVMProvider) DeleteSnapshot(ctx context.Context, snap *types.Snapshot) error {
+	if snap.Kind == types.SnapshotKindDisk {
+		sc, err := p.newSnapshotsClient(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = sc.Close() }()
+
+		op, err := sc.Delete(ctx, &computepb.DeleteSnapshotRequest{Project: p.client.Project(), Snapshot: snap.ID})
+		if err != nil {
+			return fmt.Errorf("delete snapshot: %w", err)
+		}
+		return op.Wait(ctx)
+	}
+
+	mc, err := p.newMachineImagesClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = mc.Close() }()
+
+	op, err := mc.Delete(ctx, &computepb.DeleteMachineImageRequest{Project: p.client.Project(), MachineImage: snap.ID})
+	if err != nil {
+		return fmt.Errorf("delete machine image: %w", err)
+	}
+	return op.Wait(ctx)
+}
+
+func (p *GCPVMProvider) newMachineImagesClient(ctx context.Context) (*compute.MachineImagesClient, error) {
+	c, err := compute.NewMachineImagesRESTClient(ctx, option.WithTokenSource(p.client.Credentials().TokenSource))
+	if err != nil {
+		return nil, fmt.Errorf("create machine images client: %w", err)
+	}
+	return c, nil
+}
+
+func (p *GCPVMProvider) newSnapshotsClient(ctx context.Context) (*compute.SnapshotsClient, error) {
+	c, err := compute.NewSnapshotsRESTClient(ctx, option.WithTokenSource(p.client.Credentials().TokenSource))
+	if err != nil {
+		return nil, fmt.Errorf("create snapshots client: %w", err)
+	}
+	return c, nil
+}
+
+// machineImageToSnapshot converts a GCE machine image to the unified type
+func machineImageToSnapshot(img *computepb.MachineImage) types.Snapshot {
+	s := types.Snapshot{
+		ID:       img.GetName(),
+		Name:     img.GetName(),
+		Kind:     types.SnapshotKindImage,
+		State:    gceSnapshotStatus(img.GetStatus()),
+		Progress: -1,
+		Tags:     img.GetLabels(),
+		Provider: "gcp",
+		Raw:      img,
+	}
+	if s.State == types.SnapshotStateAvailable {
+		s.Progress = 100
+	}
+	if s.Tags == nil {
+		s.Tags = make(map[string]string)
+	}
+	s.SourceVM = s.Tags[provider.SnapshotSourceTag]
+	if s.SourceVM == "" {
+		s.SourceVM = path.Base(img.GetSourceInstance())
+	}
+	for _, d := range img.GetSourceInstanceProperties().GetDisks() {
+		s.SizeGB += d.GetDiskSizeGb()
+	}
+	if t, err := time.Parse(time.RFC3339, img.GetCreationTimestamp()); err == nil {
+		s.CreatedAt = t
+	}
+	return s
+}
+
+// gceSnapshotToSnapshot converts a GCE disk snapshot to the unified type
+func gceSnapshotToSnapshot(snap *computepb.Snapshot) types.Snapshot {
+	s := types.Snapshot{
+		ID:       snap.GetName(),
+		Name:     snap.GetName(),
+		Kind:     types.SnapshotKindDisk,
+		State:    gceSnapshotStatus(snap.GetStatus()),
+		Progress: -1,
+		SizeGB:   snap.GetDiskSizeGb(),
+		Tags:     snap.GetLabels(),
+		Provider: "gcp",
+		Raw:      snap,
+	}
+	if s.State == types.SnapshotStateAvailable {
+		s.Progress = 100
+	}
+	if s.Tags == nil {
+		s.Tags = make(map[string]string)
+	}
+	s.SourceVM = s.Tags[provider.SnapshotSourceTag]
+	if t, err := time.Parse(time.RFC3339, snap.GetCreationTimestamp()); err == nil {
+		s.CreatedAt = t
+	}
+	return s
+}
+
+// gceSnapshotStatus maps machine image and snapshot statuses
+func gceSnapshotStatus(status string) types.SnapshotState {
+	switch status {
+	case "READY":
+		return types.SnapshotStateAvailable
+	case "FAILED", "INVALID":
+		return types.SnapshotStateFailed
+	case "DELETING":
+		return types.SnapshotStateDeleting
+	default: // CREATING, UPLOADING
+		return types.SnapshotStatePending
+	}
+}
+
+// gceResourceName lowercases s and replaces characters GCE resource names
+// do not allow, trimming to 63 characters.
+func gceResourceName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, s)
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		name = "s-" + name
+	}
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.TrimRight(name, "-")
+}
@@ -0,0 +1,92 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/googleapi"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+// setLabelsAttempts bounds retries when the label fingerprint changes
+// between reading and writing the labels.
+const setLabelsAttempts = 3
+
+// SetTags adds or overwrites labels on a GCE instance.
+func (p *GCPVMProvider) SetTags(ctx context.Context, vm *types.VM, tags map[string]string) error {
+	return p.updateLabels(ctx, vm, func(labels map[string]string) {
+		for k, v := range tags {
+			labels[k] = v
+		}
+	})
+}
+
+// RemoveTags deletes labels from a GCE instance. Missing keys are ignored.
+func (p *GCPVMProvider) RemoveTags(ctx context.Context, vm *types.VM, keys []string) error {
+	return p.updateLabels(ctx, vm, func(labels map[string]string) {
+		for _, k := range keys {
+			delete(labels, k)
+		}
+	})
+}
+
+// updateLabels applies change to the instance's current labels and writes
+// them with setLabels. setLabels replaces the whole map and requires the
+// current label fingerprint, so the instance is re-read on each attempt and
+// the write is retried if another change landed in between (HTTP 412).
+func (p *GCPVMProvider) updateLabels(ctx context.Context, vm *types.VM, change func(map[string]string)) error {
+	if vm.Zone == "" {
+		return fmt.Errorf("could not determine zone for instance %s", vm.Name)
+	}
+
+	ic, err := p.newInstancesClient(ctx)
+	if err != nil {
+		return fmt.Errorf("create instances client: %w", err)
+	}
+	defer func() { _ = ic.Close() }()
+
+	for attempt := 1; ; attempt++ {
+		err := p.setLabelsOnce(ctx, ic, vm, change)
+		var gerr *googleapi.Error
+		if err == nil || attempt == setLabelsAttempts ||
+			!errors.As(err, &gerr) || gerr.Code != http.StatusPreconditionFailed {
+			return err
+		}
+	}
+}
+
+func (p *GCPVMProvider) setLabelsOnce(ctx context.Context, ic *compute.InstancesClient, vm *types.VM, change func(map[string]string)) error {
+	inst, err := ic.Get(ctx, &computepb.GetInstanceRequest{
+		Project:  p.client.Project(),
+		Zone:     vm.Zone,
+		Instance: vm.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("get instance: %w", err)
+	}
+
+	labels := make(map[string]string, len(inst.GetLabels()))
+	for k, v := range inst.GetLabels() {
+		labels[k] = v
+	}
+	change(labels)
+
+	op, err := ic.SetLabels(ctx, &computepb.SetLabelsInstanceRequest{
+		Project:  p.client.Project(),
+		Zone:     vm.Zone,
+		Instance: vm.Name,
+		InstancesSetLabelsRequestResource: &computepb.InstancesSetLabelsRequest{
+			LabelFingerprint: inst.LabelFingerprint,
+			Labels:           labels,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("set labels: %w", err)
+	}
+	return op.Wait(ctx)
+}
@@ -3,9 +3,11 @@ package gcp
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	"google.golang.org/api/option"
 
 	"github.com/vietdv277/cumulus/pkg/provider"
+	"github.com/vietdv277/cumulus/pkg/query"
 	"github.com/vietdv277/cumulus/pkg/types"
 )
 
@@ -146,11 +149,38 @@ func buildFilter(filter *provider.VMFilter) string {
 		for k, v := range filter.Tags {
 			parts = append(parts, fmt.Sprintf("labels.%s=%s", k, v))
 		}
+
+		if filter.Where != nil {
+			parts = append(parts, whereToGCEFilter(filter.Where)...)
+		}
 	}
 
 	return strings.Join(parts, " AND ")
 }
 
+// whereToGCEFilter translates single-valued --where terms GCE can evaluate
+// server-side. Everything else is left to client-side filtering.
+func whereToGCEFilter(q *query.Query) []string {
+	var parts []string
+	for _, term := range q.Terms() {
+		if len(term.Values) != 1 {
+			continue
+		}
+		v := term.Values[0]
+
+		switch {
+		case term.Field == "name", term.Field == "id":
+			parts = append(parts, fmt.Sprintf("%s=%s", term.Field, strconv.Quote(v)))
+		case strings.HasPrefix(term.Field, "tags."):
+			parts = append(parts, fmt.Sprintf("labels.%s=%s", strings.TrimPrefix(term.Field, "tags."), strconv.Quote(v)))
+		case term.Field == "state" && v == string(types.VMStateRunning):
+			// Other unified states cover several GCE statuses
+			parts = append(parts, "status=RUNNING")
+		}
+	}
+	return parts
+}
+
 // gceToVM converts a GCE Instance proto to the unified VM type.
 func gceToVM(inst *computepb.Instance) types.VM {
 	vm := types.VM{
@@ -407,26 +437,10 @@ func (p *GCPVMProvider) Connect(ctx context.Context, nameOrID string) error {
 	}
 
 	if p.client.Bastion() != "" {
-		bastionProject := p.client.BastionProject()
-		if bastionProject == "" {
-			bastionProject = p.client.Project()
-		}
-		bastionZone := p.client.BastionZone()
-		if bastionZone == "" {
-			bastionZone = p.client.Region()
-		}
-		args := []string{
-			"compute",
-			"--project", bastionProject,
-			"ssh",
-			"--zone", bastionZone,
-			p.client.Bastion(),
+		args := append(p.bastionSSHArgs(p.client.Bastion()),
 			"--ssh-flag=-tA",
 			"--command", fmt.Sprintf("ssh %s", vm.PrivateIP),
-		}
-		if p.client.BastionIAP() {
-			args = append(args, "--tunnel-through-iap")
-		}
+		)
 		cmd := exec.CommandContext(ctx, "gcloud", args...)
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
@@ -444,11 +458,19 @@ func (p *GCPVMProvider) Connect(ctx context.Context, nameOrID string) error {
 	return cmd.Run()
 }
 
-// Tunnel creates a port-forwarding tunnel via `gcloud compute ssh`.
+// Tunnel creates port-forwarding tunnels (blocking). Through a bastion, all
+// forwards share one `gcloud compute ssh -L` session to the bastion, with
+// the instance's private IP as the default remote host. Without a bastion,
+// ports on the instance itself are forwarded natively through IAP and
+// remote-host forwards use `gcloud compute ssh -L` to the instance.
 func (p *GCPVMProvider) Tunnel(ctx context.Context, nameOrID string, opts *provider.TunnelOptions) error {
 	if opts == nil {
 		return fmt.Errorf("tunnel options required")
 	}
+	forwards := opts.AllForwards()
+	if len(forwards) == 0 {
+		return fmt.Errorf("no forwards specified")
+	}
 
 	vm, err := p.resolveVM(ctx, nameOrID)
 	if err != nil {
@@ -456,32 +478,50 @@ func (p *GCPVMProvider) Tunnel(ctx context.Context, nameOrID string, opts *provi
 	}
 
 	if p.client.Bastion() != "" {
-		remoteHost := opts.RemoteHost
-		if remoteHost == "" {
-			remoteHost = vm.PrivateIP
+		args := append(p.bastionSSHArgs(p.client.Bastion()), "--ssh-flag=-A", "--", "-N")
+		for _, f := range forwards {
+			remoteHost := f.RemoteHost
+			if remoteHost == "" {
+				remoteHost = vm.PrivateIP
+			}
+			args = append(args, "-L", fmt.Sprintf("%d:%s:%d", f.LocalPort, remoteHost, f.RemotePort))
 		}
-		localArg := fmt.Sprintf("%d:%s:%d", opts.LocalPort, remoteHost, opts.RemotePort)
+		cmd := exec.CommandContext(ctx, "gcloud", args...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
 
-		bastionProject := p.client.BastionProject()
-		if bastionProject == "" {
-			bastionProject = p.client.Project()
-		}
-		bastionZone := p.client.BastionZone()
-		if bastionZone == "" {
-			bastionZone = p.client.Region()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// A port on the instance itself goes straight through IAP, no gcloud needed
+	var listeners []*IAPListener
+	defer func() {
+		for _, ln := range listeners {
+			_ = ln.Close()
 		}
-		args := []string{
-			"compute",
-			"--project", bastionProject,
-			"ssh",
-			"--zone", bastionZone,
-			p.client.Bastion(),
-			"--ssh-flag=-A",
+	}()
+	var sshArgs []string
+	for _, f := range forwards {
+		if f.RemoteHost != "" {
+			sshArgs = append(sshArgs, "-L", fmt.Sprintf("%d:%s:%d", f.LocalPort, f.RemoteHost, f.RemotePort))
+			continue
 		}
-		if p.client.BastionIAP() {
-			args = append(args, "--tunnel-through-iap")
+		ln, err := p.ListenIAP(ctx, p.instanceIAPTarget(vm, f.RemotePort), f.LocalPort)
+		if err != nil {
+			return err
 		}
-		args = append(args, "--", "-N", "-L", localArg)
+		listeners = append(listeners, ln)
+		fmt.Fprintf(os.Stderr, "Listening on %s (IAP → %s:%d)\n", ln.Addr(), vm.Name, f.RemotePort)
+	}
+
+	if len(sshArgs) > 0 {
+		args := append([]string{"compute", "ssh", vm.Name,
+			"--project", p.client.Project(),
+			"--zone", vm.Zone,
+			"--", "-N"}, sshArgs...)
 		cmd := exec.CommandContext(ctx, "gcloud", args...)
 		cmd.Stdin = os.Stdin
 		cmd.Stdout = os.Stdout
@@ -489,20 +529,119 @@ func (p *GCPVMProvider) Tunnel(ctx context.Context, nameOrID string, opts *provi
 		return cmd.Run()
 	}
 
-	remoteHost := opts.RemoteHost
-	if remoteHost == "" {
-		remoteHost = "localhost"
+	// Run until interrupted or until any listener fails
+	done := make(chan struct{}, len(listeners))
+	for _, ln := range listeners {
+		go func(ln *IAPListener) {
+			_ = ln.Wait()
+			done <- struct{}{}
+		}(ln)
 	}
+	select {
+	case <-ctx.Done():
+	case <-done:
+	}
+	return nil
+}
 
-	localArg := fmt.Sprintf("%d:%s:%d", opts.LocalPort, remoteHost, opts.RemotePort)
-
-	cmd := exec.CommandContext(ctx, "gcloud", "compute", "ssh", vm.Name,
-		"--project", p.client.Project(),
-		"--zone", vm.Zone,
-		"--", "-N", "-L", localArg,
+// StartPortForward starts a background `gcloud compute ssh -N -L` session to
+// the bastion, forwarding localPort to remotePort on the bastion itself, and
+// returns the running command. Caller owns the process lifecycle (Wait/Kill).
+// Output is wired to os.Stderr so the caller's stdout stays clean for subshells.
+func (p *GCPVMProvider) StartPortForward(ctx context.Context, bastion string, remotePort, localPort int) (*exec.Cmd, error) {
+	localArg := fmt.Sprintf("%d:localhost:%d", localPort, remotePort)
+	args := append(p.bastionSSHArgs(bastion),
+		"--", "-N", "-o", "ExitOnForwardFailure=yes", "-L", localArg,
 	)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+
+	cmd := exec.CommandContext(ctx, "gcloud", args...)
+	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start gcloud compute ssh (is gcloud installed?): %w", err)
+	}
+	return cmd, nil
+}
+
+// bastionSSHArgs returns the `gcloud compute ssh` arguments that reach the
+// named bastion, using the bastion project/zone (falling back to the context
+// project/region) and IAP when enabled.
+func (p *GCPVMProvider) bastionSSHArgs(bastion string) []string {
+	bastionProject := p.client.BastionProject()
+	if bastionProject == "" {
+		bastionProject = p.client.Project()
+	}
+	bastionZone := p.client.BastionZone()
+	if bastionZone == "" {
+		bastionZone = p.client.Region()
+	}
+	args := []string{
+		"compute",
+		"--project", bastionProject,
+		"ssh",
+		"--zone", bastionZone,
+		bastion,
+	}
+	if p.client.BastionIAP() {
+		args = append(args, "--tunnel-through-iap")
+	}
+	return args
+}
+
+// IAPTunnel forwards localPort to remotePort on the instance through
+// Identity-Aware Proxy (blocking until ctx is cancelled). The instance needs
+// no external IP and no bastion.
+func (p *GCPVMProvider) IAPTunnel(ctx context.Context, nameOrID string, remotePort, localPort int) error {
+	vm, err := p.resolveVM(ctx, nameOrID)
+	if err != nil {
+		return err
+	}
+
+	ln, err := p.ListenIAP(ctx, p.instanceIAPTarget(vm, remotePort), localPort)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", ln.Addr())
+	return ln.Wait()
+}
+
+// ListenIAP starts a native IAP listener on 127.0.0.1:localPort forwarding
+// to target. Caller closes the listener.
+func (p *GCPVMProvider) ListenIAP(ctx context.Context, target IAPTarget, localPort int) (*IAPListener, error) {
+	dialer := NewIAPDialer(p.client.Credentials().TokenSource)
+	return dialer.Listen(ctx, fmt.Sprintf("127.0.0.1:%d", localPort), target)
+}
+
+// Dial opens an IAP tunnel to port on the instance. The context bastion is
+// recognised by name so it resolves in the bastion project and zone.
+func (p *GCPVMProvider) Dial(ctx context.Context, nameOrID string, port int) (io.ReadWriteCloser, error) {
+	var target IAPTarget
+	if bastion := p.client.Bastion(); bastion != "" && nameOrID == bastion {
+		target = p.BastionIAPTarget(bastion, port)
+	} else {
+		vm, err := p.resolveVM(ctx, nameOrID)
+		if err != nil {
+			return nil, err
+		}
+		target = p.instanceIAPTarget(vm, port)
+	}
+	return NewIAPDialer(p.client.Credentials().TokenSource).Dial(ctx, target)
+}
+
+// BastionIAPTarget returns the IAP target for a port on the named bastion,
+// honouring the bastion project and zone.
+func (p *GCPVMProvider) BastionIAPTarget(bastion string, port int) IAPTarget {
+	project := p.client.BastionProject()
+	if project == "" {
+		project = p.client.Project()
+	}
+	zone := p.client.BastionZone()
+	if zone == "" {
+		zone = p.client.Region()
+	}
+	return IAPTarget{Project: project, Zone: zone, Instance: bastion, Port: port}
+}
+
+func (p *GCPVMProvider) instanceIAPTarget(vm *types.VM, port int) IAPTarget {
+	return IAPTarget{Project: p.client.Project(), Zone: vm.Zone, Instance: vm.Name, Port: port}
 }
@@ -0,0 +1,39 @@
+package gcp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vietdv277/cumulus/pkg/query"
+)
+
+func TestWhereToGCEFilter(t *testing.T) {
+	tests := []struct {
+		where string
+		want  []string
+	}{
+		{`name=web-1`, []string{`name="web-1"`}},
+		{`id=123 && tags.team=payments`, []string{`id="123"`, `labels.team="payments"`}},
+		{`name="a \"b\""`, []string{`name="a \"b\""`}},
+		{`state=running`, []string{"status=RUNNING"}},
+
+		// Left to client-side filtering
+		{`state=stopped`, nil},
+		{`name in (web-1, web-2)`, nil},
+		{`zone=us-central1-a && type=e2-small`, nil},
+		{`name=web-1 || name=web-2`, nil},
+		{`!tags.team=payments`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			q, err := query.Parse(tt.where)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := whereToGCEFilter(q); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"path"
+	"strings"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	pkgtypes "github.com/vietdv277/cumulus/pkg/types"
+)
+
+// gceReservedIPs is the number of addresses GCE reserves in every primary
+// subnet range (network, gateway, second-to-last and broadcast).
+const gceReservedIPs = 4
+
+// newNetworksClient returns an authenticated GCE Networks REST client.
+func (c *Client) newNetworksClient(ctx context.Context) (*compute.NetworksClient, error) {
+	return compute.NewNetworksRESTClient(ctx,
+		option.WithTokenSource(c.Credentials().TokenSource),
+	)
+}
+
+// newSubnetworksClient returns an authenticated GCE Subnetworks REST client.
+func (c *Client) newSubnetworksClient(ctx context.Context) (*compute.SubnetworksClient, error) {
+	return compute.NewSubnetworksRESTClient(ctx,
+		option.WithTokenSource(c.Credentials().TokenSource),
+	)
+}
+
+// SharedVPCHost returns the shared-VPC host project of the configured project,
+// or "" when the project is not attached to a host project.
+func (c *Client) SharedVPCHost() (string, error) {
+	pc, err := compute.NewProjectsRESTClient(c.ctx,
+		option.WithTokenSource(c.Credentials().TokenSource),
+	)
+	if err != nil {
+		return "", fmt.Errorf("create projects client: %w", err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	host, err := pc.GetXpnHost(c.ctx, &computepb.GetXpnHostProjectRequest{
+		Project: c.project,
+	})
+	if err != nil {
+		return "", fmt.Errorf("get shared VPC host: %w", err)
+	}
+	return host.GetName(), nil
+}
+
+// ListVPCs returns the VPC networks of the configured project. When the
+// project is a shared-VPC service project, the host project's networks are
+// included as well and marked Shared.
+func (c *Client) ListVPCs() ([]pkgtypes.VPC, error) {
+	nc, err := c.newNetworksClient(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create networks client: %w", err)
+	}
+	defer func() { _ = nc.Close() }()
+
+	vpcs, err := c.listNetworks(nc, c.project)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shared VPC lookup is best-effort: service projects without
+	// compute.projects.get on themselves still get their own networks.
+	host, err := c.SharedVPCHost()
+	if err != nil || host == "" || host == c.project {
+		return vpcs, nil
+	}
+
+	hostVPCs, err := c.listNetworks(nc, host)
+	if err != nil {
+		return nil, fmt.Errorf("list networks in shared VPC host %s: %w", host, err)
+	}
+	for i := range hostVPCs {
+		hostVPCs[i].Shared = true
+	}
+	return append(vpcs, hostVPCs...), nil
+}
+
+func (c *Client) listNetworks(nc *compute.NetworksClient, project string) ([]pkgtypes.VPC, error) {
+	var vpcs []pkgtypes.VPC
+	it := nc.List(c.ctx, &computepb.ListNetworksRequest{Project: project})
+	for {
+		n, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list networks: %w", err)
+		}
+		vpcs = append(vpcs, gceToVPC(n, project))
+	}
+	return vpcs, nil
+}
+
+// DescribeVPC returns a network by name, numeric ID, or "project/name".
+// Returns nil when no visible network matches.
+func (c *Client) DescribeVPC(vpcID string) (*pkgtypes.VPC, error) {
+	vpcs, err := c.ListVPCs()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range vpcs {
+		v := &vpcs[i]
+		if v.ID == vpcID || v.Name == vpcID || v.OwnerID+"/"+v.Name == vpcID {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListSubnets returns the subnetworks of a network across all regions,
+// including secondary ranges and Private Google Access. For shared VPC
+// networks the subnetworks are read from the host project.
+func (c *Client) ListSubnets(vpcID string) ([]pkgtypes.Subnet, error) {
+	vpc, err := c.DescribeVPC(vpcID)
+	if err != nil {
+		return nil, err
+	}
+	if vpc == nil {
+		return nil, fmt.Errorf("network not found: %s", vpcID)
+	}
+
+	network, ok := vpc.Raw.(*computepb.Network)
+	if !ok {
+		return nil, fmt.Errorf("network %s has no self link", vpcID)
+	}
+
+	sc, err := c.newSubnetworksClient(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create subnetworks client: %w", err)
+	}
+	defer func() { _ = sc.Close() }()
+
+	var subnets []pkgtypes.Subnet
+	it := sc.AggregatedList(c.ctx, &computepb.AggregatedListSubnetworksRequest{
+		Project: vpc.OwnerID,
+	})
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list subnetworks: %w", err)
+		}
+		for _, s := range pair.Value.GetSubnetworks() {
+			if s.GetNetwork() != network.GetSelfLink() {
+				continue
+			}
+			subnets = append(subnets, gceToSubnet(s, vpc.ID))
+		}
+	}
+	return subnets, nil
+}
+
+// gceToVPC converts a GCE Network to our VPC type. GCE networks are global
+// and stateless, so State is always "available".
+func gceToVPC(n *computepb.Network, project string) pkgtypes.VPC {
+	return pkgtypes.VPC{
+		ID:        fmt.Sprintf("%d", n.GetId()),
+		Name:      n.GetName(),
+		CIDR:      n.GetIPv4Range(), // legacy networks only
+		State:     "available",
+		IsDefault: n.GetName() == "default",
+		OwnerID:   project,
+		Raw:       n,
+	}
+}
+
+// gceToSubnet converts a GCE Subnetwork to our Subnet type.
+func gceToSubnet(s *computepb.Subnetwork, vpcID string) pkgtypes.Subnet {
+	subnet := pkgtypes.Subnet{
+		ID:                  fmt.Sprintf("%d", s.GetId()),
+		Name:                s.GetName(),
+		VPCID:               vpcID,
+		CIDR:                s.GetIpCidrRange(),
+		Region:              path.Base(s.GetRegion()),
+		AvailableIPs:        usableIPs(s.GetIpCidrRange()),
+		State:               gceSubnetState(s.GetState()),
+		PrivateGoogleAccess: s.GetPrivateIpGoogleAccess(),
+	}
+
+	for _, r := range s.GetSecondaryIpRanges() {
+		subnet.SecondaryRanges = append(subnet.SecondaryRanges, pkgtypes.SecondaryRange{
+			Name: r.GetRangeName(),
+			CIDR: r.GetIpCidrRange(),
+		})
+	}
+
+	return subnet
+}
+
+// gceSubnetState maps the subnetwork state to the AWS-style vocabulary used
+// by the subnet table. An empty state means the subnetwork is ready.
+func gceSubnetState(state string) string {
+	switch state {
+	case "", "READY":
+		return "available"
+	default:
+		return strings.ToLower(state)
+	}
+}
+
+// usableIPs returns the size of a primary IPv4 range minus the addresses GCE
+// reserves. It does not account for addresses already in use.
+func usableIPs(cidr string) int {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil || !prefix.Addr().Is4() {
+		return 0
+	}
+	size := 1 << (32 - prefix.Bits())
+	if size <= gceReservedIPs {
+		return 0
+	}
+	return size - gceReservedIPs
+}
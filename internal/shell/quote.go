@@ -0,0 +1,21 @@
+// Package shell builds command lines for POSIX shells on remote hosts.
+package shell
+
+import "strings"
+
+// Quote quotes s as a single word for a POSIX shell: it is wrapped in single
+// quotes, and each embedded single quote closes the quoting, adds an escaped
+// quote and reopens it.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes each argument and joins them with spaces, so the shell sees
+// exactly args.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = Quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,43 @@
+package shell
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", `''`},
+		{"plain", `'plain'`},
+		{"two words", `'two words'`},
+		{"it's", `'it'\''s'`},
+		{`$HOME "x" \n`, `'$HOME "x" \n'`},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJoinRoundTrip(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	args := []string{"a b", "it's", `$HOME`, "*", "", "semi;colon", "new\nline"}
+
+	script := "set -- " + Join(args) + `; for a in "$@"; do printf '%s\0' "$a"; done`
+	out, err := exec.Command(sh, "-c", script).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	if !reflect.DeepEqual(got, args) {
+		t.Errorf("shell saw %q, want %q", got, args)
+	}
+}
@@ -241,3 +241,195 @@ func formatSubnetState(state string, width int) string {
 	cell := " " + padRight(stateText, width) + " "
 	return style.Render(cell)
 }
+
+// GCP network table column widths
+var gcpVPCColumnWidths = []int{30, 22, 30, 8, 8}
+
+// GCP subnetwork table column widths
+var gcpSubnetColumnWidths = []int{28, 24, 18, 40, 6}
+
+// PrintGCPVPCTable prints GCP VPC networks in a styled box table. Networks
+// from a shared-VPC host project are flagged in the Shared column.
+func PrintGCPVPCTable(vpcs []pkgtypes.VPC) {
+	headers := []string{"Name", "ID", "Project", "Default", "Shared"}
+
+	var sb strings.Builder
+
+	// Top border
+	sb.WriteString(BorderStyle.Render(TopLeft))
+	for i, w := range gcpVPCColumnWidths {
+		sb.WriteString(BorderStyle.Render(strings.Repeat(Horizontal, w+2)))
+		if i < len(gcpVPCColumnWidths)-1 {
+			sb.WriteString(BorderStyle.Render(TopT))
+		}
+	}
+	sb.WriteString(BorderStyle.Render(TopRight))
+	sb.WriteString("\n")
+
+	// Header row
+	sb.WriteString(BorderStyle.Render(Vertical))
+	for i, h := range headers {
+		cell := " " + padRight(h, gcpVPCColumnWidths[i]) + " "
+		sb.WriteString(HeaderStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+	}
+	sb.WriteString("\n")
+
+	// Header separator
+	sb.WriteString(BorderStyle.Render(LeftT))
+	for i, w := range gcpVPCColumnWidths {
+		sb.WriteString(BorderStyle.Render(strings.Repeat(Horizontal, w+2)))
+		if i < len(gcpVPCColumnWidths)-1 {
+			sb.WriteString(BorderStyle.Render(Cross))
+		}
+	}
+	sb.WriteString(BorderStyle.Render(RightT))
+	sb.WriteString("\n")
+
+	// Data rows
+	for _, vpc := range vpcs {
+		sb.WriteString(BorderStyle.Render(Vertical))
+
+		// Name
+		cell := " " + padRight(vpc.Name, gcpVPCColumnWidths[0]) + " "
+		sb.WriteString(NameStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+
+		// ID
+		cell = " " + padRight(vpc.ID, gcpVPCColumnWidths[1]) + " "
+		sb.WriteString(IDStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+
+		// Project
+		cell = " " + padRight(vpc.OwnerID, gcpVPCColumnWidths[2]) + " "
+		sb.WriteString(GCPStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+
+		// Default
+		cell = " " + padRight(formatBool(vpc.IsDefault), gcpVPCColumnWidths[3]) + " "
+		sb.WriteString(MutedStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+
+		// Shared
+		cell = " " + padRight(formatBool(vpc.Shared), gcpVPCColumnWidths[4]) + " "
+		sb.WriteString(MutedStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+
+		sb.WriteString("\n")
+	}
+
+	// Bottom border
+	sb.WriteString(BorderStyle.Render(BottomLeft))
+	for i, w := range gcpVPCColumnWidths {
+		sb.WriteString(BorderStyle.Render(strings.Repeat(Horizontal, w+2)))
+		if i < len(gcpVPCColumnWidths)-1 {
+			sb.WriteString(BorderStyle.Render(BottomT))
+		}
+	}
+	sb.WriteString(BorderStyle.Render(BottomRight))
+	sb.WriteString("\n")
+
+	fmt.Print(sb.String())
+	fmt.Printf("  %d networks\n", len(vpcs))
+}
+
+// PrintGCPSubnetTable prints GCP subnetworks with their region, primary and
+// secondary ranges, and Private Google Access flag.
+func PrintGCPSubnetTable(subnets []pkgtypes.Subnet) {
+	headers := []string{"Name", "Region", "Primary CIDR", "Secondary Ranges", "PGA"}
+
+	var sb strings.Builder
+
+	// Top border
+	sb.WriteString(BorderStyle.Render(TopLeft))
+	for i, w := range gcpSubnetColumnWidths {
+		sb.WriteString(BorderStyle.Render(strings.Repeat(Horizontal, w+2)))
+		if i < len(gcpSubnetColumnWidths)-1 {
+			sb.WriteString(BorderStyle.Render(TopT))
+		}
+	}
+	sb.WriteString(BorderStyle.Render(TopRight))
+	sb.WriteString("\n")
+
+	// Header row
+	sb.WriteString(BorderStyle.Render(Vertical))
+	for i, h := range headers {
+		cell := " " + padRight(h, gcpSubnetColumnWidths[i]) + " "
+		sb.WriteString(HeaderStyle.Render(cell))
+		sb.WriteString(BorderStyle.Render(Vertical))
+	}
+	sb.WriteString("\n")
+
+	// Header separator
+	sb.WriteString(BorderStyle.Render(LeftT))
+	for i, w := range gcpSubnetColumnWidths {
+		sb.WriteString(BorderStyle.Render(strings.Repeat(Horizontal, w+2)))
+		if i < len(gcpSubnetColumnWidths)-1 {
+			sb.WriteString(BorderStyle.Render(Cross))
+		}
+	}
+	sb.WriteString(BorderStyle.Render(RightT))
+	sb.WriteString("\n")
+
+	// Data rows: one row per secondary range so wide GKE layouts stay readable
+	for _, subnet := range subnets {
+		ranges := []string{""}
+		if len(subnet.SecondaryRanges) > 0 {
+			ranges = ranges[:0]
+			for _, r := range subnet.SecondaryRanges {
+				ranges = append(ranges, r.Name+" "+r.CIDR)
+			}
+		}
+
+		for i, r := range ranges {
+			name, region, cidr, pga := "", "", "", ""
+			if i == 0 {
+				name, region, cidr = subnet.Name, subnet.Region, subnet.CIDR
+				pga = formatBool(subnet.PrivateGoogleAccess)
+			}
+
+			sb.WriteString(BorderStyle.Render(Vertical))
+
+			// Name
+			cell := " " + padRight(name, gcpSubnetColumnWidths[0]) + " "
+			sb.WriteString(NameStyle.Render(cell))
+			sb.WriteString(BorderStyle.Render(Vertical))
+
+			// Region
+			cell = " " + padRight(region, gcpSubnetColumnWidths[1]) + " "
+			sb.WriteString(AZStyle.Render(cell))
+			sb.WriteString(BorderStyle.Render(Vertical))
+
+			// Primary CIDR
+			cell = " " + padRight(cidr, gcpSubnetColumnWidths[2]) + " "
+			sb.WriteString(IPStyle.Render(cell))
+			sb.WriteString(BorderStyle.Render(Vertical))
+
+			// Secondary range
+			cell = " " + padRight(r, gcpSubnetColumnWidths[3]) + " "
+			sb.WriteString(MutedStyle.Render(cell))
+			sb.WriteString(BorderStyle.Render(Vertical))
+
+			// Private Google Access
+			cell = " " + padRight(pga, gcpSubnetColumnWidths[4]) + " "
+			sb.WriteString(MutedStyle.Render(cell))
+			sb.WriteString(BorderStyle.Render(Vertical))
+
+			sb.WriteString("\n")
+		}
+	}
+
+	// Bottom border
+	sb.WriteString(BorderStyle.Render(BottomLeft))
+	for i, w := range gcpSubnetColumnWidths {
+		sb.WriteString(BorderStyle.Render(strings.Repeat(Horizontal, w+2)))
+		if i < len(gcpSubnetColumnWidths)-1 {
+			sb.WriteString(BorderStyle.Render(BottomT))
+		}
+	}
+	sb.WriteString(BorderStyle.Render(BottomRight))
+	sb.WriteString("\n")
+
+	fmt.Print(sb.String())
+	fmt.Printf("  %d subnetworks\n", len(subnets))
+}
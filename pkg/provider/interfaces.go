@@ -7,7 +7,9 @@ package provider
 import (
 	"context"
 	"errors"
+	"io"
 
+	"github.com/vietdv277/cumulus/pkg/query"
 	"github.com/vietdv277/cumulus/pkg/types"
 )
 
@@ -25,6 +27,11 @@ type VMFilter struct {
 	State string            // running, stopped, etc.
 	Name  string            // Name pattern
 	Tags  map[string]string // Tag filters
+
+	// Where is an optional --where expression. Providers push what they can
+	// of Where.Terms() into the API query; callers apply Where.Filter to the
+	// result.
+	Where *query.Query
 }
 
 // VMProvider defines the interface for VM operations
@@ -51,16 +58,166 @@ type VMProvider interface {
 	Tunnel(ctx context.Context, nameOrID string, opts *TunnelOptions) error
 }
 
+// VMExecutor is implemented by VM providers that can run non-interactive
+// commands on a VM.
+type VMExecutor interface {
+	// Exec runs command on the VM, writing its output to stdout and stderr,
+	// and returns the remote exit code. A non-nil error means the command
+	// could not be run or its result could not be determined.
+	Exec(ctx context.Context, vm *types.VM, command string, stdout, stderr io.Writer) (int, error)
+}
+
+// VMDialer is implemented by VM providers that can open a raw TCP stream to
+// a port on a VM without a public IP (e.g. an SSH ProxyCommand).
+type VMDialer interface {
+	Dial(ctx context.Context, nameOrID string, port int) (io.ReadWriteCloser, error)
+}
+
+// VMConsole is implemented by VM providers that can read a VM's serial
+// console, which still works when the VM fails to boot.
+type VMConsole interface {
+	// ConsoleOutput writes the console output to w. With follow it keeps
+	// polling and writes only new output until ctx is cancelled.
+	ConsoleOutput(ctx context.Context, vm *types.VM, follow bool, w io.Writer) error
+
+	// Screenshot returns an image of the VM's screen (JPEG or PNG)
+	Screenshot(ctx context.Context, vm *types.VM) ([]byte, error)
+}
+
+// CopyOptions contains options for copying files to and from a VM
+type CopyOptions struct {
+	StagingBucket string // AWS: S3 bucket used to stage the transfer
+}
+
+// VMCopier is implemented by VM providers that can copy files to and from
+// a VM. Paths are single regular files.
+type VMCopier interface {
+	// CopyTo uploads localPath to remotePath on the VM
+	CopyTo(ctx context.Context, vm *types.VM, localPath, remotePath string, opts *CopyOptions) error
+
+	// CopyFrom downloads remotePath on the VM to localPath
+	CopyFrom(ctx context.Context, vm *types.VM, remotePath, localPath string, opts *CopyOptions) error
+}
+
+// VMCreateOptions contains options for creating VMs from a template
+type VMCreateOptions struct {
+	Template string            // AWS: launch template name or ID; GCP: instance template name or URL
+	Version  string            // AWS: launch template version (default: $Default); GCP: unsupported
+	Name     string            // VM name; suffixed -1..n when Count > 1
+	Count    int               // Number of VMs (default 1)
+	Zone     string            // GCP: zone to create in (default: context region if it is a zone)
+	Tags     map[string]string // Extra tags (GCP: labels) merged with the template's
+}
+
+// VMCreator is implemented by VM providers that can create VMs from
+// templates and terminate them.
+type VMCreator interface {
+	// Create launches VMs from a template and returns them as created
+	Create(ctx context.Context, opts *VMCreateOptions) ([]types.VM, error)
+
+	// CheckTerminate returns an error if vm is protected against termination
+	CheckTerminate(ctx context.Context, vm *types.VM) error
+
+	// Terminate permanently deletes the VM
+	Terminate(ctx context.Context, vm *types.VM) error
+}
+
+// VMTagger is implemented by VM providers that can change a VM's tags
+// (GCP: labels).
+type VMTagger interface {
+	// SetTags adds tags, overwriting existing values for the same keys
+	SetTags(ctx context.Context, vm *types.VM, tags map[string]string) error
+
+	// RemoveTags deletes the given tag keys; missing keys are ignored
+	RemoveTags(ctx context.Context, vm *types.VM, keys []string) error
+}
+
+// SnapshotSourceTag is the tag (GCP: label) cml sets on images and
+// snapshots it creates, holding the source VM ID.
+const SnapshotSourceTag = "cml-source"
+
+// SnapshotOptions contains options for snapshotting a VM
+type SnapshotOptions struct {
+	Name   string             // Image name, or snapshot name prefix for disks
+	Kind   types.SnapshotKind // image (default) or disk
+	Reboot bool               // AWS images: reboot for a consistent file system
+	Tags   map[string]string  // Extra tags (GCP: labels)
+}
+
+// SnapshotFilter contains filters for snapshot listing
+type SnapshotFilter struct {
+	Kind     types.SnapshotKind // image or disk; empty for both
+	SourceVM string             // Only snapshots of this VM ID
+	Tags     map[string]string  // Only snapshots with these tags
+	Managed  bool               // Only snapshots created by cml
+}
+
+// VMSnapshotter is implemented by VM providers that can image and snapshot
+// VMs.
+type VMSnapshotter interface {
+	// CreateSnapshot starts an image or disk snapshots of vm and returns
+	// them without waiting for completion
+	CreateSnapshot(ctx context.Context, vm *types.VM, opts *SnapshotOptions) ([]types.Snapshot, error)
+
+	// GetSnapshot returns the current state and progress of a snapshot
+	GetSnapshot(ctx context.Context, snap *types.Snapshot) (*types.Snapshot, error)
+
+	// ListSnapshots returns images and snapshots owned by the account/project
+	ListSnapshots(ctx context.Context, filter *SnapshotFilter) ([]types.Snapshot, error)
+
+	// DeleteSnapshot deletes an image (with its backing snapshots on AWS)
+	// or a disk snapshot
+	DeleteSnapshot(ctx context.Context, snap *types.Snapshot) error
+}
+
+// ReachEndpoint is one end of a reachability check: a VM, or a bare IP
+// address (VM nil) such as an on-premises host or the internet.
+type ReachEndpoint struct {
+	VM *types.VM
+	IP string
+}
+
+// VMReachability is implemented by VM providers that can explain, from
+// firewall, ACL and route configuration alone, whether traffic can flow
+// between two endpoints.
+type VMReachability interface {
+	// Reach evaluates traffic from src to port on dst over protocol
+	// (tcp or udp) without sending any packets.
+	Reach(ctx context.Context, src, dst ReachEndpoint, protocol string, port int) (*types.ReachReport, error)
+}
+
 // TunnelOptions contains options for creating a tunnel
 type TunnelOptions struct {
 	LocalPort  int
 	RemotePort int
 	RemoteHost string // For remote host forwarding
+
+	// Forwards are additional forwards opened alongside the one above
+	Forwards []ForwardSpec
+}
+
+// ForwardSpec is one local port forwarded through the VM. An empty
+// RemoteHost targets the VM itself.
+type ForwardSpec struct {
+	LocalPort  int
+	RemoteHost string
+	RemotePort int
+}
+
+// AllForwards returns the primary forward (if RemotePort is set) followed by
+// Forwards.
+func (o *TunnelOptions) AllForwards() []ForwardSpec {
+	var specs []ForwardSpec
+	if o.RemotePort > 0 {
+		specs = append(specs, ForwardSpec{LocalPort: o.LocalPort, RemoteHost: o.RemoteHost, RemotePort: o.RemotePort})
+	}
+	return append(specs, o.Forwards...)
 }
 
 // SecretFilter contains filters for secret listing
 type SecretFilter struct {
-	Prefix string
+	Prefix  string
+	Backend string // AWS: ssm or sm; empty for both
 }
 
 // SecretsProvider defines the interface for secrets operations
@@ -78,6 +235,31 @@ type SecretsProvider interface {
 	Delete(ctx context.Context, name string) error
 }
 
+// SecretVersioner is implemented by secrets providers that keep a version
+// history.
+type SecretVersioner interface {
+	// History returns the versions of a secret, newest first
+	History(ctx context.Context, name string) ([]types.SecretVersion, error)
+
+	// GetVersion returns the value of one version (or SSM label / Secrets
+	// Manager stage)
+	GetVersion(ctx context.Context, name, version string) (*types.SecretValue, error)
+
+	// Rollback makes version the current value again: Secrets Manager moves
+	// AWSCURRENT to it; SSM and GCP write its value as a new version.
+	Rollback(ctx context.Context, name, version string) error
+}
+
+// SecretRotator is implemented by secrets providers that can trigger
+// rotation (AWS Secrets Manager).
+type SecretRotator interface {
+	// Rotate starts a rotation and returns the resulting status
+	Rotate(ctx context.Context, name string) (*types.SecretRotation, error)
+
+	// RotationStatus returns the rotation configuration and state
+	RotationStatus(ctx context.Context, name string) (*types.SecretRotation, error)
+}
+
 // DBFilter contains filters for database listing
 type DBFilter struct {
 	Engine string // mysql, postgres, etc.
@@ -0,0 +1,349 @@
+package query
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokIn
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// wordBreak lists characters that end a bare word.
+const wordBreak = `()!=<>~&|,"'`
+
+// lex splits the expression into tokens.
+func lex(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			toks = append(toks, token{tokComma, ",", i})
+			i++
+		case strings.HasPrefix(s[i:], "&&"):
+			toks = append(toks, token{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			toks = append(toks, token{tokOr, "||", i})
+			i += 2
+		case c == '"' || c == '\'':
+			end := i + 1
+			var sb strings.Builder
+			for end < len(s) && s[end] != c {
+				if s[end] == '\\' && end+1 < len(s) {
+					end++
+				}
+				sb.WriteByte(s[end])
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			toks = append(toks, token{tokString, sb.String(), i})
+			i = end + 1
+		case strings.ContainsRune("=!<>~", rune(c)):
+			op := string(c)
+			if i+1 < len(s) && strings.ContainsRune("=~", rune(s[i+1])) {
+				op = s[i : i+2]
+			}
+			switch op {
+			case "=", "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+				toks = append(toks, token{tokOp, op, i})
+			case "!":
+				toks = append(toks, token{tokNot, op, i})
+			default:
+				return nil, fmt.Errorf("unknown operator %q at position %d", op, i)
+			}
+			i += len(op)
+		default:
+			start := i
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && !strings.ContainsRune(wordBreak, rune(s[i])) {
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			word := s[start:i]
+			switch strings.ToLower(word) {
+			case "and":
+				toks = append(toks, token{tokAnd, word, start})
+			case "or":
+				toks = append(toks, token{tokOr, word, start})
+			case "not":
+				toks = append(toks, token{tokNot, word, start})
+			case "in":
+				toks = append(toks, token{tokIn, word, start})
+			default:
+				toks = append(toks, token{tokWord, word, start})
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(s)}), nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+	now  time.Time
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), t.pos)
+}
+
+// parseOr parses: and ('||' and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary ('&&' unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses: '!' unary | '(' or ')' | comparison
+func (p *parser) parseUnary() (node, error) {
+	switch t := p.peek(); t.kind {
+	case tokNot:
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner}, nil
+	case tokLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, p.errorf(r, "expected ')'")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses: field op value | field ['not'] 'in' values
+func (p *parser) parseComparison() (node, error) {
+	ft := p.next()
+	if ft.kind != tokWord {
+		return nil, p.errorf(ft, "expected field name")
+	}
+	f, err := lookupField(ft.text)
+	if err != nil {
+		return nil, p.errorf(ft, "%v", err)
+	}
+
+	negate := false
+	if p.peek().kind == tokNot {
+		p.next()
+		negate = true
+		if p.peek().kind != tokIn {
+			return nil, p.errorf(p.peek(), "expected 'in' after 'not'")
+		}
+	}
+
+	op := p.next()
+	switch op.kind {
+	case tokIn:
+		c, err := p.parseIn(f, ft)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			return &notNode{c}, nil
+		}
+		return c, nil
+	case tokOp:
+	default:
+		return nil, p.errorf(op, "expected operator after %s", ft.text)
+	}
+
+	vt := p.next()
+	if vt.kind != tokWord && vt.kind != tokString {
+		return nil, p.errorf(vt, "expected value after %s", op.text)
+	}
+
+	c := &compareNode{field: f, op: op.text, value: vt.text}
+	if c.op == "==" {
+		c.op = "="
+	}
+
+	switch c.op {
+	case "=~", "!~":
+		if f.kind != kindString && f.kind != kindIP {
+			return nil, p.errorf(op, "%s does not support %s", f.name, op.text)
+		}
+		re, err := regexp.Compile("^(?:" + vt.text + ")$")
+		if err != nil {
+			return nil, p.errorf(vt, "invalid regex: %v", err)
+		}
+		c.re = re
+	case "<", "<=", ">", ">=":
+		if f.kind != kindTime && f.kind != kindAge {
+			return nil, p.errorf(op, "%s does not support %s", f.name, op.text)
+		}
+	}
+
+	switch f.kind {
+	case kindTime:
+		t, err := parseTime(vt.text, p.now)
+		if err != nil {
+			return nil, p.errorf(vt, "%v", err)
+		}
+		c.t = t
+	case kindAge:
+		d, err := ParseDuration(vt.text)
+		if err != nil {
+			return nil, p.errorf(vt, "%v", err)
+		}
+		c.d = d
+	}
+	return c, nil
+}
+
+// parseIn parses the right-hand side of 'in': a parenthesised list, or a
+// CIDR for IP fields.
+func (p *parser) parseIn(f field, ft token) (*inNode, error) {
+	n := &inNode{field: f}
+
+	if t := p.peek(); t.kind != tokLParen {
+		p.next()
+		if f.kind != kindIP || (t.kind != tokWord && t.kind != tokString) {
+			return nil, p.errorf(t, "expected '(' list after in")
+		}
+		_, cidr, err := net.ParseCIDR(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "invalid CIDR %q", t.text)
+		}
+		n.cidr = cidr
+		return n, nil
+	}
+
+	p.next()
+	for {
+		t := p.next()
+		if t.kind != tokWord && t.kind != tokString {
+			return nil, p.errorf(t, "expected value in list")
+		}
+		n.values = append(n.values, t.text)
+
+		switch sep := p.next(); sep.kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			if f.kind == kindTime || f.kind == kindAge {
+				return nil, p.errorf(ft, "%s does not support in", f.name)
+			}
+			return n, nil
+		default:
+			return nil, p.errorf(sep, "expected ',' or ')'")
+		}
+	}
+}
+
+var shortDuration = regexp.MustCompile(`^(\d+)([smhdw])$`)
+
+// ParseDuration accepts Go durations plus whole days (30d) and weeks (2w).
+// It is shared by commands taking an age, such as --older-than.
+func ParseDuration(s string) (time.Duration, error) {
+	if m := shortDuration.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{
+			"s": time.Second,
+			"m": time.Minute,
+			"h": time.Hour,
+			"d": 24 * time.Hour,
+			"w": 7 * 24 * time.Hour,
+		}[m[2]]
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 30d, 12h, 2w)", s)
+	}
+	return d, nil
+}
+
+// parseTime accepts RFC 3339, a date, or a duration meaning that long ago.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if d, err := ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use 2006-01-02, RFC 3339 or a duration like 30d)", s)
+}
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []token
+	}{
+		{
+			in: `type=m5.large`,
+			want: []token{
+				{tokWord, "type", 0}, {tokOp, "=", 4}, {tokWord, "m5.large", 5},
+			},
+		},
+		{
+			in: `tags.team == "pay ments"`,
+			want: []token{
+				{tokWord, "tags.team", 0}, {tokOp, "==", 10}, {tokString, "pay ments", 13},
+			},
+		},
+		{
+			in: `name=~'web-\'[0-9]+'`,
+			want: []token{
+				{tokWord, "name", 0}, {tokOp, "=~", 4}, {tokString, `web-'[0-9]+`, 6},
+			},
+		},
+		{
+			in: `!(a!=b)&&c!~d||e`,
+			want: []token{
+				{tokNot, "!", 0}, {tokLParen, "(", 1}, {tokWord, "a", 2}, {tokOp, "!=", 3},
+				{tokWord, "b", 5}, {tokRParen, ")", 6}, {tokAnd, "&&", 7}, {tokWord, "c", 9},
+				{tokOp, "!~", 10}, {tokWord, "d", 12}, {tokOr, "||", 13}, {tokWord, "e", 15},
+			},
+		},
+		{
+			in: `zone NOT In (a, "b") and x or y`,
+			want: []token{
+				{tokWord, "zone", 0}, {tokNot, "NOT", 5}, {tokIn, "In", 9}, {tokLParen, "(", 12},
+				{tokWord, "a", 13}, {tokComma, ",", 14}, {tokString, "b", 16}, {tokRParen, ")", 19},
+				{tokAnd, "and", 21}, {tokWord, "x", 25}, {tokOr, "or", 27}, {tokWord, "y", 30},
+			},
+		},
+		{
+			in: `private_ip in 10.0.0.0/8`,
+			want: []token{
+				{tokWord, "private_ip", 0}, {tokIn, "in", 11}, {tokWord, "10.0.0.0/8", 14},
+			},
+		},
+		{
+			in: `age>=30d`,
+			want: []token{
+				{tokWord, "age", 0}, {tokOp, ">=", 3}, {tokWord, "30d", 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := lex(tt.in)
+			if err != nil {
+				t.Fatalf("lex: %v", err)
+			}
+			want := append(tt.want, token{tokEOF, "", len(tt.in)})
+			if len(got) != len(want) {
+				t.Fatalf("tokens = %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("token %d = %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`name="web`, "unterminated string at position 5"},
+		{`name>~web`, `unknown operator ">~" at position 4`},
+		{`name & web`, `unexpected '&' at position 5`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			_, err := lex(tt.in)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "45s", want: 45 * time.Second},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "12h", want: 12 * time.Hour},
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "1h30m", want: 90 * time.Minute},
+		{in: "0d", want: 0},
+		{in: "1.5d", wantErr: true},
+		{in: "d", wantErr: true},
+		{in: "3y", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDuration(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDuration(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2024-01-02T03:04:05Z", want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{in: "2024-01-02", want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{in: "1w", want: now.Add(-7 * 24 * time.Hour)},
+		{in: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseTime(tt.in, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseTime(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("parseTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// Package query implements the --where expression language used to filter
+// VMs, e.g.
+//
+//	tags.team=payments && type=~"m5.*" && private_ip in 10.1.0.0/16 && launched_at < 30d
+//
+// Comparisons combine with && (and), || (or), ! (not) and parentheses.
+// Expressions are evaluated client-side with Match; Terms exposes the
+// top-level equality conditions a provider may push down into its API.
+package query
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindIP
+	kindTime
+	kindAge
+)
+
+type field struct {
+	name string // as written, e.g. "type" or "tags.team"
+	kind fieldKind
+	get  func(vm *types.VM) string
+}
+
+// fields lists the fixed field names. tags.<key> is handled separately.
+var fields = map[string]field{
+	"id":          {kind: kindString, get: func(vm *types.VM) string { return vm.ID }},
+	"name":        {kind: kindString, get: func(vm *types.VM) string { return vm.Name }},
+	"state":       {kind: kindString, get: func(vm *types.VM) string { return string(vm.State) }},
+	"type":        {kind: kindString, get: func(vm *types.VM) string { return vm.Type }},
+	"zone":        {kind: kindString, get: func(vm *types.VM) string { return vm.Zone }},
+	"provider":    {kind: kindString, get: func(vm *types.VM) string { return vm.Provider }},
+	"asg":         {kind: kindString, get: func(vm *types.VM) string { return vm.ASG }},
+	"private_ip":  {kind: kindIP, get: func(vm *types.VM) string { return vm.PrivateIP }},
+	"public_ip":   {kind: kindIP, get: func(vm *types.VM) string { return vm.PublicIP }},
+	"launched_at": {kind: kindTime},
+	"age":         {kind: kindAge},
+}
+
+func lookupField(name string) (field, error) {
+	if key, ok := strings.CutPrefix(name, "tags."); ok && key != "" {
+		return field{name: name, kind: kindString, get: func(vm *types.VM) string { return vm.GetTag(key) }}, nil
+	}
+	f, ok := fields[name]
+	if !ok {
+		names := make([]string, 0, len(fields)+1)
+		for n := range fields {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		names = append(names, "tags.<key>")
+		return field{}, fmt.Errorf("unknown field %q (fields: %s)", name, strings.Join(names, ", "))
+	}
+	f.name = name
+	return f, nil
+}
+
+// Query is a parsed --where expression.
+type Query struct {
+	src  string
+	root node
+}
+
+// Parse parses a --where expression. Relative times such as
+// "launched_at < 30d" are resolved against the current time.
+func Parse(s string) (*Query, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("where: %w", err)
+	}
+	p := &parser{toks: toks, now: time.Now()}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("where: empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("where: %w", err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("where: unexpected %q at position %d", t.text, t.pos)
+	}
+	return &Query{src: s, root: root}, nil
+}
+
+// String returns the expression as given to Parse.
+func (q *Query) String() string { return q.src }
+
+// Match reports whether vm satisfies the expression.
+func (q *Query) Match(vm *types.VM) bool { return q.root.match(vm) }
+
+// Filter returns the VMs that satisfy the expression.
+func (q *Query) Filter(vms []types.VM) []types.VM {
+	var out []types.VM
+	for i := range vms {
+		if q.Match(&vms[i]) {
+			out = append(out, vms[i])
+		}
+	}
+	return out
+}
+
+// Term is a condition every match must satisfy: Field equals one of Values.
+type Term struct {
+	Field  string // e.g. "type" or "tags.team"
+	Values []string
+}
+
+// Terms returns the top-level && conditions of the form field = value or
+// field in (a, b). A provider may translate any of them into API filters to
+// narrow the listing; the full expression must still be applied afterwards.
+func (q *Query) Terms() []Term {
+	var terms []Term
+	var walk func(n node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *andNode:
+			walk(n.left)
+			walk(n.right)
+		case *compareNode:
+			if n.op == "=" && (n.field.kind == kindString || n.field.kind == kindIP) {
+				terms = append(terms, Term{Field: n.field.name, Values: []string{n.value}})
+			}
+		case *inNode:
+			if n.cidr == nil {
+				terms = append(terms, Term{Field: n.field.name, Values: n.values})
+			}
+		}
+	}
+	walk(q.root)
+	return terms
+}
+
+// References reports whether the expression mentions field anywhere.
+func (q *Query) References(name string) bool {
+	var walk func(n node) bool
+	walk = func(n node) bool {
+		switch n := n.(type) {
+		case *andNode:
+			return walk(n.left) || walk(n.right)
+		case *orNode:
+			return walk(n.left) || walk(n.right)
+		case *notNode:
+			return walk(n.inner)
+		case *compareNode:
+			return n.field.name == name
+		case *inNode:
+			return n.field.name == name
+		}
+		return false
+	}
+	return walk(q.root)
+}
+
+type node interface {
+	match(vm *types.VM) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) match(vm *types.VM) bool { return n.left.match(vm) && n.right.match(vm) }
+
+type orNode struct{ left, right node }
+
+func (n *orNode) match(vm *types.VM) bool { return n.left.match(vm) || n.right.match(vm) }
+
+type notNode struct{ inner node }
+
+func (n *notNode) match(vm *types.VM) bool { return !n.inner.match(vm) }
+
+type compareNode struct {
+	field field
+	op    string
+	value string
+	re    *regexp.Regexp
+	t     time.Time
+	d     time.Duration
+}
+
+func (n *compareNode) match(vm *types.VM) bool {
+	switch n.field.kind {
+	case kindTime:
+		if vm.LaunchedAt.IsZero() {
+			return false
+		}
+		return compareOrdered(vm.LaunchedAt.Compare(n.t), n.op)
+	case kindAge:
+		if vm.LaunchedAt.IsZero() {
+			return false
+		}
+		age := time.Since(vm.LaunchedAt)
+		switch {
+		case age < n.d:
+			return compareOrdered(-1, n.op)
+		case age > n.d:
+			return compareOrdered(1, n.op)
+		}
+		return compareOrdered(0, n.op)
+	}
+
+	v := n.field.get(vm)
+	switch n.op {
+	case "=":
+		return v == n.value
+	case "!=":
+		return v != n.value
+	case "=~":
+		return n.re.MatchString(v)
+	case "!~":
+		return !n.re.MatchString(v)
+	}
+	return false
+}
+
+// compareOrdered applies op to the result of a three-way comparison.
+func compareOrdered(cmp int, op string) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+type inNode struct {
+	field  field
+	values []string
+	cidr   *net.IPNet
+}
+
+func (n *inNode) match(vm *types.VM) bool {
+	v := n.field.get(vm)
+	if n.cidr != nil {
+		ip := net.ParseIP(v)
+		return ip != nil && n.cidr.Contains(ip)
+	}
+	for _, want := range n.values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
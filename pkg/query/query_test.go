@@ -0,0 +1,181 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vietdv277/cumulus/pkg/types"
+)
+
+func TestMatch(t *testing.T) {
+	vm := &types.VM{
+		ID:         "i-0abc",
+		Name:       "web-1",
+		State:      types.VMStateRunning,
+		Type:       "m5.large",
+		Zone:       "us-east-1a",
+		PrivateIP:  "10.1.2.3",
+		Tags:       map[string]string{"team": "payments", "env": "prod"},
+		LaunchedAt: time.Now().Add(-48 * time.Hour),
+	}
+
+	tests := []struct {
+		where string
+		want  bool
+	}{
+		{`name=web-1`, true},
+		{`name == "web-1"`, true},
+		{`name != web-1`, false},
+		{`tags.team=payments`, true},
+		{`tags.missing=""`, true},
+
+		// && binds tighter than ||, ! tighter than both
+		{`name=db || name=web-1 && type=m5.large`, true},
+		{`name=web-1 || name=db && type=t3.micro`, true},
+		{`(name=web-1 || name=db) && type=t3.micro`, false},
+		{`!name=db && type=m5.large`, true},
+		{`!(name=web-1 && type=t3.micro)`, true},
+		{`not name=web-1 or state=running`, true},
+		{`! ! name=web-1`, true},
+
+		// in with a list or a CIDR
+		{`zone in (us-east-1a, "us-east-1b")`, true},
+		{`zone in (us-east-1b)`, false},
+		{`zone not in (us-east-1a)`, false},
+		{`private_ip in 10.1.0.0/16`, true},
+		{`private_ip in "10.2.0.0/16"`, false},
+		{`private_ip not in 10.2.0.0/16`, true},
+		{`public_ip in 0.0.0.0/0`, false},
+
+		// Regexes must match the whole value
+		{`type=~m5`, false},
+		{`type=~"m5.*"`, true},
+		{`type=~large`, false},
+		{`type=~"m5|t3"`, false},
+		{`type=~"m5.large|t3"`, true},
+		{`name!~"web"`, true},
+		{`name!~"web-[0-9]"`, false},
+
+		{`launched_at < 1d`, true},
+		{`launched_at > 1w`, true},
+		{`age > 1d`, true},
+		{`age <= 1d`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			q, err := Parse(tt.where)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := q.Match(vm); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchNoLaunchTime(t *testing.T) {
+	vm := &types.VM{Name: "web-1"}
+	for _, where := range []string{`age > 1d`, `age < 1d`, `launched_at < 1d`, `launched_at >= 1d`} {
+		q, err := Parse(where)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", where, err)
+		}
+		if q.Match(vm) {
+			t.Errorf("%q matched a VM without a launch time", where)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		where string
+		want  string
+	}{
+		{``, "empty expression"},
+		{`colour=red`, `unknown field "colour"`},
+		{`name`, "expected operator after name"},
+		{`name=`, "expected value after ="},
+		{`(name=web`, "expected ')'"},
+		{`name=web)`, `unexpected ")"`},
+		{`name=~"["`, "invalid regex"},
+		{`name < web`, "name does not support <"},
+		{`age =~ 1d`, "age does not support =~"},
+		{`age in (1d)`, "age does not support in"},
+		{`name in 10.0.0.0/8`, "expected '(' list after in"},
+		{`private_ip in 10.0.0.0`, `invalid CIDR "10.0.0.0"`},
+		{`zone in (a b)`, "expected ',' or ')'"},
+		{`zone not = a`, "expected 'in' after 'not'"},
+		{`age > soon`, `invalid duration "soon"`},
+		{`launched_at > soon`, `invalid time "soon"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			_, err := Parse(tt.where)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		where string
+		want  []Term
+	}{
+		{`name=web`, []Term{{"name", []string{"web"}}}},
+		{
+			`tags.team=payments && zone in (a, b) && type=~"m5.*"`,
+			[]Term{{"tags.team", []string{"payments"}}, {"zone", []string{"a", "b"}}},
+		},
+		{`(name=web && state=running) && type=m5`, []Term{
+			{"name", []string{"web"}}, {"state", []string{"running"}}, {"type", []string{"m5"}},
+		}},
+
+		// Terms under || or ! do not constrain every match
+		{`name=web || name=db`, nil},
+		{`state=running && (name=web || name=db)`, []Term{{"state", []string{"running"}}}},
+		{`!name=web && zone=a`, []Term{{"zone", []string{"a"}}}},
+		{`zone not in (a, b)`, nil},
+		{`!(name=web && zone=a)`, nil},
+
+		// Only equality on string and IP fields
+		{`name!=web`, nil},
+		{`private_ip in 10.0.0.0/8`, nil},
+		{`private_ip=10.0.0.1`, []Term{{"private_ip", []string{"10.0.0.1"}}}},
+		{`age > 1d && launched_at < 2024-01-01`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.where, func(t *testing.T) {
+			q, err := Parse(tt.where)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := q.Terms(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Terms = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	q, err := Parse(`name=web || !(zone in (a) && private_ip in 10.0.0.0/8) || age > 1d`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for _, name := range []string{"name", "zone", "private_ip", "age"} {
+		if !q.References(name) {
+			t.Errorf("References(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"type", "launched_at", "tags.name"} {
+		if q.References(name) {
+			t.Errorf("References(%q) = true", name)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package types
+
+// ReachVerdict is the outcome of one reachability check
+type ReachVerdict string
+
+const (
+	ReachAllow ReachVerdict = "allow" // traffic passes this check
+	ReachDeny  ReachVerdict = "deny"  // traffic is blocked here
+	ReachWarn  ReachVerdict = "warn"  // may be blocked; depends on something not evaluated
+	ReachInfo  ReachVerdict = "info"  // not evaluated or not applicable
+)
+
+// ReachStep is one check along the path, naming the rule that decided it
+type ReachStep struct {
+	Check   string       `json:"check"`            // e.g. "source security groups (egress)"
+	Verdict ReachVerdict `json:"verdict"`          // allow, deny, warn, info
+	Rule    string       `json:"rule,omitempty"`   // the deciding rule or route
+	Detail  string       `json:"detail,omitempty"` // why, or what to change
+}
+
+// ReachReport explains whether traffic from Source can reach Destination
+type ReachReport struct {
+	Source      string      `json:"source"`
+	SourceIP    string      `json:"source_ip"`
+	Destination string      `json:"destination"`
+	DestIP      string      `json:"destination_ip"`
+	Protocol    string      `json:"protocol"`
+	Port        int         `json:"port"`
+	Steps       []ReachStep `json:"steps"`
+}
+
+// Allowed returns true when no check denies the traffic
+func (r *ReachReport) Allowed() bool {
+	for _, s := range r.Steps {
+		if s.Verdict == ReachDeny {
+			return false
+		}
+	}
+	return true
+}
+
+// Add appends a step to the report
+func (r *ReachReport) Add(check string, verdict ReachVerdict, rule, detail string) {
+	r.Steps = append(r.Steps, ReachStep{Check: check, Verdict: verdict, Rule: rule, Detail: detail})
+}
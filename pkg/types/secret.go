@@ -9,6 +9,10 @@ type Secret struct {
 	CreatedAt time.Time `json:"created_at"` // Creation time
 	UpdatedAt time.Time `json:"updated_at"` // Last update time
 	Provider  string    `json:"provider"`   // aws, gcp
+	Backend   string    `json:"backend"`    // ssm, sm (AWS Secrets Manager), secretmanager (GCP)
+
+	Type            string `json:"type,omitempty"`             // SSM: String, StringList, SecureString
+	RotationEnabled bool   `json:"rotation_enabled,omitempty"` // AWS Secrets Manager: automatic rotation configured
 
 	// Raw holds the original API response
 	Raw interface{} `json:"-"`
@@ -20,3 +24,22 @@ type SecretValue struct {
 	Value   string `json:"value"`   // The secret value
 	Version string `json:"version"` // Version identifier
 }
+
+// SecretVersion is one entry in a secret's version history
+type SecretVersion struct {
+	Version   string    `json:"version"`              // SSM: number; Secrets Manager: version ID; GCP: number
+	Labels    []string  `json:"labels,omitempty"`     // SSM labels, Secrets Manager stages (AWSCURRENT, ...)
+	State     string    `json:"state,omitempty"`      // GCP: enabled, disabled, destroyed
+	Current   bool      `json:"current"`              // the version Get returns
+	CreatedAt time.Time `json:"created_at"`           // When the version was created
+	CreatedBy string    `json:"created_by,omitempty"` // SSM: IAM principal that wrote it
+}
+
+// SecretRotation is the rotation status of a secret
+type SecretRotation struct {
+	Enabled        bool      `json:"enabled"`
+	LambdaARN      string    `json:"lambda_arn,omitempty"`
+	LastRotated    time.Time `json:"last_rotated"`
+	NextRotation   time.Time `json:"next_rotation"`
+	PendingVersion string    `json:"pending_version,omitempty"` // version being rotated in, if any
+}
@@ -0,0 +1,43 @@
+package types
+
+import "time"
+
+// SnapshotKind distinguishes whole-VM images from per-disk snapshots
+type SnapshotKind string
+
+const (
+	SnapshotKindImage SnapshotKind = "image" // AWS AMI, GCP machine image
+	SnapshotKindDisk  SnapshotKind = "disk"  // AWS EBS snapshot, GCP disk snapshot
+)
+
+// SnapshotState represents the state of a snapshot or image
+type SnapshotState string
+
+const (
+	SnapshotStatePending   SnapshotState = "pending"
+	SnapshotStateAvailable SnapshotState = "available"
+	SnapshotStateFailed    SnapshotState = "failed"
+	SnapshotStateDeleting  SnapshotState = "deleting"
+)
+
+// Snapshot represents a VM image or disk snapshot
+type Snapshot struct {
+	ID        string            `json:"id"`         // AMI/snapshot ID, or GCE resource name
+	Name      string            `json:"name"`       // Image name or Name tag
+	Kind      SnapshotKind      `json:"kind"`       // image, disk
+	State     SnapshotState     `json:"state"`      // pending, available, failed
+	Progress  int               `json:"progress"`   // Percent complete, -1 if unknown
+	SourceVM  string            `json:"source_vm"`  // ID of the VM it was taken from
+	SizeGB    int64             `json:"size_gb"`    // Disk size(s) covered
+	CreatedAt time.Time         `json:"created_at"` // Creation time
+	Tags      map[string]string `json:"tags"`       // All tags (GCP: labels)
+	Provider  string            `json:"provider"`   // aws, gcp
+
+	// Raw holds the original API response for provider-specific access
+	Raw interface{} `json:"-"`
+}
+
+// IsDone returns true once the snapshot is no longer in progress
+func (s *Snapshot) IsDone() bool {
+	return s.State == SnapshotStateAvailable || s.State == SnapshotStateFailed
+}
@@ -1,23 +1,38 @@
 package types
 
-// VPC represents an AWS VPC
+// VPC represents an AWS VPC or a GCP VPC network
 type VPC struct {
 	ID        string
 	Name      string
 	CIDR      string
 	State     string
 	IsDefault bool
-	OwnerID   string
+	OwnerID   string // AWS account ID or GCP project hosting the network
+	Shared    bool   // GCP: network lives in a shared-VPC host project
+
+	// Raw holds the original API response
+	Raw interface{}
 }
 
-// Subnet represents an AWS VPC Subnet
+// Subnet represents an AWS VPC Subnet or a GCP subnetwork
 type Subnet struct {
 	ID           string
 	Name         string
 	VPCID        string
 	CIDR         string
 	AZ           string
+	Region       string // GCP subnetworks are regional
 	AvailableIPs int
 	State        string
 	Public       bool // MapPublicIpOnLaunch
+
+	// GCP-specific fields
+	PrivateGoogleAccess bool             // Private Google Access enabled
+	SecondaryRanges     []SecondaryRange // Alias IP ranges (e.g. GKE pods/services)
+}
+
+// SecondaryRange represents a named secondary IP range on a GCP subnetwork
+type SecondaryRange struct {
+	Name string
+	CIDR string
 }